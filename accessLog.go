@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Remembers the outcome of a response for the access log
+type statusRecorder struct {
+	http.ResponseWriter
+	code int
+	size int
+}
+
+func (w *statusRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusRecorder) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	written, err := w.ResponseWriter.Write(data)
+	w.size += written
+	return written, err
+}
+
+// Keeps the streamed responses flowing
+func (w *statusRecorder) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Logs and counts each request once completed, with its status, the bytes sent and the time taken
+func logReq(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		inFlight := inFlightRequests.Load()
+		recorder := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(recorder, r)
+
+		if recorder.code == 0 {
+			recorder.code = http.StatusOK
+		}
+		servedRequests.Record(recorder.code)
+		duration := float64(time.Since(start).Microseconds()) / 1000
+		Logger.Infof("method=%s path=%s status=%d size=%d duration_ms=%.3f in_flight=%d",
+			r.Method, r.RequestURI, recorder.code, recorder.size, duration, inFlight)
+	})
+}
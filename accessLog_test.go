@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// ACCESS LOG TESTS
+// =============================================================================
+
+// Test each request is logged once completed with its outcome
+func TestAccessLog(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+	app := newApp()
+
+	tests := []struct {
+		method  string
+		target  string
+		body    string
+		pattern string
+	}{
+		{"POST", "/api/cats", `{"name": "Toto"}`, `method=POST path=/api/cats status=201 size=\d+ duration_ms=\d+\.\d{3} `},
+		{"GET", "/api/cats/unknown?fields=name", "", `method=GET path=/api/cats/unknown\?fields=name status=404 size=\d+ `},
+		{"DELETE", "/api/cats/unknown", "", `method=DELETE path=/api/cats/unknown status=404 `},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest(test.method, test.target, strings.NewReader(test.body)))
+
+		pattern := regexp.MustCompile(test.pattern)
+		if !slices.ContainsFunc(logRing.Lines(), pattern.MatchString) {
+			t.Errorf("Expected an access log line matching %q", test.pattern)
+		}
+	}
+}
+
+// Test the recorder counts the bytes and defaults to a 200
+func TestStatusRecorder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: rec}
+
+	recorder.Write([]byte("hello "))
+	recorder.Write([]byte("cats"))
+	recorder.WriteHeader(http.StatusTeapot)
+	recorder.Flush()
+
+	if recorder.code != http.StatusOK || recorder.size != 10 {
+		t.Errorf("Expected 10 bytes with a 200, got %d bytes with %d", recorder.size, recorder.code)
+	}
+	if !rec.Flushed {
+		t.Error("Expected the flush passed through")
+	}
+}
@@ -1,73 +1,591 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
 	"net/http"
-
-	"github.com/google/uuid"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Cat struct {
-	Name      string `json:"name"`
-	ID        string `json:"id,omitempty"`
-	BirthDate string `json:"birthDate,omitempty"`
-	Color     string `json:"color,omitempty"`
+	Name        string    `json:"name"`
+	ID          string    `json:"id,omitempty"`
+	BirthDate   string    `json:"birthDate,omitempty"`
+	Color       string    `json:"color,omitempty"`
+	Breed       string    `json:"breed,omitempty"`
+	WeightGrams int       `json:"weightGrams,omitempty"` // Unknown when zero
+	CreatedAt   time.Time `json:"createdAt,omitzero"`    // Server assigned
+	UpdatedAt   time.Time `json:"updatedAt,omitzero"`    // Server assigned
+}
+
+// Fields identifying a cat in the logs, the other ones are left out
+func (cat Cat) LogFields() Fields {
+	fields := Fields{"name": cat.Name}
+	if cat.ID != "" {
+		fields["cat_id"] = cat.ID
+	}
+	return fields
+}
+
+// Age in completed months at the reference time, false for a missing, invalid or future birth date
+func (cat Cat) AgeMonths(now time.Time) (int, bool) {
+	birthDate, err := time.Parse(dateLayout, cat.BirthDate)
+	if err != nil || birthDate.After(now) {
+		return 0, false
+	}
+	months := (now.Year()-birthDate.Year())*12 + int(now.Month()-birthDate.Month())
+	if now.Day() < birthDate.Day() {
+		months--
+	}
+	return months, true
+}
+
+// Weight for display, 4.2 for 4200 grams
+func (cat Cat) WeightKg() float64 {
+	return float64(cat.WeightGrams) / 1000
+}
+
+// Answer for the store failures other than a missing cat
+func storeFailure(req *http.Request, err error) (int, any) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		Logger.Info("Store call interrupted: ", err)
+		return http.StatusServiceUnavailable, localize(req, "request_interrupted")
+	}
+	Logger.Error("Store failure: ", err)
+	return http.StatusInternalServerError, localize(req, "internal_error")
+}
+
+// Answer decided inside a store transaction, carried out of it as its error
+type refusedWrite struct {
+	code int
+	body any
+}
+
+func (refusal refusedWrite) Error() string {
+	return fmt.Sprintf("write refused with %d: %v", refusal.code, refusal.body)
+}
+
+// Answer for a failed transaction, the refusal of its checks or else a store failure
+func transactFailure(req *http.Request, err error) (int, any) {
+	var refusal refusedWrite
+	if errors.As(err, &refusal) {
+		return refusal.code, refusal.body
+	}
+	return storeFailure(req, err)
+}
+
+// Finds the cat created first, the ones without creation time coming before
+func oldestCat(cats []Cat) (Cat, bool) {
+	if len(cats) == 0 {
+		return Cat{}, false
+	}
+
+	oldest := cats[0]
+	for _, cat := range cats[1:] {
+		if cat.CreatedAt.Before(oldest.CreatedAt) ||
+			(cat.CreatedAt.Equal(oldest.CreatedAt) && cat.ID < oldest.ID) {
+			oldest = cat
+		}
+	}
+	return oldest, true
 }
 
-// Simple in-memory database, for demo purpose
-var catsDatabase = map[string]Cat{
-	"id1": {Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"},
+// Body of a conflicting creation, pointing at the existing cat
+type ConflictError struct {
+	Message string `json:"message"`
+	ID      string `json:"id"`
 }
 
-func listMapKeys(aMap map[string]Cat) []string {
+// Looks for a cat with the same name and birth date
+func findDuplicateCat(cats []Cat, cat Cat) (string, bool) {
+	for _, existing := range cats {
+		if existing.Name == cat.Name && existing.BirthDate == cat.BirthDate {
+			return existing.ID, true
+		}
+	}
+	return "", false
+}
+
+func listCatIDs(cats []Cat) []string {
 	results := []string{}
 
-	for catID := range aMap {
-		results = append(results, catID)
+	for _, cat := range cats {
+		results = append(results, cat.ID)
+	}
+
+	return results
+}
+
+// Criteria narrowing down the cats, read from the query parameters
+type CatFilter struct {
+	Name  string
+	Color string
+	// Bounds in grams, nil when not given
+	MinWeight *int
+	MaxWeight *int
+	// Keeps the cats younger than this many months, nil when not given
+	MaxAgeMonths *int
+	// Reference time of the ages
+	Now time.Time
+}
+
+func parseCatFilter(params *QueryParams) CatFilter {
+	maxAgeMonths := params.OptionalInt("maxAgeMonths")
+	if maxAgeMonths != nil && *maxAgeMonths <= 0 {
+		params.Invalid("maxAgeMonths", "positive")
+	}
+	return CatFilter{
+		Name:         params.String("name", ""),
+		Color:        params.String("color", ""),
+		MinWeight:    params.OptionalInt("minWeight"),
+		MaxWeight:    params.OptionalInt("maxWeight"),
+		MaxAgeMonths: maxAgeMonths,
+		Now:          time.Now().UTC(),
+	}
+}
+
+// Empty criteria match everything, comparisons ignore the case.
+// The cats of unknown weight or birth date are left out by the weight and age bounds
+func (filter CatFilter) matches(cat Cat) bool {
+	if filter.Name != "" && !strings.EqualFold(cat.Name, filter.Name) {
+		return false
+	}
+	if filter.Color != "" && !strings.EqualFold(cat.Color, filter.Color) {
+		return false
+	}
+	if filter.MinWeight != nil && (cat.WeightGrams == 0 || cat.WeightGrams < *filter.MinWeight) {
+		return false
+	}
+	if filter.MaxWeight != nil && (cat.WeightGrams == 0 || cat.WeightGrams > *filter.MaxWeight) {
+		return false
+	}
+	if filter.MaxAgeMonths != nil {
+		if months, known := cat.AgeMonths(filter.Now); !known || months >= *filter.MaxAgeMonths {
+			return false
+		}
+	}
+	return true
+}
+
+// Whether no criterion was given, the reference time not being one
+func (filter CatFilter) isEmpty() bool {
+	filter.Now = time.Time{}
+	return filter == CatFilter{}
+}
+
+// Shared by all the endpoints working on a subset of the cats
+func filterCats(cats []Cat, filter CatFilter) []Cat {
+	results := []Cat{}
+
+	for _, cat := range cats {
+		if filter.matches(cat) {
+			results = append(results, cat)
+		}
 	}
 
 	return results
 }
 
+// Media type of the streamed lists, one JSON cat per line
+const ndjsonContentType = "application/x-ndjson"
+
+// Whether the Accept header lists the media type, its parameters ignored
+func acceptsMediaType(req *http.Request, mediaType string) bool {
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		acceptedType, _, _ := strings.Cut(accepted, ";")
+		if strings.EqualFold(strings.TrimSpace(acceptedType), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Asked with `?format=ndjson` or an Accept header listing the NDJSON media type
+func wantsNDJSON(req *http.Request) bool {
+	return req.URL.Query().Get("format") == "ndjson" || acceptsMediaType(req, ndjsonContentType)
+}
+
+// Lists the cat IDs, or streams the whole cats as NDJSON
+func listCatsHandler(res http.ResponseWriter, req *http.Request) {
+	if !wantsNDJSON(req) {
+		makeHandlerFunc(listCats)(res, req)
+		return
+	}
+
+	Logger.Info("Streaming the cats")
+	params := newQueryParams(req)
+	filter := parseCatFilter(params)
+	if err := params.Err(); err != nil {
+		code, body := errorResponse(req, err)
+		writeResponse(res, req, code, body)
+		return
+	}
+	// Filtered on the fly rather than into another slice
+	streamCats(res, req, streamNDJSON, filter.matches)
+}
+
+type CatCount struct {
+	Count int `json:"count"`
+}
+
 func listCats(req *http.Request) (int, any) {
 	Logger.Info("Listing the cats")
-	return http.StatusOK, listMapKeys(catsDatabase)
+
+	params := newQueryParams(req)
+	filter := parseCatFilter(params)
+	page := parsePage(params)
+	if err := params.Err(); err != nil {
+		return errorResponse(req, err)
+	}
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		return storeFailure(req, err)
+	}
+
+	// Sorted so the pages do not overlap
+	catIDs := listCatIDs(filterCats(cats, filter))
+	slices.Sort(catIDs)
+	return http.StatusOK, Response{Header: pageHeader(len(catIDs), page), Body: paginate(catIDs, page)}
 }
 
-func createCat(req *http.Request) (int, any) {
+func countCats(req *http.Request) (any, error) {
+	Logger.Info("Counting the cats")
 
-	// Decode the request body into a Cat structure
-	decoder := json.NewDecoder(req.Body)
-	var catCreationData Cat
-	err := decoder.Decode(&catCreationData)
+	params := newQueryParams(req)
+	filter := parseCatFilter(params)
+	if err := params.Err(); err != nil {
+		return nil, err
+	}
+	cats, err := storeOf(req.Context()).List(req.Context())
 	if err != nil {
+		return nil, err
+	}
+	return CatCount{Count: len(filterCats(cats, filter))}, nil
+}
+
+// Aggregates over the whole store, the cats without a valid birth date are left out of the ages
+type CatStats struct {
+	Total       int            `json:"total"`
+	ByColor     map[string]int `json:"byColor"`
+	Oldest      *Cat           `json:"oldest,omitempty"`
+	Youngest    *Cat           `json:"youngest,omitempty"`
+	NoBirthDate int            `json:"noBirthDate"`
+}
+
+func computeCatStats(cats []Cat) CatStats {
+	stats := CatStats{Total: len(cats), ByColor: map[string]int{}}
+	var oldestDate, youngestDate time.Time
+
+	for _, cat := range cats {
+		if cat.Color != "" {
+			stats.ByColor[cat.Color]++
+		}
+
+		if isMissingBirthDate(cat) {
+			stats.NoBirthDate++
+			continue
+		}
+		birthDate, err := time.Parse(dateLayout, cat.BirthDate)
+		if err != nil {
+			continue
+		}
+
+		// Ties go to the smallest ID so the answer is stable
+		if stats.Oldest == nil || birthDate.Before(oldestDate) || (birthDate.Equal(oldestDate) && cat.ID < stats.Oldest.ID) {
+			stats.Oldest, oldestDate = &cat, birthDate
+		}
+		if stats.Youngest == nil || birthDate.After(youngestDate) || (birthDate.Equal(youngestDate) && cat.ID < stats.Youngest.ID) {
+			stats.Youngest, youngestDate = &cat, birthDate
+		}
+	}
+	return stats
+}
+
+func catsStats(req *http.Request) (any, error) {
+	Logger.Info("Computing the cats statistics")
+
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	return computeCatStats(cats), nil
+}
+
+// Group of the cats without a valid birth date
+const unknownBirthYear = "unknown"
+
+// IDs of the cats by birth year, sorted in each year
+func groupCatsByYear(cats []Cat) map[string][]string {
+	groups := map[string][]string{}
+	for _, cat := range cats {
+		year := unknownBirthYear
+		if birthDate, err := time.Parse(dateLayout, cat.BirthDate); err == nil {
+			year = strconv.Itoa(birthDate.Year())
+		}
+		groups[year] = append(groups[year], cat.ID)
+	}
+	for _, catIDs := range groups {
+		slices.Sort(catIDs)
+	}
+	return groups
+}
+
+func catsByYear(req *http.Request) (any, error) {
+	Logger.Info("Grouping the cats by birth year")
+
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	return groupCatsByYear(cats), nil
+}
+
+// One of the cats picked uniformly, from the randomly seeded source of math/rand/v2
+func randomCat(req *http.Request) (int, any) {
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		return storeFailure(req, err)
+	}
+	if len(cats) == 0 {
+		Logger.Info("No cat to pick, the store is empty")
+		return http.StatusNotFound, localize(req, "store_empty")
+	}
+	return http.StatusOK, cats[rand.IntN(len(cats))]
+}
+
+// Refuses a cat with the same name and birth date as one of the others under --unique-cats
+func refuseDuplicateCat(req *http.Request, others []Cat, cat Cat) (int, any, bool) {
+	if !currentConfig().UniqueCats {
+		return 0, nil, false
+	}
+	if existingID, found := findDuplicateCat(others, cat); found {
+		Logger.Infof("Cat '%s' already has the same name and birth date", existingID)
+		return http.StatusConflict, ConflictError{
+			Message: localize(req, "duplicate_cat"),
+			ID:      existingID,
+		}, true
+	}
+	return 0, nil, false
+}
+
+// Checks a new cat against the uniqueness and the size of the store, the answer tells why it is refused
+func refuseNewCat(req *http.Request, cats []Cat, cat Cat) (int, any, bool) {
+	if code, refusal, refused := refuseDuplicateCat(req, cats, cat); refused {
+		return code, refusal, true
+	}
+
+	cfg := currentConfig()
+	isFull := cfg.MaxCats > 0 && len(cats) >= cfg.MaxCats
+	if isFull && cfg.EvictionPolicy != evictionOldest {
+		Logger.Infof("The DB is full with %d cats", len(cats))
+		return http.StatusInsufficientStorage, localize(req, "store_full"), true
+	}
+	return 0, nil, false
+}
+
+// Oldest cats to evict so a new one fits among the stored ones, none without --max-cats
+func catsToEvict(cats []Cat) []string {
+	maxCats := currentConfig().MaxCats
+	remaining := slices.Clone(cats)
+	var evictedIDs []string
+	for maxCats > 0 && len(remaining) >= maxCats {
+		oldest, _ := oldestCat(remaining)
+		evictedIDs = append(evictedIDs, oldest.ID)
+		remaining = slices.DeleteFunc(remaining, func(cat Cat) bool { return cat.ID == oldest.ID })
+	}
+	return evictedIDs
+}
+
+// Cleans up after the cats a new one evicted from the store
+func announceEvictions(evictedIDs []string) {
+	deletePhotos(evictedIDs)
+	for _, evictedID := range evictedIDs {
+		Logger.Infof("Cat '%s' evicted from the full DB", evictedID)
+		publishCatEvent(eventDeleted, evictedID, nil)
+	}
+}
+
+// New cat holding the configured --default-color and --default-birth-date, to decode a creation onto
+func catDefaults() Cat {
+	cfg := currentConfig()
+	return Cat{Color: cfg.DefaultColor, BirthDate: cfg.DefaultBirthDate}
+}
+
+func createCat(req *http.Request) (int, any) {
+	params := newQueryParams(req)
+	dryRun := params.Bool("dryRun", false)
+	if err := params.Err(); err != nil {
+		return errorResponse(req, err)
+	}
+
+	// Decode the request body into a Cat structure, a form can carry a photo along.
+	// The defaults are only kept for the fields the body leaves out
+	catCreationData := catDefaults()
+	var photo []byte
+	if isMultipartForm(req) {
+		var err error
+		if photo, err = readCatForm(req, &catCreationData); err != nil {
+			Logger.Info("Unable to read the form for cat creation: ", err)
+			return formFailure(req, err)
+		}
+	} else if err := decodeJSON(req.Body, &catCreationData); err != nil {
 		Logger.Info("Unable to parse the JSON input for cat creation")
-		return http.StatusBadRequest, "Invalid JSON input"
+		return decodeFailure(req, err)
 	}
 
-	Logger.Info("Creating the cat: ", catCreationData)
+	applyInputPolicies(&catCreationData)
+	Logger.WithFields(catCreationData.LogFields()).Info("Creating the cat")
+
+	if verr := requestValidator(req).Validate(catCreationData); verr.HasErrors() {
+		Logger.WithField("errors", verr.Error()).Info("Invalid cat")
+		return http.StatusUnprocessableEntity, verr
+	}
 
-	// Creating the new cat's ID and storing the Cat
-	newCatID := uuid.New().String()
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		return storeFailure(req, err)
+	}
+
+	if code, refusal, refused := refuseNewCat(req, cats, catCreationData); refused {
+		return code, refusal
+	}
+
+	// Creating the new cat's ID unless the client can pick it, client timestamps are ignored
+	newCatID, taken := pickCatID(cats, catCreationData.ID)
+	if taken {
+		Logger.WithField("cat_id", newCatID).Info("Cat already existing")
+		return http.StatusConflict, localize(req, "id_exists")
+	}
 	catCreationData.ID = newCatID
+	catCreationData.CreatedAt = time.Now().UTC()
+	catCreationData.UpdatedAt = catCreationData.CreatedAt
 
-	catsDatabase[newCatID] = catCreationData
+	// Everything was checked, the store is left untouched
+	if dryRun {
+		Logger.WithField("cat_id", newCatID).Info("Dry run, cat not saved")
+		return http.StatusOK, catCreationData
+	}
+
+	// The ID, the uniqueness and the room left are checked again as the cat is written, a
+	// concurrent creation may have changed them since the cats were listed
+	var evictedIDs []string
+	defer lockEventOrder()()
+	err = storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		if _, found := stored[newCatID]; found {
+			Logger.WithField("cat_id", newCatID).Info("Cat already existing")
+			return StoreChange{}, refusedWrite{http.StatusConflict, localize(req, "id_exists")}
+		}
+		storedCats := slices.Collect(maps.Values(stored))
+		if code, refusal, refused := refuseNewCat(req, storedCats, catCreationData); refused {
+			return StoreChange{}, refusedWrite{code, refusal}
+		}
+		evictedIDs = catsToEvict(storedCats)
+		return StoreChange{Save: []Cat{catCreationData}, Delete: evictedIDs}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
+	}
+	announceEvictions(evictedIDs)
+	// Saved once the ID is known to be this cat's, not to overwrite the photo of another one
+	if photo != nil {
+		if err := savePhoto(newCatID, photo); err != nil {
+			storeOf(req.Context()).Delete(req.Context(), newCatID)
+			return storeFailure(req, err)
+		}
+	}
 
-	Logger.Infof("Cat '%s' saved into the DB", newCatID)
-	return http.StatusCreated, newCatID
+	Logger.WithFields(catCreationData.LogFields()).Info("Cat saved into the DB")
+	publishCatEvent(eventCreated, newCatID, &catCreationData)
+	return http.StatusCreated, Response{
+		Header: http.Header{"Location": {apiPath("/cats/" + newCatID)}},
+		Body:   catCreationData,
+	}
+}
+
+// Number of cats removed by a bulk delete
+type DeletedCount struct {
+	Deleted int `json:"deleted"`
+}
+
+// Deletes all the cats matching the list filters. Without filter all the cats go,
+// once the request is confirmed with the token of a first attempt
+func deleteCats(req *http.Request) (int, any) {
+	params := newQueryParams(req)
+	filter := parseCatFilter(params)
+	confirm := params.String("confirm", "")
+	if err := params.Err(); err != nil {
+		return errorResponse(req, err)
+	}
+	if filter.isEmpty() {
+		if code, refusal, refused := confirmClearAll(req, confirm); refused {
+			return code, refusal
+		}
+		Logger.Info("Deleting all the cats, confirmed")
+	} else {
+		Logger.Infof("Deleting the cats matching %+v", filter)
+	}
+
+	defer lockEventOrder()()
+	deletedIDs, err := storeOf(req.Context()).DeleteMatching(req.Context(), filter.matches)
+	if err != nil {
+		return storeFailure(req, err)
+	}
+
+	deletePhotos(deletedIDs)
+	trackDeletes := currentConfig().TrackDeletes
+	for _, catID := range deletedIDs {
+		if trackDeletes {
+			deletedCats.Add(catID)
+		}
+		publishCatEvent(eventDeleted, catID, nil)
+	}
+	Logger.Infof("%d cats deleted from the DB", len(deletedIDs))
+	return http.StatusOK, DeletedCount{Deleted: len(deletedIDs)}
 }
 
+// Answers 204, or 200 with the deleted cat for `?return=true` so a client retrying knows its first
+// attempt went through: only that one gets the cat, the retries get a 404 or a 410
 func deleteCat(req *http.Request) (int, any) {
 	catID := req.PathValue("catId")
-	Logger.Infof("Deleting the cat: %s", catID)
+	params := newQueryParams(req)
+	returnCat := params.Bool("return", false)
+	if err := params.Err(); err != nil {
+		return errorResponse(req, err)
+	}
+	Logger.WithField("cat_id", catID).Info("Deleting the cat")
 
-	_, catExists := catsDatabase[catID]
-	if !catExists {
-		Logger.Infof("Cat '%s' not found in the DB", catID)
-		return http.StatusNotFound, "Cat not found"
+	// Read and deleted in one transaction, the answer is the very cat the delete removed
+	defer lockEventOrder()()
+	var deleted Cat
+	err := storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		cat, found := stored[catID]
+		if !found {
+			code, failure := catNotFound(req, catID)
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+		deleted = cat
+		return StoreChange{Delete: []string{catID}}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
 	}
 
-	delete(catsDatabase, catID)
-	Logger.Infof("Cat '%s' deleted from the DB", catID)
+	if currentConfig().TrackDeletes {
+		deletedCats.Add(catID)
+	}
+	if err := deletePhoto(catID); err != nil {
+		Logger.WithField("cat_id", catID).Warn("Unable to delete the photo of the cat: ", err)
+	}
+	Logger.WithField("cat_id", catID).Info("Cat deleted from the DB")
+	publishCatEvent(eventDeleted, catID, nil)
+	if returnCat {
+		return http.StatusOK, deleted
+	}
 	return http.StatusNoContent, nil
-}
\ No newline at end of file
+}
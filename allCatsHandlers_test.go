@@ -0,0 +1,630 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Store another server writes to right after it is read, between the checks of a request and its write
+type overtakenStore struct {
+	Store
+	once  *sync.Once
+	write func()
+}
+
+func (store overtakenStore) Get(ctx context.Context, catID string) (Cat, error) {
+	cat, err := store.Store.Get(ctx, catID)
+	store.once.Do(store.write)
+	return cat, err
+}
+
+func (store overtakenStore) List(ctx context.Context) ([]Cat, error) {
+	cats, err := store.Store.List(ctx)
+	store.once.Do(store.write)
+	return cats, err
+}
+
+func (store overtakenStore) Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error {
+	store.once.Do(store.write)
+	return store.Store.Transact(ctx, decide)
+}
+
+// Creates a cat through the handler and returns its ID
+func mustCreateCat(t *testing.T, store Store, body string) string {
+	t.Helper()
+	statusCode, response := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(body))))
+	if statusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d (%v)", http.StatusCreated, statusCode, response)
+	}
+	return response.(Response).Body.(Cat).ID
+}
+
+// =============================================================================
+// ALL CATS HANDLERS TESTS
+// =============================================================================
+
+// Test the created cat and its location go through the HTTP layer
+func TestCreateCatThroughApp(t *testing.T) {
+	store := NewMemoryRepo()
+
+	req := newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix", "color": "Black"}`))
+	rec := httptest.NewRecorder()
+	newAppWithStore(store).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+
+	var created Cat
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Expected a cat object in the body: %v", err)
+	}
+
+	if created.ID == "" || created.Name != "Felix" || created.Color != "Black" {
+		t.Errorf("Unexpected created cat: %+v", created)
+	}
+
+	if location := rec.Header().Get("Location"); location != "/api/cats/"+created.ID {
+		t.Errorf("Expected Location '/api/cats/%s', got '%s'", created.ID, location)
+	}
+}
+
+// Test duplicates are rejected only when the uniqueness check is enabled
+func TestCreateCatUniqueCats(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo(
+		Cat{ID: "existing-id", Name: "Toto", BirthDate: "2023-04-16"},
+	)
+	duplicate := `{"name": "Toto", "birthDate": "2023-04-16"}`
+
+	// Disabled by default: the duplicate is accepted
+	statusCode, _ := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(duplicate))))
+	if statusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, statusCode)
+	}
+
+	cfg := currentConfig()
+	cfg.UniqueCats = true
+	setConfig(cfg)
+
+	statusCode, response := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(duplicate))))
+	if statusCode != http.StatusConflict {
+		t.Errorf("Expected status code %d, got %d", http.StatusConflict, statusCode)
+	}
+
+	conflict, ok := response.(ConflictError)
+	if !ok {
+		t.Fatalf("Expected ConflictError response, got %T", response)
+	}
+	if conflict.ID == "" {
+		t.Error("Expected the conflicting cat ID in the response")
+	}
+
+	// Same name but another birth date is not a duplicate
+	statusCode, _ = createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Toto", "birthDate": "2024-01-01"}`))))
+	if statusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, statusCode)
+	}
+}
+
+// Test a duplicate created by another server after the cats were read is still refused, by POST, PUT or batch
+func TestOvertakenUniqueCats(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.UniqueCats = true
+	setConfig(cfg)
+
+	toto := `{"name": "Toto", "birthDate": "2023-04-16"}`
+	for _, req := range []*http.Request{
+		newJSONRequest("POST", "/api/cats", strings.NewReader(toto)),
+		newJSONRequest("PUT", "/api/cats/id2", strings.NewReader(toto)),
+		newJSONRequest("POST", "/api/cats/batch", strings.NewReader("["+toto+"]")),
+	} {
+		store := NewMemoryRepo()
+		app := newAppWithStore(overtakenStore{store, &sync.Once{}, func() {
+			store.Save(context.Background(), Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"})
+		}})
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		if rec.Code != http.StatusConflict && rec.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s %s: expected the duplicate refused, got %d", req.Method, req.URL.Path, rec.Code)
+		}
+		if cats, _ := store.List(t.Context()); len(cats) != 1 {
+			t.Errorf("%s %s: expected only the first cat stored, got %v", req.Method, req.URL.Path, cats)
+		}
+	}
+}
+
+// Test list and count share the same name/color filters
+func TestListAndCountWithFilters(t *testing.T) {
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey", WeightGrams: 3500},
+		Cat{ID: "id2", Name: "Felix", Color: "grey", WeightGrams: 5000},
+		Cat{ID: "id3", Name: "Garfield", Color: "Orange"},
+	)
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{"", 3},
+		{"?color=Grey", 2},
+		{"?color=GREY&name=felix", 1},
+		{"?name=Garfield", 1},
+		{"?color=Blue", 0},
+		{"?minWeight=3500", 2},
+		{"?maxWeight=4000", 1},
+		{"?minWeight=4000&maxWeight=5000", 1},
+		{"?minWeight=0", 2},
+	}
+
+	for _, test := range tests {
+		statusCode, response := listCats(withStore(store, httptest.NewRequest("GET", "/api/cats"+test.query, nil)))
+		if statusCode != http.StatusOK {
+			t.Errorf("%s: expected status code %d, got %d", test.query, http.StatusOK, statusCode)
+		}
+		if ids := response.(Response).Body.([]string); len(ids) != test.expected {
+			t.Errorf("%s: expected %d listed cats, got %d", test.query, test.expected, len(ids))
+		}
+
+		response, err := countCats(withStore(store, httptest.NewRequest("GET", "/api/cats/count"+test.query, nil)))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.query, err)
+		}
+		if count := response.(CatCount).Count; count != test.expected {
+			t.Errorf("%s: expected count %d, got %d", test.query, test.expected, count)
+		}
+	}
+
+	for _, query := range []string{"?minWeight=heavy", "?maxWeight=4.5"} {
+		if statusCode, _ := listCats(withStore(store, httptest.NewRequest("GET", "/api/cats"+query, nil))); statusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, statusCode)
+		}
+	}
+}
+
+// Test the age counts the completed months at the reference time
+func TestCatAgeMonths(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		birthDate string
+		months    int
+		known     bool
+	}{
+		{"2024-06-15", 0, true},
+		{"2023-12-15", 6, true},
+		{"2023-12-16", 5, true},
+		{"2020-01-31", 52, true},
+		{"", 0, false},
+		{"unknown", 0, false},
+		{"2024-07-01", 0, false},
+	}
+	for _, test := range tests {
+		if months, known := (Cat{BirthDate: test.birthDate}).AgeMonths(now); months != test.months || known != test.known {
+			t.Errorf("%q: expected %d months (%v), got %d (%v)", test.birthDate, test.months, test.known, months, known)
+		}
+	}
+}
+
+// Test the age bound keeps the younger cats and adds to the other filters
+func TestMaxAgeFilter(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	cats := []Cat{
+		{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2024-03-01"},
+		{ID: "id2", Name: "Felix", Color: "Black", BirthDate: "2024-01-10"},
+		{ID: "id3", Name: "Garfield", Color: "Grey", BirthDate: "2019-11-02"},
+		{ID: "id4", Name: "Tom", Color: "Grey"},
+		{ID: "id5", Name: "Tata", Color: "Grey", BirthDate: "unknown"},
+	}
+	maxAge := 6
+	if kittens := listCatIDs(filterCats(cats, CatFilter{MaxAgeMonths: &maxAge, Now: now})); !reflect.DeepEqual(kittens, []string{"id1", "id2"}) {
+		t.Errorf("Expected the cats younger than 6 months, got %v", kittens)
+	}
+	if kittens := listCatIDs(filterCats(cats, CatFilter{Color: "grey", MaxAgeMonths: &maxAge, Now: now})); !reflect.DeepEqual(kittens, []string{"id1"}) {
+		t.Errorf("Expected the grey kittens only, got %v", kittens)
+	}
+
+	for _, query := range []string{"?maxAgeMonths=young", "?maxAgeMonths=0"} {
+		if statusCode, _ := listCats(httptest.NewRequest("GET", "/api/cats"+query, nil)); statusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, statusCode)
+		}
+	}
+}
+
+// Test the weight in kilograms for display
+func TestCatWeightKg(t *testing.T) {
+	if weight := (Cat{WeightGrams: 4250}).WeightKg(); weight != 4.25 {
+		t.Errorf("Expected 4.25 kg, got %v", weight)
+	}
+	if weight := (Cat{}).WeightKg(); weight != 0 {
+		t.Errorf("Expected no weight, got %v", weight)
+	}
+}
+
+// Test the bulk delete removes the cats the list would show, and only with a filter
+func TestDeleteCatsWithFilters(t *testing.T) {
+	// Save original state
+	originalConfig, originalTombstones := currentConfig(), deletedCats
+	defer func() {
+		// Restore original state
+		deletedCats = originalTombstones
+		setConfig(originalConfig)
+	}()
+
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey"},
+		Cat{ID: "id2", Name: "Felix", Color: "grey"},
+		Cat{ID: "id3", Name: "Garfield", Color: "Orange"},
+	)
+	deletedCats = NewTombstones(maxTombstones)
+	cfg := currentConfig()
+	cfg.TrackDeletes = true
+	setConfig(cfg)
+	app := newAppWithStore(store)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats", nil))
+	if rec.Code != http.StatusConflict || len(storedCats(store)) != 3 {
+		t.Fatalf("Expected an unfiltered delete to wait for a confirmation, got %d with %d cats left", rec.Code, len(storedCats(store)))
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats?color=GREY", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+	var result DeletedCount
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil || result.Deleted != 2 {
+		t.Errorf("Expected 2 deleted cats, got %v (%v)", result, err)
+	}
+	if _, found := storedCats(store)["id3"]; !found || len(storedCats(store)) != 1 {
+		t.Errorf("Expected only Garfield left, got %v", storedCats(store))
+	}
+	if !deletedCats.Has("id1") || !deletedCats.Has("id2") {
+		t.Error("Expected the deleted cats to be tracked")
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats?name=Toto", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"deleted":0`) {
+		t.Errorf("Expected nothing deleted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// Test the count route is not taken for a cat ID
+func TestCountRoute(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/count", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result map[string]int
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("Expected a JSON object: %v", err)
+	}
+	if _, found := result["count"]; !found {
+		t.Errorf("Expected a 'count' field, got %v", result)
+	}
+}
+
+// Test the statistics over the whole store
+func TestCatsStats(t *testing.T) {
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"},
+		Cat{ID: "id2", Name: "Felix", Color: "Black", BirthDate: "2019-11-02"},
+		Cat{ID: "id3", Name: "Garfield", Color: "Grey", BirthDate: "2019-11-02"},
+		Cat{ID: "id4", Name: "Tom", Color: "Grey"},
+		Cat{ID: "id5", Name: "Legacy", BirthDate: "02/11/2019"},
+	)
+
+	rec := httptest.NewRecorder()
+	newAppWithStore(store).ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var stats CatStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("Expected a JSON object: %v", err)
+	}
+	if stats.Total != 5 || stats.NoBirthDate != 1 {
+		t.Errorf("Expected 5 cats with 1 without birth date, got %+v", stats)
+	}
+	if len(stats.ByColor) != 2 || stats.ByColor["Grey"] != 3 || stats.ByColor["Black"] != 1 {
+		t.Errorf("Expected 3 Grey and 1 Black, got %v", stats.ByColor)
+	}
+	// The invalid date is skipped, the tie goes to the smallest ID
+	if stats.Oldest == nil || stats.Oldest.ID != "id2" {
+		t.Errorf("Expected id2 as the oldest, got %+v", stats.Oldest)
+	}
+	if stats.Youngest == nil || stats.Youngest.ID != "id1" {
+		t.Errorf("Expected id1 as the youngest, got %+v", stats.Youngest)
+	}
+
+	// Nothing to compare in an empty store
+	empty := computeCatStats(nil)
+	if empty.Total != 0 || empty.Oldest != nil || empty.Youngest != nil || empty.ByColor == nil {
+		t.Errorf("Expected empty stats, got %+v", empty)
+	}
+}
+
+// Test the cats are grouped by birth year, the ones without a valid date as unknown
+func TestCatsByYear(t *testing.T) {
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"},
+		Cat{ID: "id3", Name: "Garfield", BirthDate: "2019-11-02"},
+		Cat{ID: "id2", Name: "Felix", BirthDate: "2019-01-30"},
+		Cat{ID: "id4", Name: "Tom"},
+		Cat{ID: "id5", Name: "Legacy", BirthDate: "02/11/2019"},
+		Cat{ID: "id6", Name: "Typo", BirthDate: "2019-13-01"},
+	)
+
+	rec := httptest.NewRecorder()
+	newAppWithStore(store).ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/byYear", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var groups map[string][]string
+	if err := json.NewDecoder(rec.Body).Decode(&groups); err != nil {
+		t.Fatalf("Expected a JSON object: %v", err)
+	}
+	expected := map[string][]string{
+		"2023":    {"id1"},
+		"2019":    {"id2", "id3"},
+		"unknown": {"id4", "id5", "id6"},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Expected %v, got %v", expected, groups)
+	}
+
+	if empty := groupCatsByYear(nil); len(empty) != 0 {
+		t.Errorf("Expected no group for an empty store, got %v", empty)
+	}
+}
+
+// Test every cat gets picked, the empty store answering 404 and "random" never taken for an ID
+func TestRandomCat(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"}, Cat{ID: "random", Name: "Tricky"})
+	app := newAppWithStore(store)
+
+	picked := map[string]int{}
+	for range 300 {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/random", nil))
+		var cat Cat
+		if err := json.NewDecoder(rec.Body).Decode(&cat); rec.Code != http.StatusOK || err != nil {
+			t.Fatalf("Expected a cat, got %d (%v)", rec.Code, err)
+		}
+		picked[cat.ID]++
+	}
+	for _, catID := range []string{"id1", "id2", "random"} {
+		if picked[catID] < 50 {
+			t.Errorf("Expected each cat picked about 100 times, got %v", picked)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newAppWithStore(NewMemoryRepo()).ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/random", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for an empty store, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// Test the timestamps are assigned by the server
+func TestCatTimestamps(t *testing.T) {
+	store := NewMemoryRepo()
+
+	before := time.Now()
+	catID := mustCreateCat(t, store, `{"name": "Toto", "createdAt": "2000-01-01T00:00:00Z", "updatedAt": "2000-01-01T00:00:00Z"}`)
+
+	created := storedCats(store)[catID]
+	if created.CreatedAt.Before(before) {
+		t.Errorf("Client creation time should be ignored, got %v", created.CreatedAt)
+	}
+	if !created.UpdatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Expected update time %v, got %v", created.CreatedAt, created.UpdatedAt)
+	}
+
+	// Patching bumps the update time only
+	req := httptest.NewRequest("PATCH", "/api/cats/"+catID, strings.NewReader(`{"color": "Grey", "createdAt": "2000-01-01T00:00:00Z"}`))
+	req.SetPathValue("catId", catID)
+	patchCat(withStore(store, req))
+
+	patched := storedCats(store)[catID]
+	if !patched.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Creation time should not change, got %v", patched.CreatedAt)
+	}
+	if patched.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("Expected a bumped update time, got %v", patched.UpdatedAt)
+	}
+
+	// Serialized in RFC3339 and omitted when unknown
+	encoded, _ := json.Marshal(patched)
+	var fields map[string]string
+	json.Unmarshal(encoded, &fields)
+	if _, err := time.Parse(time.RFC3339, fields["createdAt"]); err != nil {
+		t.Errorf("Expected an RFC3339 creation time, got %q", fields["createdAt"])
+	}
+
+	encoded, _ = json.Marshal(Cat{Name: "Toto"})
+	if strings.Contains(string(encoded), "createdAt") {
+		t.Errorf("Expected no timestamps for an unknown time, got %s", encoded)
+	}
+}
+
+// Test a dry run validates and previews the cat without storing it
+func TestCreateCatDryRun(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	cfg := currentConfig()
+	cfg.MaxCats = 1
+	cfg.EvictionPolicy = evictionOldest
+	setConfig(cfg)
+
+	statusCode, response := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats?dryRun=true", strings.NewReader(`{"name": "Felix"}`))))
+	if statusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d (%v)", http.StatusOK, statusCode, response)
+	}
+
+	preview, ok := response.(Cat)
+	if !ok || preview.ID == "" || preview.Name != "Felix" || preview.CreatedAt.IsZero() {
+		t.Errorf("Expected the would-be cat, got %+v", response)
+	}
+
+	// Nothing was stored nor evicted
+	_, ids := listCats(withStore(store, httptest.NewRequest("GET", "/api/cats", nil)))
+	if catIDs := ids.(Response).Body.([]string); len(catIDs) != 1 || catIDs[0] != "id1" {
+		t.Errorf("Expected the store unchanged, got %v", catIDs)
+	}
+
+	// Validation still applies
+	statusCode, _ = createCat(withStore(store, httptest.NewRequest("POST", "/api/cats?dryRun=true", strings.NewReader(`{"color": "Grey"}`))))
+	if statusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, statusCode)
+	}
+}
+
+// Test the defaults fill the absent fields only, and are stored
+func TestCreateCatDefaults(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.DefaultColor = "Unknown"
+	cfg.DefaultBirthDate = "2020-01-01"
+	setConfig(cfg)
+
+	tests := []struct {
+		body      string
+		color     string
+		birthDate string
+	}{
+		{`{"name": "Felix"}`, "Unknown", "2020-01-01"},
+		{`{"name": "Felix", "color": "", "birthDate": "2019-05-05"}`, "", "2019-05-05"},
+		{`{"name": "Felix", "color": "Black", "birthDate": ""}`, "Black", ""},
+	}
+
+	for _, test := range tests {
+		statusCode, response := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(test.body))))
+		if statusCode != http.StatusCreated {
+			t.Fatalf("%s: expected status code %d, got %d (%v)", test.body, http.StatusCreated, statusCode, response)
+		}
+
+		created := response.(Response).Body.(Cat)
+		stored, _ := store.Get(context.Background(), created.ID)
+		if created.Color != test.color || created.BirthDate != test.birthDate || stored != created {
+			t.Errorf("%s: expected %q born %q, got %+v stored as %+v", test.body, test.color, test.birthDate, created, stored)
+		}
+	}
+
+	cfg.DefaultBirthDate = "2999-01-01"
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected a future default birth date to be refused")
+	}
+}
+
+// =============================================================================
+// STORE LIMIT TESTS
+// =============================================================================
+
+// Test a full store rejects new cats by default
+func TestMaxCatsReject(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.MaxCats = 2
+	setConfig(cfg)
+
+	mustCreateCat(t, store, `{"name": "First"}`)
+	mustCreateCat(t, store, `{"name": "Second"}`)
+
+	statusCode, _ := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Third"}`))))
+	if statusCode != http.StatusInsufficientStorage {
+		t.Errorf("Expected status code %d, got %d", http.StatusInsufficientStorage, statusCode)
+	}
+	if len(storedCats(store)) != 2 {
+		t.Errorf("Expected 2 cats in database, got %d", len(storedCats(store)))
+	}
+}
+
+// Test the oldest cat makes room when eviction is enabled
+func TestMaxCatsEvictOldest(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.MaxCats = 2
+	cfg.EvictionPolicy = evictionOldest
+	setConfig(cfg)
+
+	firstID := mustCreateCat(t, store, `{"name": "First"}`)
+	secondID := mustCreateCat(t, store, `{"name": "Second"}`)
+	thirdID := mustCreateCat(t, store, `{"name": "Third"}`)
+
+	if _, found := storedCats(store)[firstID]; found {
+		t.Error("The oldest cat should have been evicted")
+	}
+	for _, catID := range []string{secondID, thirdID} {
+		if _, found := storedCats(store)[catID]; !found {
+			t.Errorf("Cat '%s' should still be stored", catID)
+		}
+	}
+
+	// A deleted cat is not the oldest anymore
+	req := httptest.NewRequest("DELETE", "/api/cats/"+secondID, nil)
+	req.SetPathValue("catId", secondID)
+	deleteCat(withStore(store, req))
+
+	fourthID := mustCreateCat(t, store, `{"name": "Fourth"}`)
+	fifthID := mustCreateCat(t, store, `{"name": "Fifth"}`)
+
+	if len(storedCats(store)) != 2 {
+		t.Errorf("Expected 2 cats in database, got %d", len(storedCats(store)))
+	}
+	for _, catID := range []string{fourthID, fifthID} {
+		if _, found := storedCats(store)[catID]; !found {
+			t.Errorf("Cat '%s' should still be stored", catID)
+		}
+	}
+}
+
+// Test a cat created by another server after the cats were read still counts against --max-cats
+func TestOvertakenMaxCats(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.MaxCats = 1
+	setConfig(cfg)
+
+	store := NewMemoryRepo()
+	app := newAppWithStore(overtakenStore{store, &sync.Once{}, func() {
+		store.Save(context.Background(), Cat{ID: "id1", Name: "Toto"})
+	}})
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix"}`)))
+	if rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected status code %d, got %d", http.StatusInsufficientStorage, rec.Code)
+	}
+	if cats, _ := store.List(t.Context()); len(cats) != 1 {
+		t.Errorf("Expected only the first cat stored, got %v", cats)
+	}
+}
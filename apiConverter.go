@@ -1,19 +1,42 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-func yml2json() {
+// The spec travels with the binary
+//
+//go:embed openapi.yml
+var specFS embed.FS
 
-	yfile, err := os.ReadFile("openapi.yml")
+// Reads the YAML spec from the --spec-file override if given, else the embedded one
+func readSpec() ([]byte, error) {
+	if specFile := currentConfig().SpecFile; specFile != "" {
+		return os.ReadFile(specFile)
+	}
+	return specFS.ReadFile("openapi.yml")
+}
+
+// Converts the YAML spec into indented JSON
+func specJSON() ([]byte, error) {
+
+	yfile, err := readSpec()
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	var data any
@@ -21,9 +44,165 @@ func yml2json() {
 	err = yaml.Unmarshal(yfile, &data)
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	enc := json.NewEncoder(&buffer)
+	enc.SetIndent("", "\t")
+	err = enc.Encode(data)
+	return buffer.Bytes(), err
+}
+
+// Spec converted to JSON, along with what tells whether its source changed since
+type ConvertedSpec struct {
+	JSON []byte
+	ETag string
+	// --spec-file at the time, empty for the embedded spec
+	source  string
+	modTime time.Time
+}
+
+// Whether the spec was converted from another source, or from an older version of the file
+func (spec *ConvertedSpec) stale() bool {
+	source := currentConfig().SpecFile
+	return spec.source != source || !spec.modTime.Equal(specModTime(source))
+}
+
+// Modification time of the spec file, zero for the embedded spec or an unreadable file
+func specModTime(source string) time.Time {
+	if source == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// JSON of the spec served to the docs, converted once then again on reload or when the file changes
+var cachedSpec atomic.Pointer[ConvertedSpec]
+
+// The converted spec, converting it on first use and after an edit of --spec-file.
+// An edit which fails to convert keeps the previous spec served
+func currentSpec() (*ConvertedSpec, error) {
+	spec := cachedSpec.Load()
+	if spec != nil && !spec.stale() {
+		return spec, nil
+	}
+	fresh, err := reloadSpec()
+	if err != nil && spec != nil {
+		Logger.Warn("Unable to convert the edited spec, keeping the previous one: ", err)
+		// Not tried again until the next edit
+		kept := *spec
+		kept.source = currentConfig().SpecFile
+		kept.modTime = specModTime(kept.source)
+		cachedSpec.Store(&kept)
+		return &kept, nil
+	}
+	return fresh, err
+}
+
+func loadedSpec() ([]byte, error) {
+	spec, err := currentSpec()
+	if err != nil {
+		return nil, err
+	}
+	return spec.JSON, nil
+}
+
+// Converts the spec again from its source, a failure keeps the previous one served
+func reloadSpec() (*ConvertedSpec, error) {
+	// Dated before reading, an edit in between is converted again next time
+	source := currentConfig().SpecFile
+	modTime := specModTime(source)
+	jsonSpec, err := specJSON()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(jsonSpec)
+	spec := &ConvertedSpec{JSON: jsonSpec, ETag: `"` + hex.EncodeToString(sum[:8]) + `"`, source: source, modTime: modTime}
+	cachedSpec.Store(spec)
+	return spec, nil
+}
+
+// Scheme and host the request reached the server at, or the --public-url override behind a proxy
+func publicBaseURL(req *http.Request) string {
+	if publicURL := currentConfig().PublicURL; publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + req.Host
+}
+
+// Tag of the spec served at this base URL, the servers differing from one to the other
+func (spec *ConvertedSpec) ETagFor(baseURL string) string {
+	sum := sha256.Sum256([]byte(spec.ETag + baseURL))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// JSON of the spec with the relative server URLs resolved against the base URL, so the
+// "Try it out" of the docs targets this server. The rest of the spec is left as converted
+func (spec *ConvertedSpec) ServedAt(baseURL string) ([]byte, error) {
+	documentURL, err := url.Parse(baseURL + "/openapi.json")
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(spec.JSON))
+	// The numbers are kept as written rather than going through float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
 	}
-	enc := json.NewEncoder(os.Stdout)
+
+	resolveServers(data, documentURL)
+	if paths, ok := data["paths"].(map[string]any); ok {
+		for _, pathItem := range paths {
+			if pathItem, ok := pathItem.(map[string]any); ok {
+				resolveServers(pathItem, documentURL)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	enc := json.NewEncoder(&buffer)
 	enc.SetIndent("", "\t")
-	enc.Encode(data)
+	err = enc.Encode(data)
+	return buffer.Bytes(), err
+}
+
+// Rewrites the relative URLs of the servers of a spec object, the absolute ones are kept
+func resolveServers(object map[string]any, documentURL *url.URL) {
+	servers, _ := object["servers"].([]any)
+	for _, server := range servers {
+		server, ok := server.(map[string]any)
+		if !ok {
+			continue
+		}
+		serverURL, ok := server["url"].(string)
+		if !ok {
+			continue
+		}
+		if relative, err := url.Parse(serverURL); err == nil && !relative.IsAbs() {
+			server["url"] = strings.TrimSuffix(documentURL.ResolveReference(relative).String(), "/")
+		}
+	}
+}
+
+// Prints the JSON of the spec, the convert command
+func yml2json() error {
+
+	jsonSpec, err := specJSON()
+
+	if err != nil {
+		return fmt.Errorf("spec unavailable: %w", err)
+	}
+	_, err = os.Stdout.Write(jsonSpec)
+	return err
 }
\ No newline at end of file
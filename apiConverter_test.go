@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// SPEC TESTS
+// =============================================================================
+
+// Test the embedded spec is used without any file in the working directory
+func TestEmbeddedSpec(t *testing.T) {
+	originalDir, _ := os.Getwd()
+	defer os.Chdir(originalDir)
+	os.Chdir(t.TempDir())
+
+	jsonSpec, err := specJSON()
+	if err != nil {
+		t.Fatalf("Expected the embedded spec to convert, got %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(jsonSpec, &result); err != nil {
+		t.Fatalf("Converted spec is not valid JSON: %v", err)
+	}
+	if _, exists := result["paths"]; !exists {
+		t.Error("Expected field 'paths' in the embedded spec")
+	}
+}
+
+// Test an explicitly given spec file which is missing is reported
+func TestSpecFileOverrideMissing(t *testing.T) {
+	originalConfig := currentConfig()
+	defer func() {
+		setConfig(originalConfig)
+	}()
+	cfg := currentConfig()
+	cfg.SpecFile = filepath.Join(t.TempDir(), "missing.yml")
+	setConfig(cfg)
+
+	if _, err := specJSON(); err == nil {
+		t.Error("Expected an error for a missing spec file")
+	}
+}
+
+// Test a missing spec only takes the docs down
+func TestMissingSpecDegrades(t *testing.T) {
+	originalConfig, originalSpec := currentConfig(), cachedSpec.Load()
+	defer func() {
+		setConfig(originalConfig)
+		cachedSpec.Store(originalSpec)
+	}()
+	cachedSpec.Store(nil)
+	cfg := currentConfig()
+	cfg.SpecFile = filepath.Join(t.TempDir(), "missing.yml")
+	setConfig(cfg)
+
+	if err := initialize(cfg); err != nil {
+		t.Fatalf("Expected the server to start without its spec, got %v", err)
+	}
+
+	app := newApp()
+	tests := []struct {
+		target       string
+		expectedCode int
+	}{
+		{"/openapi.json", http.StatusServiceUnavailable},
+		{"/swagger/", http.StatusServiceUnavailable},
+		{"/api/cats", http.StatusOK},
+		{"/ready", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", test.target, nil))
+		if rec.Code != test.expectedCode {
+			t.Errorf("%s: expected status code %d, got %d", test.target, test.expectedCode, rec.Code)
+		}
+		if test.expectedCode == http.StatusServiceUnavailable && !strings.Contains(rec.Body.String(), "Spec unavailable") {
+			t.Errorf("%s: expected a clear error, got %s", test.target, rec.Body.String())
+		}
+	}
+}
+
+// Test the reload endpoint serves an edited spec, and keeps the previous one when broken
+func TestReloadSpecEndpoint(t *testing.T) {
+	// Save original state
+	originalConfig, originalSpec := currentConfig(), cachedSpec.Load()
+	defer func() {
+		// Restore original state
+		setConfig(originalConfig)
+		cachedSpec.Store(originalSpec)
+	}()
+
+	specFile := filepath.Join(t.TempDir(), "openapi.yml")
+	writeSpec := func(content string) {
+		if err := os.WriteFile(specFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write the spec: %v", err)
+		}
+	}
+	cfg := currentConfig()
+	cfg.SpecFile = specFile
+	cfg.APIKey = "secret"
+	setConfig(cfg)
+	cachedSpec.Store(nil)
+	app := newApp()
+
+	servedSpec := func() string {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+		return rec.Body.String()
+	}
+	reload := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/admin/spec/reload", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	writeSpec("title: first\n")
+	if spec := servedSpec(); !strings.Contains(spec, "first") {
+		t.Fatalf("Expected the first spec, got %s", spec)
+	}
+
+	// An edit keeping the modification time is only picked up by the reload
+	info, _ := os.Stat(specFile)
+	writeSpec("title: second\n")
+	os.Chtimes(specFile, info.ModTime(), info.ModTime())
+	if spec := servedSpec(); !strings.Contains(spec, "first") {
+		t.Errorf("Expected the cached spec until a reload, got %s", spec)
+	}
+	if rec := reload(""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without the key, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	rec := reload("secret")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"source":"`+specFile+`"`) {
+		t.Errorf("Expected a reload from the spec file, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if spec := servedSpec(); !strings.Contains(spec, "second") {
+		t.Errorf("Expected the reloaded spec, got %s", spec)
+	}
+
+	writeSpec("title: [broken\n")
+	rec = reload("secret")
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "Invalid spec") {
+		t.Errorf("Expected the parse error, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if spec := servedSpec(); !strings.Contains(spec, "second") {
+		t.Errorf("Expected the previous spec to stay served, got %s", spec)
+	}
+}
+
+// Test the relative servers of the spec target the host it is served from, or --public-url
+func TestSpecServers(t *testing.T) {
+	originalConfig, originalSpec := currentConfig(), cachedSpec.Load()
+	defer func() {
+		setConfig(originalConfig)
+		cachedSpec.Store(originalSpec)
+	}()
+
+	specFile := filepath.Join(t.TempDir(), "openapi.yml")
+	os.WriteFile(specFile, []byte(`info:
+  version: 1.0.0
+servers:
+- url: ../api
+- url: https://staging.example.com/api
+paths:
+  /health:
+    servers:
+    - url: ..
+`), 0644)
+	cfg := currentConfig()
+	cfg.SpecFile = specFile
+	setConfig(cfg)
+	cachedSpec.Store(nil)
+	app := newApp()
+
+	type served struct {
+		Info    map[string]string
+		Servers []struct{ URL string }
+		Paths   map[string]struct{ Servers []struct{ URL string } }
+	}
+	get := func(host string) (served, string) {
+		req := httptest.NewRequest("GET", "/openapi.json", nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		var spec served
+		json.NewDecoder(rec.Body).Decode(&spec)
+		return spec, rec.Header().Get("ETag")
+	}
+
+	spec, localTag := get("localhost:8080")
+	if len(spec.Servers) != 2 || spec.Servers[0].URL != "http://localhost:8080/api" || spec.Servers[1].URL != "https://staging.example.com/api" {
+		t.Errorf("Expected the relative server resolved and the absolute one kept, got %+v", spec.Servers)
+	}
+	if servers := spec.Paths["/health"].Servers; len(servers) != 1 || servers[0].URL != "http://localhost:8080" {
+		t.Errorf("Expected the server of the path resolved, got %+v", servers)
+	}
+	if spec.Info["version"] != "1.0.0" {
+		t.Errorf("Expected the rest of the spec untouched, got %+v", spec.Info)
+	}
+
+	cfg.PublicURL = "https://cats.example.com/"
+	setConfig(cfg)
+	spec, publicTag := get("localhost:8080")
+	if spec.Servers[0].URL != "https://cats.example.com/api" || spec.Paths["/health"].Servers[0].URL != "https://cats.example.com" {
+		t.Errorf("Expected the public URL, got %+v", spec)
+	}
+	if publicTag == localTag {
+		t.Error("Expected a tag for each base URL")
+	}
+}
+
+// Test the spec is served with a validator the clients revalidate, changing with the file
+func TestSpecCaching(t *testing.T) {
+	originalConfig, originalSpec := currentConfig(), cachedSpec.Load()
+	defer func() {
+		setConfig(originalConfig)
+		cachedSpec.Store(originalSpec)
+	}()
+
+	specFile := filepath.Join(t.TempDir(), "openapi.yml")
+	os.WriteFile(specFile, []byte("title: first\n"), 0644)
+	cfg := currentConfig()
+	cfg.SpecFile = specFile
+	setConfig(cfg)
+	cachedSpec.Store(nil)
+	app := newApp()
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/openapi.json", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || !strings.Contains(rec.Header().Get("Cache-Control"), "max-age=") {
+		t.Fatalf("Expected a cacheable spec, got %d with %v", rec.Code, rec.Header())
+	}
+	converted := cachedSpec.Load()
+	if rec := get(etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected status code %d for a fresh copy, got %d", http.StatusNotModified, rec.Code)
+	}
+	if cachedSpec.Load() != converted {
+		t.Error("Expected the spec not converted again while the file is unchanged")
+	}
+
+	// A later modification time converts the file again
+	os.WriteFile(specFile, []byte("title: second\n"), 0644)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(specFile, later, later)
+	rec = get(etag)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "second") || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected the edited spec with a new tag, got %d with %s", rec.Code, rec.Body.String())
+	}
+
+	// A broken edit keeps the previous spec
+	os.WriteFile(specFile, []byte("title: [broken\n"), 0644)
+	later = later.Add(time.Minute)
+	os.Chtimes(specFile, later, later)
+	if rec := get(""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "second") {
+		t.Errorf("Expected the previous spec to stay served, got %d with %s", rec.Code, rec.Body.String())
+	}
+}
@@ -1,41 +1,252 @@
 package main
 
 import (
+	"bytes"
+	"crypto/subtle"
 	"embed"
 	"encoding/json"
-	"io/fs"
+	"errors"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
 )
 
 //go:embed swagger-ui
 var content embed.FS
 
-func logReq(next http.Handler) http.Handler {
+// Bounds the request bodies so a huge payload cannot exhaust the memory
+func limitBody(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		Logger.Infof("New request to: '%s %s'", r.Method, r.RequestURI)
+		if maxBodyBytes := currentConfig().MaxBodyBytes; maxBodyBytes > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
+// Rewrites the API paths without their trailing or doubled slashes: `/api/cats/` lists the cats
+// and `/api/cats//` cannot reach a cat with an empty ID. Nothing changes without a base path.
+func normalizeSlashes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := apiPath("/")
+		if prefix == "/" || !strings.HasPrefix(r.URL.Path, prefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cleaned := path.Clean(r.URL.Path)
+		if cleaned == r.URL.Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+		Logger.Debugf("Path '%s' rewritten to '%s'", r.URL.Path, cleaned)
+		rewritten := new(http.Request)
+		*rewritten = *r
+		rewritten.URL = new(url.URL)
+		*rewritten.URL = *r.URL
+		rewritten.URL.Path = cleaned
+		rewritten.URL.RawPath = ""
+		next.ServeHTTP(w, rewritten)
+	})
+}
+
+// Rejects the requests lacking the API key, the endpoint stays open when no key is configured
+func requireAPIKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		given, apiKey := []byte(r.Header.Get("X-API-Key")), currentConfig().APIKey
+		if apiKey != "" && subtle.ConstantTimeCompare(given, []byte(apiKey)) != 1 {
+			Logger.Warnf("Missing or invalid API key for '%s'", r.URL.Path)
+			writeResponse(w, r, http.StatusUnauthorized, ErrorBody{Error: localize(r, "invalid_api_key")})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// Answer for a body which cannot be decoded, telling an oversized body apart from a malformed one
+func decodeFailure(req *http.Request, err error) (int, any) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		Logger.Infof("Request body over the %d bytes limit", maxBytesErr.Limit)
+		return http.StatusRequestEntityTooLarge, localize(req, "body_too_large")
+	}
+	var unknownErr UnknownFieldError
+	if errors.As(err, &unknownErr) {
+		Logger.Info("Unknown field in the JSON input: ", unknownErr.Field)
+		return http.StatusBadRequest, localize(req, "unknown_field", unknownErr.Field)
+	}
+	return http.StatusBadRequest, localize(req, "invalid_json")
+}
+
+// Error body of the responses not produced by a ServiceFunc
+type ErrorBody struct {
+	Error string `json:"error"`
+	// Requested path, for the unknown routes
+	Path string `json:"path,omitempty"`
+}
+
+// Methods probed to fill the Allow header
+var knownMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// Methods having a route for the requested path
+func allowedMethods(router *http.ServeMux, req *http.Request) []string {
+	allowed := []string{}
+	for _, method := range knownMethods {
+		probe := *req
+		probe.Method = method
+		if _, pattern := router.Handler(&probe); pattern != "" {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// Answers the requests no route matches in JSON: a 405 with the Allow header on a method mismatch,
+// a 404 naming the path otherwise rather than the plain text of net/http
+func unmatchedRoutes(router *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := router.Handler(r); pattern == "" {
+			if allowed := allowedMethods(router, r); len(allowed) > 0 {
+				Logger.Infof("Method %s not allowed on '%s'", r.Method, r.URL.Path)
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				writeResponse(w, r, http.StatusMethodNotAllowed, ErrorBody{
+					Error: localize(r, "method_not_allowed", r.Method, strings.Join(allowed, ", ")),
+				})
+				return
+			}
+			Logger.Infof("No route for '%s %s'", r.Method, r.URL.Path)
+			writeResponse(w, r, http.StatusNotFound, ErrorBody{
+				Error: localize(r, "unknown_route"),
+				Path:  r.URL.Path,
+			})
+			return
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// Freshness of /openapi.json in the client caches, the Swagger UI fetching it on every page load
+const specCacheControl = "public, max-age=60"
+
+// Serves the YAML spec converted into JSON, the API keeps working without it
+func getSpecHandler(res http.ResponseWriter, req *http.Request) {
+	spec, err := currentSpec()
+	if err != nil {
+		Logger.Warn("Unable to convert the spec: ", err)
+		writeResponse(res, req, http.StatusServiceUnavailable, ErrorBody{Error: localize(req, "spec_unavailable")})
+		return
+	}
+
+	// Cached a little by the clients, then revalidated against the tag of the converted spec
+	baseURL := publicBaseURL(req)
+	etag := spec.ETagFor(baseURL)
+	res.Header().Set("ETag", etag)
+	res.Header().Set("Cache-Control", specCacheControl)
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" && etagListMatches(ifNoneMatch, etag, true) {
+		res.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	jsonSpec, err := spec.ServedAt(baseURL)
+	if err != nil {
+		Logger.Warn("Unable to set the servers of the spec: ", err)
+		writeResponse(res, req, http.StatusServiceUnavailable, ErrorBody{Error: localize(req, "spec_unavailable")})
+		return
+	}
+	if !currentConfig().JSONNewline {
+		jsonSpec = bytes.TrimSuffix(jsonSpec, []byte("\n"))
+	}
+	res.Header().Set("content-type", jsonResponseType)
+	res.Write(jsonSpec)
+}
+
+// Source and size of a reloaded spec
+type SpecReload struct {
+	Source string `json:"source"`
+	Bytes  int    `json:"bytes"`
+}
+
+// Converts the spec again, so an edited --spec-file is served without a restart
+func reloadSpecHandler(req *http.Request) (int, any) {
+	source := currentConfig().SpecFile
+	if source == "" {
+		source = "embedded"
+	}
+	Logger.Infof("Reloading the spec from %s", source)
+
+	spec, err := reloadSpec()
+	if err != nil {
+		Logger.Warn("Unable to reload the spec, keeping the previous one: ", err)
+		return http.StatusInternalServerError, localize(req, "invalid_spec", err)
+	}
+	return http.StatusOK, SpecReload{Source: source, Bytes: len(spec.JSON)}
+}
+
+// The docs pages are useless without the spec they load
+func requireSpec(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := loadedSpec(); err != nil {
+			Logger.Warn("Unable to convert the spec: ", err)
+			writeResponse(w, r, http.StatusServiceUnavailable, ErrorBody{Error: localize(r, "spec_unavailable")})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Prefixes an API route with the configured base path
+func apiPath(path string) string {
+	basePath := strings.Trim(currentConfig().BasePath, "/")
+	if basePath == "" {
+		return path
+	}
+	return "/" + basePath + path
+}
+
 func newApp() http.Handler {
 	Logger.Info("Init the backend")
 
 	router := http.NewServeMux()
-	router.HandleFunc("GET /{$}", getHomeHandler)
-	router.HandleFunc("POST /api/cats", makeHandlerFunc(createCat))
-	router.HandleFunc("GET /api/cats", makeHandlerFunc(listCats))
-	router.HandleFunc("GET /api/cats/{catId}", makeHandlerFunc(getCat))
-	router.HandleFunc("DELETE /api/cats/{catId}", makeHandlerFunc(deleteCat))
+	for _, route := range appRoutes() {
+		router.Handle(route.Pattern(), route.Handler)
+	}
 
-	fsys, _ := fs.Sub(content, "swagger-ui")
-	router.Handle("GET /swagger/", http.StripPrefix("/swagger", http.FileServer(http.FS(fsys))))
+	var handler http.Handler = unmatchedRoutes(router)
+	if currentConfig().ValidateRequests {
+		if specRouter, err := loadSpecRouter(); err != nil {
+			Logger.Error("Unable to load the spec, the requests are not validated: ", err)
+		} else {
+			handler = validateRequests(specRouter, handler)
+		}
+	}
 
-	return logReq(router)
+	return appMiddlewares(router).Then(handler)
 }
 
 // Simpler way to handle requests
 type ServiceFunc func(*http.Request) (int, any)
 
+// Body of a ServiceFunc needing to send extra response headers
+type Response struct {
+	Header http.Header
+	Body   any
+}
+
+// Indented output asked with `?pretty=true` or the `X-Pretty` header, compact by default
+func wantsPretty(req *http.Request) bool {
+	for _, value := range []string{req.URL.Query().Get("pretty"), req.Header.Get("X-Pretty")} {
+		if pretty, err := strconv.ParseBool(value); err == nil && pretty {
+			return true
+		}
+	}
+	return false
+}
+
 // Wraps the ServiceFunc to make a http.HandlerFunc with panic handling and JSON response encoding
 func makeHandlerFunc(svcFunc ServiceFunc) http.HandlerFunc {
 
@@ -48,15 +259,55 @@ func makeHandlerFunc(svcFunc ServiceFunc) http.HandlerFunc {
 					Logger.Error("Recovering from a panic: ", recov)
 					// Using the named return values
 					code = http.StatusInternalServerError
-					body = http.StatusText(code)
+					body = localize(req, "internal_error")
 				}
 			}()
 			return svcFunc(req)
 		}(req)
 
-		// Single response
-		res.Header().Set("content-type", "application/json")
+		writeResponse(res, req, code, body)
+	}
+}
+
+// Single JSON response, or YAML when asked, shared by the handlers not going through a ServiceFunc
+func writeResponse(res http.ResponseWriter, req *http.Request, code int, body any) {
+	if response, ok := body.(Response); ok {
+		for key, values := range response.Header {
+			res.Header()[key] = values
+		}
+		body = response.Body
+	}
+
+	// The error messages were written in the language of Accept-Language
+	if code >= http.StatusBadRequest {
+		res.Header().Set("Content-Language", requestLanguage(req))
+		res.Header().Add("Vary", "Accept-Language")
+	}
+
+	// Encoded beforehand to announce the exact length
+	var buffer bytes.Buffer
+	contentType := jsonResponseType
+	if wantsYAML(req) {
+		contentType = yamlResponseType
+		encodeYAML(&buffer, outputNaming(body))
+	} else {
+		encoder := json.NewEncoder(&buffer)
+		if wantsPretty(req) {
+			encoder.SetIndent("", "\t")
+		}
+		encoder.Encode(outputNaming(body))
+		if !currentConfig().JSONNewline && buffer.Len() > 0 {
+			buffer.Truncate(buffer.Len() - 1)
+		}
+	}
+
+	// Single response
+	res.Header().Set("content-type", contentType)
+	if code == http.StatusNoContent || code == http.StatusNotModified {
 		res.WriteHeader(code)
-		json.NewEncoder(res).Encode(body)
+		return
 	}
+	res.Header().Set("content-length", strconv.Itoa(buffer.Len()))
+	res.WriteHeader(code)
+	res.Write(buffer.Bytes())
 }
\ No newline at end of file
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// APP TESTS
+// =============================================================================
+
+// Test the API can be mounted under another prefix
+func TestConfigurableBasePath(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	store := NewMemoryRepo()
+
+	tests := []struct {
+		basePath string
+		expected string
+	}{
+		{"/api", "/api/cats"},
+		{"/gateway/cats-api/", "/gateway/cats-api/cats"},
+		{"v2", "/v2/cats"},
+		{"", "/cats"},
+		{"/", "/cats"},
+	}
+
+	for _, test := range tests {
+		cfg := currentConfig()
+		cfg.BasePath = test.basePath
+		setConfig(cfg)
+		if path := apiPath("/cats"); path != test.expected {
+			t.Errorf("Base path %q: expected %s, got %s", test.basePath, test.expected, path)
+		}
+	}
+
+	cfg := currentConfig()
+	cfg.BasePath = "/gateway"
+	setConfig(cfg)
+	app := newAppWithStore(store)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/gateway/cats", strings.NewReader(`{"name": "Felix"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); !strings.HasPrefix(location, "/gateway/cats/") {
+		t.Errorf("Expected Location under the base path, got %s", location)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the default prefix to be gone, got status %d", rec.Code)
+	}
+}
+
+// Test the trailing and doubled slashes are ignored under the base path
+func TestTrailingSlashes(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newAppWithStore(store)
+
+	tests := []struct {
+		method       string
+		target       string
+		expectedCode int
+		expectedBody string
+	}{
+		{"GET", "/api/cats", http.StatusOK, `["id1"]`},
+		{"GET", "/api/cats/", http.StatusOK, `["id1"]`},
+		{"GET", "/api/cats//", http.StatusOK, `["id1"]`},
+		{"GET", "/api//cats", http.StatusOK, `["id1"]`},
+		{"GET", "/api/cats/id1/", http.StatusOK, `"name":"Toto"`},
+		{"POST", "/api/cats/", http.StatusCreated, `"name":"Felix"`},
+		// Never the ID route with an empty ID, the bulk delete without filter wants a confirmation
+		{"DELETE", "/api/cats//", http.StatusConflict, `"token"`},
+		// Outside the API, the router rules apply
+		{"GET", "/swagger/", http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, newJSONRequest(test.method, test.target, strings.NewReader(`{"name": "Felix"}`)))
+
+			if rec.Code != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", test.expectedCode, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), test.expectedBody) {
+				t.Errorf("Expected a body with %s, got %s", test.expectedBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+// Test oversized bodies get a 413 while malformed ones keep their 400
+func TestMaxBodyBytes(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	cfg := currentConfig()
+	cfg.MaxBodyBytes = 64
+	setConfig(cfg)
+	app := newAppWithStore(store)
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"small create", "POST", "/api/cats", `{"name": "Felix"}`, http.StatusCreated, ""},
+		{"huge create", "POST", "/api/cats", `{"name": "` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge, "Request body too large"},
+		{"malformed create", "POST", "/api/cats", `{ invalid json }`, http.StatusBadRequest, "Invalid JSON input"},
+		{"huge patch", "PATCH", "/api/cats/id1", `{"color": "` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge, "Request body too large"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, newJSONRequest(test.method, test.target, strings.NewReader(test.body)))
+
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status code %d, got %d", test.expectedCode, rec.Code)
+			}
+
+			var message string
+			json.NewDecoder(rec.Body).Decode(&message)
+			if message != test.expectedBody {
+				t.Errorf("Expected message %q, got %q", test.expectedBody, message)
+			}
+		})
+	}
+}
+
+// Test method mismatches get a JSON 405 listing the allowed methods
+func TestMethodNotAllowed(t *testing.T) {
+	app := newApp()
+
+	tests := []struct {
+		method   string
+		target   string
+		expected []string
+	}{
+		{"POST", "/api/cats/id1", []string{"GET", "HEAD", "PUT", "PATCH", "DELETE"}},
+		{"PUT", "/api/cats", []string{"GET", "HEAD", "POST", "PATCH", "DELETE"}},
+		{"GET", "/api/import", []string{"POST"}},
+		{"POST", "/api/export", []string{"GET", "HEAD"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest(test.method, test.target, nil))
+
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("Expected status code %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+			}
+
+			if allow := rec.Header().Get("Allow"); allow != strings.Join(test.expected, ", ") {
+				t.Errorf("Expected Allow '%s', got '%s'", strings.Join(test.expected, ", "), allow)
+			}
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != jsonResponseType {
+				t.Errorf("Expected a JSON body, got %s", contentType)
+			}
+			var body ErrorBody
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" {
+				t.Errorf("Expected an error message, got %v", err)
+			}
+		})
+	}
+
+	// Matching methods are left to the router
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("POST", "/nonsense", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// Test an unknown route gets a JSON 404 naming the path, the home page being left alone
+func TestUnknownRoute(t *testing.T) {
+	app := newApp()
+
+	for _, target := range []string{"/nonsense", "/api/cats/id1/nonsense"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected status code %d, got %d", target, http.StatusNotFound, rec.Code)
+		}
+		if contentType := rec.Header().Get("Content-Type"); contentType != jsonResponseType {
+			t.Errorf("%s: expected a JSON body, got %s", target, contentType)
+		}
+		var body ErrorBody
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" || body.Path != target {
+			t.Errorf("%s: expected an error naming the path, got %+v (%v)", target, body, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<html>") {
+		t.Errorf("Expected the home page, got %d", rec.Code)
+	}
+}
+
+// =============================================================================
+// RESPONSE WRITING TESTS
+// =============================================================================
+
+// Test responses are compact by default and indented on demand
+func TestPrettyResponses(t *testing.T) {
+	handler := makeHandlerFunc(func(req *http.Request) (int, any) {
+		return http.StatusOK, Cat{Name: "Toto", Color: "Grey"}
+	})
+
+	tests := []struct {
+		name     string
+		target   string
+		header   string
+		indented bool
+	}{
+		{"default", "/", "", false},
+		{"query param", "/?pretty=true", "", true},
+		{"query param off", "/?pretty=false", "", false},
+		{"header", "/", "true", true},
+		{"invalid value", "/?pretty=maybe", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.target, nil)
+			if test.header != "" {
+				req.Header.Set("X-Pretty", test.header)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			body := rec.Body.String()
+			if strings.Contains(body, "\n\t") != test.indented {
+				t.Errorf("Expected indented=%v, got %q", test.indented, body)
+			}
+
+			if contentLength := rec.Header().Get("Content-Length"); contentLength != strconv.Itoa(len(body)) {
+				t.Errorf("Expected Content-Length %d, got %s", len(body), contentLength)
+			}
+
+			var cat Cat
+			if err := json.Unmarshal([]byte(body), &cat); err != nil || cat.Name != "Toto" {
+				t.Errorf("Expected the cat back, got %q (%v)", body, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Birth date telling the date was looked for but is not known, kept apart from a date never set
+const unknownBirthDate = "unknown"
+
+// How POST /admin/backfill fills the missing birth dates
+const (
+	backfillUnknown = "unknown"
+	backfillDefault = "default"
+)
+
+// Outcome of a backfill, a second run with the same parameters updates nothing
+type BackfillResult struct {
+	Strategy string `json:"strategy"`
+	Updated  int    `json:"updated"`
+}
+
+func isMissingBirthDate(cat Cat) bool {
+	return cat.BirthDate == "" || cat.BirthDate == unknownBirthDate
+}
+
+// Birth date given to the cats missing one: the unknown marker, or the `date` param with the default strategy
+func backfillValue(req *http.Request) (string, string, error) {
+	query := req.URL.Query()
+	switch strategy := query.Get("strategy"); strategy {
+	case backfillUnknown:
+		return strategy, unknownBirthDate, nil
+	case backfillDefault:
+		date := query.Get("date")
+		if verr := (CatValidator{}).Validate(Cat{Name: "backfill", BirthDate: date}); date == "" || verr.HasErrors() {
+			return "", "", invalidInput("backfill_date")
+		}
+		return strategy, date, nil
+	default:
+		return "", "", invalidInput("backfill_strategy", backfillUnknown, backfillDefault)
+	}
+}
+
+// Fills the missing birth dates in one transaction, the cats having a date are left untouched.
+// Rewriting every cat, it stays a 404 unless --api-key guards it
+func backfillBirthDates(req *http.Request) (any, error) {
+	if currentConfig().APIKey == "" {
+		return nil, clientError{sentinel: ErrNotFound, code: "backfill_disabled"}
+	}
+	strategy, birthDate, err := backfillValue(req)
+	if err != nil {
+		return nil, err
+	}
+	Logger.Infof("Backfilling the missing birth dates with '%s'", birthDate)
+
+	var updated []Cat
+	err = storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		now := time.Now().UTC()
+		updated = nil
+		for _, cat := range stored {
+			if isMissingBirthDate(cat) && cat.BirthDate != birthDate {
+				cat.BirthDate = birthDate
+				cat.UpdatedAt = now
+				updated = append(updated, cat)
+			}
+		}
+		return StoreChange{Save: updated}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	Logger.Infof("%d birth dates backfilled", len(updated))
+	return BackfillResult{Strategy: strategy, Updated: len(updated)}, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// =============================================================================
+// BIRTH DATE BACKFILL TESTS
+// =============================================================================
+
+// Test the missing birth dates only are filled, once, behind a configured API key
+func TestBackfillBirthDates(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"},
+		Cat{ID: "id2", Name: "Felix"},
+		Cat{ID: "id3", Name: "Garfield"},
+	)
+	app := newApp()
+
+	backfill := func(query string, apiKey string) (*httptest.ResponseRecorder, BackfillResult) {
+		req := httptest.NewRequest("POST", "/api/admin/backfill"+query, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		var result BackfillResult
+		json.NewDecoder(rec.Body).Decode(&result)
+		return rec, result
+	}
+
+	if rec, _ := backfill("?strategy=unknown", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d without --api-key, got %d", http.StatusNotFound, rec.Code)
+	}
+	cfg := currentConfig()
+	cfg.APIKey = "secret"
+	setConfig(cfg)
+
+	if rec, _ := backfill("?strategy=unknown", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without the key, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	for _, query := range []string{"", "?strategy=guess", "?strategy=default", "?strategy=default&date=tomorrow", "?strategy=default&date=2999-01-01"} {
+		if rec, _ := backfill(query, "secret"); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status code %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+
+	if rec, result := backfill("?strategy=unknown", "secret"); rec.Code != http.StatusOK || result != (BackfillResult{Strategy: "unknown", Updated: 2}) {
+		t.Errorf("Expected 2 cats marked unknown, got %d with %+v", rec.Code, result)
+	}
+	if _, result := backfill("?strategy=unknown", "secret"); result.Updated != 0 {
+		t.Errorf("Expected nothing left to mark, got %+v", result)
+	}
+
+	if _, result := backfill("?strategy=default&date=2020-01-01", "secret"); result.Updated != 2 {
+		t.Errorf("Expected the unknown dates to get the default, got %+v", result)
+	}
+	if _, result := backfill("?strategy=default&date=2021-01-01", "secret"); result.Updated != 0 {
+		t.Errorf("Expected nothing left to fill, got %+v", result)
+	}
+
+	toto, _ := catsStore.Get(context.Background(), "id1")
+	felix, _ := catsStore.Get(context.Background(), "id2")
+	if toto.BirthDate != "2023-04-16" || felix.BirthDate != "2020-01-01" || felix.UpdatedAt.IsZero() {
+		t.Errorf("Expected the known date kept and the missing one filled, got %+v and %+v", toto, felix)
+	}
+}
+
+// Test a cat marked unknown can still be saved and counts as having no birth date
+func TestUnknownBirthDate(t *testing.T) {
+	if verr := (CatValidator{}).Validate(Cat{Name: "Felix", BirthDate: unknownBirthDate}); verr.HasErrors() {
+		t.Errorf("Expected the unknown marker to be valid, got %v", verr)
+	}
+	stats := computeCatStats([]Cat{{ID: "id1", Name: "Felix", BirthDate: unknownBirthDate}})
+	if stats.NoBirthDate != 1 || stats.Oldest != nil {
+		t.Errorf("Expected the marker counted as no birth date, got %+v", stats)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Problem found on a field of one cat of a batch
+type BatchFieldError struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// All the problems found on a batch, answered with a 422 so the client fixes every cat in one pass
+type BatchValidationError struct {
+	Errors []BatchFieldError `json:"errors"`
+}
+
+func (verr *BatchValidationError) Add(index int, field, message string) {
+	verr.Errors = append(verr.Errors, BatchFieldError{Index: index, Field: field, Message: message})
+}
+
+// Checks every cat of the batch the way a single creation is, against the stored cats and the
+// previous ones of the batch. The valid cats get their ID and timestamps
+func prepareBatch(validator CatValidator, stored []Cat, cats []Cat) BatchValidationError {
+	var verr BatchValidationError
+	known := append([]Cat{}, stored...)
+	now := time.Now().UTC()
+
+	for idx := range cats {
+		cat := &cats[idx]
+		applyInputPolicies(cat)
+		for _, fieldErr := range validator.Validate(*cat).Errors {
+			verr.Add(idx, fieldErr.Field, fieldErr.Message)
+		}
+
+		catID, taken := pickCatID(known, cat.ID)
+		if taken {
+			verr.Add(idx, "id", validator.message("id_taken"))
+		}
+		if existingID, found := findDuplicateCat(known, *cat); found && currentConfig().UniqueCats {
+			verr.Add(idx, "name", validator.message("same_birth_date", existingID))
+		}
+
+		cat.ID = catID
+		cat.CreatedAt = now
+		cat.UpdatedAt = now
+		known = append(known, *cat)
+	}
+	return verr
+}
+
+// Creates several cats at once, all or nothing, answering their IDs in the order of the batch.
+// A batch never evicts, it is refused when the store cannot hold it
+func createCats(req *http.Request) (int, any) {
+	// Each cat is decoded on its own so the defaults only fill its absent fields
+	var records []json.RawMessage
+	if err := decodeJSON(req.Body, &records); err != nil {
+		Logger.Info("Unable to parse the JSON input for batch creation")
+		return decodeFailure(req, err)
+	}
+	cats := make([]Cat, len(records))
+	for idx, record := range records {
+		cats[idx] = catDefaults()
+		if err := decodeStrictly(bytes.NewReader(record), &cats[idx]); err != nil {
+			Logger.Infof("Unable to parse the cat %d of the batch", idx)
+			return decodeFailure(req, err)
+		}
+	}
+	Logger.Infof("Creating a batch of %d cats", len(cats))
+
+	// Checked against the store the batch is written to, a concurrent creation cannot slip a
+	// duplicate, a taken ID or one cat too many in between
+	decoded := cats
+	defer lockEventOrder()()
+	err := storeOf(req.Context()).Transact(req.Context(), func(storedByID map[string]Cat) (StoreChange, error) {
+		stored := slices.Collect(maps.Values(storedByID))
+		cats = slices.Clone(decoded)
+		if verr := prepareBatch(requestValidator(req), stored, cats); len(verr.Errors) > 0 {
+			Logger.Infof("Invalid batch, %d errors", len(verr.Errors))
+			return StoreChange{}, refusedWrite{http.StatusUnprocessableEntity, verr}
+		}
+
+		if maxCats := currentConfig().MaxCats; maxCats > 0 && len(stored)+len(cats) > maxCats {
+			Logger.Infof("No room for %d more cats in the DB holding %d", len(cats), len(stored))
+			return StoreChange{}, refusedWrite{http.StatusInsufficientStorage, localize(req, "store_full")}
+		}
+		return StoreChange{Save: cats}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
+	}
+
+	createdIDs := listCatIDs(cats)
+	for idx := range cats {
+		publishCatEvent(eventCreated, cats[idx].ID, &cats[idx])
+	}
+	Logger.Infof("%d cats saved into the DB", len(cats))
+	return http.StatusCreated, createdIDs
+}
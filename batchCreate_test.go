@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// BATCH CREATE TESTS
+// =============================================================================
+
+// Test a valid batch is stored whole and answered with its IDs in order
+func TestCreateCats(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newAppWithStore(store)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batch", strings.NewReader(`[{"name": "Felix"}, {"name": "Tom", "birthDate": "2020-01-01"}]`)))
+	var ids []string
+	if err := json.NewDecoder(rec.Body).Decode(&ids); rec.Code != http.StatusCreated || err != nil || len(ids) != 2 {
+		t.Fatalf("Expected the 2 IDs, got %d with %v (%v)", rec.Code, ids, err)
+	}
+
+	for idx, name := range []string{"Felix", "Tom"} {
+		cat, err := store.Get(t.Context(), ids[idx])
+		if err != nil || cat.Name != name || cat.CreatedAt.IsZero() {
+			t.Errorf("Expected %s stored under %s, got %+v (%v)", name, ids[idx], cat, err)
+		}
+	}
+}
+
+// Test every invalid cat is reported with its index and nothing is stored
+func TestCreateCatsValidationErrors(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newAppWithStore(store)
+
+	body := `[{"name": "Felix"}, {"birthDate": "soon"}, {"name": "Tom"}, {"name": "  ", "weightGrams": -1}]`
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batch", strings.NewReader(body)))
+
+	var verr BatchValidationError
+	json.NewDecoder(rec.Body).Decode(&verr)
+	expected := []BatchFieldError{
+		{Index: 1, Field: "name", Message: "is required"},
+		{Index: 1, Field: "birthDate", Message: "must be YYYY-MM-DD"},
+		{Index: 3, Field: "name", Message: "is required"},
+		{Index: 3, Field: "weightGrams", Message: "cannot be negative"},
+	}
+	if rec.Code != http.StatusUnprocessableEntity || len(verr.Errors) != len(expected) {
+		t.Fatalf("Expected the 4 errors, got %d with %+v", rec.Code, verr)
+	}
+	for idx, fieldErr := range expected {
+		if verr.Errors[idx] != fieldErr {
+			t.Errorf("Expected %+v, got %+v", fieldErr, verr.Errors[idx])
+		}
+	}
+
+	if cats, _ := store.List(t.Context()); len(cats) != 1 {
+		t.Errorf("Expected nothing stored from an invalid batch, got %d cats", len(cats))
+	}
+}
+
+// Test the batch is checked against the uniqueness and the size of the store
+func TestCreateCatsStoreRules(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	cfg := currentConfig()
+	cfg.UniqueCats = true
+	cfg.MaxCats = 3
+	setConfig(cfg)
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batch", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := send(`[{"name": "Toto"}, {"name": "Felix"}, {"name": "Felix"}]`)
+	var verr BatchValidationError
+	json.NewDecoder(rec.Body).Decode(&verr)
+	if rec.Code != http.StatusUnprocessableEntity || len(verr.Errors) != 2 || verr.Errors[0].Index != 0 || verr.Errors[1].Index != 2 {
+		t.Errorf("Expected the duplicates of the store and of the batch, got %d with %+v", rec.Code, verr)
+	}
+
+	if rec := send(`[{"name": "Felix"}, {"name": "Tom"}, {"name": "Garfield"}]`); rec.Code != http.StatusInsufficientStorage {
+		t.Errorf("Expected status code %d, got %d", http.StatusInsufficientStorage, rec.Code)
+	}
+	if rec := send(`[{"name": "Felix"}, {"name": "Tom"}]`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Most IDs fetched by a single batch get
+const maxBatchGetIDs = 100
+
+type BatchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// The cats found keyed by ID, and the IDs of the others
+type BatchGetResult struct {
+	Cats    map[string]Cat `json:"cats"`
+	Missing []string       `json:"missing"`
+}
+
+// Fetches several cats at once, for the clients reconciling a local cache. Any ID is looked up, a PUT
+// or an import storing others than the generated ones, and those not stored are reported missing
+func batchGetCats(req *http.Request) (int, any) {
+	var batch BatchGetRequest
+	if err := decodeJSON(req.Body, &batch); err != nil {
+		Logger.Info("Unable to parse the JSON input for batch get")
+		return decodeFailure(req, err)
+	}
+
+	if len(batch.IDs) > maxBatchGetIDs {
+		return http.StatusBadRequest, localize(req, "batch_get_too_many", maxBatchGetIDs)
+	}
+	Logger.Infof("Fetching %d cats", len(batch.IDs))
+
+	result := BatchGetResult{Cats: map[string]Cat{}, Missing: []string{}}
+	seen := map[string]bool{}
+	for _, catID := range batch.IDs {
+		if seen[catID] {
+			continue
+		}
+		seen[catID] = true
+
+		cat, err := storeOf(req.Context()).Get(req.Context(), catID)
+		if err == ErrNotFound {
+			result.Missing = append(result.Missing, catID)
+		} else if err != nil {
+			return storeFailure(req, err)
+		} else {
+			result.Cats[catID] = cat
+		}
+	}
+	return http.StatusOK, result
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// BATCH GET TESTS
+// =============================================================================
+
+// Test the found cats are keyed by ID and the others listed as missing
+func TestBatchGetCats(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	const knownID = "6f1c2d5e-8a4b-4c3d-9e2f-1a2b3c4d5e6f"
+	catsStore = NewMemoryRepo(
+		Cat{ID: knownID, Name: "Toto"},
+		Cat{ID: "cat-1", Name: "Felix"},
+	)
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	body := `{"ids": ["cat-1", "` + knownID + `", "cat-9", "cat-1", "cat-9"]}`
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batchGet", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var result BatchGetResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode the result: %v", err)
+	}
+	if len(result.Cats) != 2 || result.Cats["cat-1"].Name != "Felix" || result.Cats[knownID].Name != "Toto" {
+		t.Errorf("Expected the two stored cats, got %v", result.Cats)
+	}
+	if !slices.Equal(result.Missing, []string{"cat-9"}) {
+		t.Errorf("Expected cat-9 to be missing once, got %v", result.Missing)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batchGet", strings.NewReader(`{"ids": []}`)))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"missing":[]`) {
+		t.Errorf("Expected an empty result, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// Test the IDs neither generated as UUID nor as cat-N, stored by a PUT or an import, are fetched too
+func TestBatchGetCatsAnyID(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+	for _, req := range []*http.Request{
+		newJSONRequest("PUT", "/api/cats/my-cat", strings.NewReader(`{"name": "Felix"}`)),
+		newJSONRequest("POST", "/api/import", strings.NewReader(`[{"id": "Imported_2", "name": "Tom"}]`)),
+	} {
+		app.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	body := `{"ids": ["id1", "my-cat", "Imported_2", "../etc", ""]}`
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batchGet", strings.NewReader(body)))
+	var result BatchGetResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the result, got %d (%v)", rec.Code, err)
+	}
+	if len(result.Cats) != 3 || result.Cats["my-cat"].Name != "Felix" || result.Cats["Imported_2"].Name != "Tom" {
+		t.Errorf("Expected the 3 stored cats, got %v", result.Cats)
+	}
+	if !slices.Equal(result.Missing, []string{"../etc", ""}) {
+		t.Errorf("Expected the unknown IDs missing, got %v", result.Missing)
+	}
+}
+
+// Test the malformed batches are refused
+func TestBatchGetCatsInvalid(t *testing.T) {
+	tooMany := make([]string, maxBatchGetIDs+1)
+	for idx := range tooMany {
+		tooMany[idx] = fmt.Sprintf("cat-%d", idx)
+	}
+	tooManyBody, _ := json.Marshal(BatchGetRequest{IDs: tooMany})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{"malformed", `{"ids": `, "Invalid JSON input"},
+		{"wrong type", `{"ids": "cat-1"}`, "Invalid JSON input"},
+		{"too many", string(tooManyBody), fmt.Sprintf("At most %d ids", maxBatchGetIDs)},
+	}
+
+	app := newApp()
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats/batchGet", strings.NewReader(test.body)))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), test.expected) {
+			t.Errorf("%s: expected a 400 with %q, got %d: %s", test.name, test.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Most cats patched by a single batch
+const maxBatchPatchItems = 100
+
+// Outcome of one item of a batch patch, with the status a single PATCH would have answered
+type BatchPatchResult struct {
+	ID     string       `json:"id"`
+	Status int          `json:"status"`
+	Cat    *Cat         `json:"cat,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type BatchPatchResponse struct {
+	Results []BatchPatchResult `json:"results"`
+	Failed  int                `json:"failed"`
+}
+
+// Applies the merge patch of one item to its cat, the same way a single PATCH does
+func patchBatchItem(req *http.Request, item map[string]json.RawMessage) BatchPatchResult {
+	var result BatchPatchResult
+	raw, found := item["id"]
+	if !found || json.Unmarshal(raw, &result.ID) != nil || result.ID == "" {
+		result.Status, result.Error = http.StatusBadRequest, localize(req, "batch_id_required")
+		return result
+	}
+	delete(item, "id")
+
+	fail := func(code int, body any) BatchPatchResult {
+		result.Status = code
+		if verr, ok := body.(ValidationError); ok {
+			result.Errors = verr.Errors
+		} else {
+			result.Error = fmt.Sprint(body)
+		}
+		return result
+	}
+
+	if err := checkPatchFields(item); err != nil {
+		return fail(decodeFailure(req, err))
+	}
+	// The cat is read and written back in one transaction, a delete or another patch landing in
+	// between is not undone
+	var cat Cat
+	err := storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		current, found := stored[result.ID]
+		if !found {
+			code, failure := catNotFound(req, result.ID)
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+
+		cat = current
+		if err := applyMergePatch(&cat, item); err != nil {
+			return StoreChange{}, refusedWrite{http.StatusBadRequest, localizeError(req, err)}
+		}
+		applyInputPolicies(&cat)
+		if verr := requestValidator(req).Validate(cat); verr.HasErrors() {
+			return StoreChange{}, refusedWrite{http.StatusUnprocessableEntity, verr}
+		}
+
+		cat.UpdatedAt = time.Now().UTC()
+		return StoreChange{Save: []Cat{cat}}, nil
+	})
+	if err != nil {
+		return fail(transactFailure(req, err))
+	}
+	result.Status, result.Cat = http.StatusOK, &cat
+	return result
+}
+
+// Patches several cats at once, each item being the merge patch of the cat of its id. The items
+// are applied in order and on their own: a failed one is reported without undoing the others
+func patchCats(req *http.Request) (int, any) {
+	var items []map[string]json.RawMessage
+	if err := decodeJSON(req.Body, &items); err != nil {
+		Logger.Info("Unable to parse the JSON input for batch patch")
+		return decodeFailure(req, err)
+	}
+	if len(items) > maxBatchPatchItems {
+		return http.StatusBadRequest, localize(req, "batch_patch_too_many", maxBatchPatchItems)
+	}
+	Logger.Infof("Patching a batch of %d cats", len(items))
+
+	response := BatchPatchResponse{Results: make([]BatchPatchResult, 0, len(items))}
+	for _, item := range items {
+		result := patchBatchItem(req, item)
+		if result.Status != http.StatusOK {
+			response.Failed++
+			Logger.WithFields(Fields{"cat_id": result.ID, "status": result.Status}).Info("Cat of the batch not patched")
+		}
+		response.Results = append(response.Results, result)
+	}
+	Logger.Infof("%d cats of the batch patched in the DB", len(items)-response.Failed)
+	return http.StatusOK, response
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// =============================================================================
+// BATCH PATCH TESTS
+// =============================================================================
+
+// Test each item is applied on its own, the failed ones reported without undoing the others
+func TestPatchCats(t *testing.T) {
+	store := NewMemoryRepo(
+		Cat{ID: "cat-1", Name: "Toto", Color: "Black"},
+		Cat{ID: "cat-2", Name: "Felix", Color: "White"},
+	)
+	app := newAppWithStore(store)
+
+	body := `[
+		{"id": "cat-1", "color": "Grey"},
+		{"id": "cat-9", "color": "Grey"},
+		{"id": "cat-2", "weightGrams": -5},
+		{"color": "Grey"},
+		{"id": "cat-2", "color": "Ginger"}
+	]`
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("PATCH", "/api/cats", strings.NewReader(body)))
+	var response BatchPatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the results, got %d (%v)", rec.Code, err)
+	}
+
+	expected := []int{http.StatusOK, http.StatusNotFound, http.StatusUnprocessableEntity, http.StatusBadRequest, http.StatusOK}
+	if len(response.Results) != len(expected) || response.Failed != 3 {
+		t.Fatalf("Expected %d results with 3 failed, got %+v", len(expected), response)
+	}
+	for idx, status := range expected {
+		if response.Results[idx].Status != status {
+			t.Errorf("Item %d: expected status %d, got %+v", idx, status, response.Results[idx])
+		}
+	}
+	if result := response.Results[2]; len(result.Errors) != 1 || result.Errors[0].Field != "weightGrams" {
+		t.Errorf("Expected the weight reported, got %+v", result)
+	}
+	if result := response.Results[0]; result.Cat == nil || result.Cat.Color != "Grey" || result.Cat.Name != "Toto" {
+		t.Errorf("Expected the patched cat answered, got %+v", result)
+	}
+
+	for catID, color := range map[string]string{"cat-1": "Grey", "cat-2": "Ginger"} {
+		cat, err := store.Get(t.Context(), catID)
+		if err != nil || cat.Color != color || cat.WeightGrams != 0 || cat.UpdatedAt.IsZero() {
+			t.Errorf("Expected %s %s, got %+v (%v)", catID, color, cat, err)
+		}
+	}
+}
+
+// Test the malformed batches are refused whole
+func TestPatchCatsInvalidBatch(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "cat-1", Name: "Toto"}))
+
+	tooMany := "[" + strings.Repeat(`{"id": "cat-1"},`, maxBatchPatchItems) + `{"id": "cat-1"}]`
+	for _, body := range []string{`{"id": "cat-1"}`, `[1]`, tooMany} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest("PATCH", "/api/cats", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d for %.40s, got %d", http.StatusBadRequest, body, rec.Code)
+		}
+	}
+}
+
+// Test a cat deleted while its item is applied stays deleted
+func TestPatchCatsOvertaken(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "cat-1", Name: "Toto", Color: "Black"})
+	app := newAppWithStore(overtakenStore{store, &sync.Once{}, func() {
+		store.Delete(context.Background(), "cat-1")
+	}})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("PATCH", "/api/cats", strings.NewReader(`[{"id": "cat-1", "color": "Grey"}]`)))
+	var response BatchPatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&response); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the results, got %d (%v)", rec.Code, err)
+	}
+	if len(response.Results) != 1 || response.Results[0].Status != http.StatusNotFound {
+		t.Errorf("Expected the deleted cat not found, got %+v", response.Results)
+	}
+	if cats, _ := store.List(t.Context()); len(cats) != 0 {
+		t.Errorf("Expected the deleted cat not written back, got %v", cats)
+	}
+}
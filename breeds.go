@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Breeds a cat can be declared with, the only list the validation and GET /breeds read
+var catBreeds = []string{
+	"Abyssinian",
+	"Bengal",
+	"British Shorthair",
+	"Maine Coon",
+	"Norwegian Forest",
+	"Persian",
+	"Ragdoll",
+	"Siamese",
+	"Sphynx",
+	"Unknown",
+}
+
+func isKnownBreed(breed string) bool {
+	return slices.Contains(catBreeds, breed)
+}
+
+// Lists the accepted breeds, for the clients building their forms
+func listBreeds(req *http.Request) (int, any) {
+	return http.StatusOK, catBreeds
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// BREEDS TESTS
+// =============================================================================
+
+// Test the listed breeds are the validated ones
+func TestListBreeds(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/breeds", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var breeds []string
+	if err := json.NewDecoder(rec.Body).Decode(&breeds); err != nil {
+		t.Fatalf("Expected a JSON array: %v", err)
+	}
+	if !slices.Equal(breeds, catBreeds) || !slices.Contains(breeds, "Unknown") {
+		t.Errorf("Expected the known breeds, got %v", breeds)
+	}
+	for _, breed := range breeds {
+		if verr := (CatValidator{}).Validate(Cat{Name: "Toto", Breed: breed}); verr.HasErrors() {
+			t.Errorf("Listed breed %q refused: %v", breed, verr)
+		}
+	}
+}
+
+// Test an unknown breed is refused with the allowed values
+func TestCreateCatUnknownBreed(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+	req := httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Toto", "breed": "Tabby"}`))
+	statusCode, response := createCat(req)
+
+	if statusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnprocessableEntity, statusCode)
+	}
+	verr := response.(ValidationError)
+	if len(verr.Errors) != 1 || verr.Errors[0].Field != "breed" || !strings.Contains(verr.Errors[0].Message, "Siamese") {
+		t.Errorf("Expected a breed error listing the breeds, got %v", verr)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+var ErrNotFound = errors.New("cat not found")
+
+// Persistence of the cats, every call can be cancelled through its context
+type Store interface {
+	List(ctx context.Context) ([]Cat, error)
+	// Hands the cats one by one until yield answers false, for the responses streaming them all
+	Iterate(ctx context.Context, yield func(Cat) bool) error
+	Get(ctx context.Context, catID string) (Cat, error)
+	// Creates or replaces the cat under its ID
+	Save(ctx context.Context, cat Cat) error
+	Delete(ctx context.Context, catID string) error
+	// Deletes the cats the function matches in one go, returning their IDs
+	DeleteMatching(ctx context.Context, match func(Cat) bool) ([]string, error)
+	// Saves all the cats at once or none, dropping the others when replacing
+	Import(ctx context.Context, cats []Cat, replace bool) error
+	// Applies the change decide builds from the stored cats keyed by ID, no other write landing in
+	// between. An error of decide is returned with nothing written. decide may be called again after
+	// a concurrent write, it must only depend on the cats it is given and leave them unchanged
+	Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error
+	// Writes then reads back a throwaway sentinel, kept apart from the cats, to check the
+	// backend accepts the writes and not only the reads
+	ProbeWrite(ctx context.Context) error
+}
+
+var ErrProbeMismatch = errors.New("the probe read back another value than written")
+
+// Writes of a transaction, the deletions applied before the saves
+type StoreChange struct {
+	Save   []Cat
+	Delete []string
+}
+
+// Simple in-memory database, for demo purpose
+type MemoryRepo struct {
+	lock sync.RWMutex
+	cats map[string]Cat
+}
+
+func NewMemoryRepo(cats ...Cat) *MemoryRepo {
+	repo := &MemoryRepo{cats: map[string]Cat{}}
+	for _, cat := range cats {
+		repo.cats[cat.ID] = cat
+	}
+	return repo
+}
+
+// Nothing to interrupt in memory, the context is only checked before answering
+func (repo *MemoryRepo) List(ctx context.Context) ([]Cat, error) {
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	results := make([]Cat, 0, len(repo.cats))
+	for _, cat := range repo.cats {
+		results = append(results, cat)
+	}
+	return results, nil
+}
+
+// The cats are already in memory, only the copy taken under the lock is walked so a slow
+// client does not hold the writers back
+func (repo *MemoryRepo) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	cats, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cat := range cats {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !yield(cat) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (repo *MemoryRepo) Get(ctx context.Context, catID string) (Cat, error) {
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+
+	if err := ctx.Err(); err != nil {
+		return Cat{}, err
+	}
+	cat, found := repo.cats[catID]
+	if !found {
+		return Cat{}, ErrNotFound
+	}
+	return cat, nil
+}
+
+func (repo *MemoryRepo) Save(ctx context.Context, cat Cat) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	repo.cats[cat.ID] = cat
+	return nil
+}
+
+func (repo *MemoryRepo) Delete(ctx context.Context, catID string) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, found := repo.cats[catID]; !found {
+		return ErrNotFound
+	}
+	delete(repo.cats, catID)
+	return nil
+}
+
+// Matched and deleted under the same lock, no cat can change in between
+func (repo *MemoryRepo) DeleteMatching(ctx context.Context, match func(Cat) bool) ([]string, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	deletedIDs := []string{}
+	for catID, cat := range repo.cats {
+		if match(cat) {
+			delete(repo.cats, catID)
+			deletedIDs = append(deletedIDs, catID)
+		}
+	}
+	return deletedIDs, nil
+}
+
+func (repo *MemoryRepo) Import(ctx context.Context, cats []Cat, replace bool) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if replace {
+		repo.cats = map[string]Cat{}
+	}
+	for _, cat := range cats {
+		repo.cats[cat.ID] = cat
+	}
+	return nil
+}
+
+// Decided and written under the same lock, the conditions checked by decide still hold
+func (repo *MemoryRepo) Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	change, err := decide(repo.cats)
+	if err != nil {
+		return err
+	}
+	for _, catID := range change.Delete {
+		delete(repo.cats, catID)
+	}
+	for _, cat := range change.Save {
+		repo.cats[cat.ID] = cat
+	}
+	return nil
+}
+
+// The memory is always writable and has no sentinel to read back, the probe only goes through
+// the lock like a write and always succeeds
+func (repo *MemoryRepo) ProbeWrite(ctx context.Context) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+	return ctx.Err()
+}
+
+// Example cats loaded at startup with --seed
+var seedCats = []Cat{
+	{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"},
+	{ID: "id2", Name: "Felix", Color: "Black", BirthDate: "2019-11-02"},
+	{ID: "id3", Name: "Garfield", Color: "Orange", BirthDate: "2016-06-19"},
+}
+
+// Merges the example cats into the store, timestamped now
+func seedStore(ctx context.Context, store Store) error {
+	cats := slices.Clone(seedCats)
+	if verr := prepareImport(CatValidator{}, cats); verr.HasErrors() {
+		return verr
+	}
+	return store.Import(ctx, cats, false)
+}
+
+// Store of the server, starting empty
+var catsStore Store = NewMemoryRepo()
+
+type storeKey struct{}
+
+// Store a request works on: the one its app was built around, else catsStore
+func storeOf(ctx context.Context) Store {
+	if store, ok := ctx.Value(storeKey{}).(Store); ok {
+		return store
+	}
+	return catsStore
+}
+
+// App serving its own store rather than catsStore, so the tests need no shared state
+func newAppWithStore(store Store) http.Handler {
+	app := newApp()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), storeKey{}, store)))
+	})
+}
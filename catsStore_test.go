@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Every store implementation, built holding the given cats
+func storeImplementations(t *testing.T) map[string]func(cats ...Cat) Store {
+	return map[string]func(cats ...Cat) Store{
+		"memory": func(cats ...Cat) Store { return NewMemoryRepo(cats...) },
+		"redis":  func(cats ...Cat) Store { return newMiniRedisRepo(t, cats...) },
+	}
+}
+
+// Request served by the given store, for calling a handler without the app around it
+func withStore(store Store, req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), storeKey{}, store))
+}
+
+func sortedIDs(cats []Cat) []string {
+	catIDs := listCatIDs(cats)
+	slices.Sort(catIDs)
+	return catIDs
+}
+
+// Reads the stored cats by ID, for the assertions
+func storedCats(store Store) map[string]Cat {
+	cats, _ := store.List(context.Background())
+
+	results := map[string]Cat{}
+	for _, cat := range cats {
+		results[cat.ID] = cat
+	}
+	return results
+}
+
+// =============================================================================
+// STORE TESTS
+// =============================================================================
+
+// Test every implementation behaves the same, the handlers relying on any of them
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	toto := Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"}
+	felix := Cat{ID: "id2", Name: "Felix", Color: "Black", WeightGrams: 4200}
+
+	for name, newStore := range storeImplementations(t) {
+		t.Run(name+" list and get", func(t *testing.T) {
+			store := newStore(toto, felix)
+			cats, err := store.List(ctx)
+			if err != nil || !slices.Equal(sortedIDs(cats), []string{"id1", "id2"}) {
+				t.Errorf("Expected id1 and id2, got %v (%v)", cats, err)
+			}
+			if cat, err := store.Get(ctx, "id2"); err != nil || cat != felix {
+				t.Errorf("Expected %+v, got %+v (%v)", felix, cat, err)
+			}
+			if _, err := store.Get(ctx, "id9"); err != ErrNotFound {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+		})
+
+		t.Run(name+" save", func(t *testing.T) {
+			store := newStore(toto)
+			renamed := toto
+			renamed.Name = "Titi"
+			for _, cat := range []Cat{renamed, felix} {
+				if err := store.Save(ctx, cat); err != nil {
+					t.Fatalf("Failed to save %s: %v", cat.ID, err)
+				}
+			}
+			if cat, err := store.Get(ctx, "id1"); err != nil || cat.Name != "Titi" {
+				t.Errorf("Expected the cat replaced, got %+v (%v)", cat, err)
+			}
+			if cats, err := store.List(ctx); err != nil || len(cats) != 2 {
+				t.Errorf("Expected 2 cats, got %v (%v)", cats, err)
+			}
+		})
+
+		t.Run(name+" delete", func(t *testing.T) {
+			store := newStore(toto, felix)
+			if err := store.Delete(ctx, "id1"); err != nil {
+				t.Fatalf("Failed to delete: %v", err)
+			}
+			if err := store.Delete(ctx, "id1"); err != ErrNotFound {
+				t.Errorf("Expected ErrNotFound on the second delete, got %v", err)
+			}
+			if cats, err := store.List(ctx); err != nil || !slices.Equal(sortedIDs(cats), []string{"id2"}) {
+				t.Errorf("Expected only id2 left, got %v (%v)", cats, err)
+			}
+		})
+
+		t.Run(name+" delete matching", func(t *testing.T) {
+			store := newStore(toto, felix, Cat{ID: "id3", Name: "Tom", Color: "Grey"})
+			deletedIDs, err := store.DeleteMatching(ctx, func(cat Cat) bool { return cat.Color == "Grey" })
+			slices.Sort(deletedIDs)
+			if err != nil || !slices.Equal(deletedIDs, []string{"id1", "id3"}) {
+				t.Errorf("Expected id1 and id3 deleted, got %v (%v)", deletedIDs, err)
+			}
+			if cats, err := store.List(ctx); err != nil || !slices.Equal(sortedIDs(cats), []string{"id2"}) {
+				t.Errorf("Expected only id2 left, got %v (%v)", cats, err)
+			}
+			if deletedIDs, err := store.DeleteMatching(ctx, func(Cat) bool { return false }); err != nil || len(deletedIDs) != 0 {
+				t.Errorf("Expected nothing deleted, got %v (%v)", deletedIDs, err)
+			}
+		})
+
+		t.Run(name+" import", func(t *testing.T) {
+			store := newStore(toto, felix)
+			renamed := toto
+			renamed.Name = "Titi"
+			if err := store.Import(ctx, []Cat{renamed, {ID: "id3", Name: "Tom"}}, false); err != nil {
+				t.Fatalf("Failed to merge: %v", err)
+			}
+			cats, err := store.List(ctx)
+			if err != nil || !slices.Equal(sortedIDs(cats), []string{"id1", "id2", "id3"}) {
+				t.Errorf("Expected the merge to keep id2, got %v (%v)", cats, err)
+			}
+			if cat, _ := store.Get(ctx, "id1"); cat.Name != "Titi" {
+				t.Errorf("Expected the merge to replace id1, got %+v", cat)
+			}
+
+			if err := store.Import(ctx, []Cat{{ID: "id4", Name: "Garfield"}}, true); err != nil {
+				t.Fatalf("Failed to replace: %v", err)
+			}
+			if cats, err := store.List(ctx); err != nil || !slices.Equal(sortedIDs(cats), []string{"id4"}) {
+				t.Errorf("Expected only id4 after replacing, got %v (%v)", cats, err)
+			}
+		})
+
+		t.Run(name+" iterate", func(t *testing.T) {
+			store := newStore(toto, felix)
+			var seen []Cat
+			err := store.Iterate(ctx, func(cat Cat) bool {
+				seen = append(seen, cat)
+				return true
+			})
+			if err != nil || !slices.Equal(sortedIDs(seen), []string{"id1", "id2"}) {
+				t.Errorf("Expected both cats, got %v (%v)", seen, err)
+			}
+
+			seen = nil
+			store.Iterate(ctx, func(cat Cat) bool {
+				seen = append(seen, cat)
+				return false
+			})
+			if len(seen) != 1 {
+				t.Errorf("Expected the iteration to stop after the first cat, got %d", len(seen))
+			}
+		})
+
+		t.Run(name+" transact", func(t *testing.T) {
+			store := newStore(toto, felix)
+			err := store.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+				if len(cats) != 2 || cats["id2"] != felix {
+					t.Errorf("Expected the stored cats to decide on, got %v", cats)
+				}
+				return StoreChange{Save: []Cat{{ID: "id3", Name: "Tom"}}, Delete: []string{"id1"}}, nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to transact: %v", err)
+			}
+			if cats, err := store.List(ctx); err != nil || !slices.Equal(sortedIDs(cats), []string{"id2", "id3"}) {
+				t.Errorf("Expected id1 swapped for id3, got %v (%v)", cats, err)
+			}
+
+			refusal := errors.New("refused")
+			err = store.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+				return StoreChange{Delete: []string{"id2"}}, refusal
+			})
+			if err != refusal {
+				t.Errorf("Expected the refusal of decide, got %v", err)
+			}
+			if _, err := store.Get(ctx, "id2"); err != nil {
+				t.Errorf("Expected a refused change to write nothing, got %v", err)
+			}
+		})
+
+		t.Run(name+" probe", func(t *testing.T) {
+			if err := newStore().ProbeWrite(ctx); err != nil {
+				t.Errorf("Expected the probe to pass, got %v", err)
+			}
+		})
+	}
+}
+
+// Test an app built around its own store leaves catsStore and the other apps alone
+func TestNewAppWithStore(t *testing.T) {
+	first, second := NewMemoryRepo(), NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	firstApp, secondApp := newAppWithStore(first), newAppWithStore(second)
+
+	rec := httptest.NewRecorder()
+	firstApp.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+
+	if cats, _ := first.List(context.Background()); len(cats) != 1 || cats[0].Name != "Felix" {
+		t.Errorf("Expected Felix in the first store, got %v", cats)
+	}
+	if cats, _ := second.List(context.Background()); len(cats) != 1 || cats[0].Name != "Toto" {
+		t.Errorf("Expected the second store unchanged, got %v", cats)
+	}
+
+	rec = httptest.NewRecorder()
+	secondApp.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the second app to serve its own cat, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	firstApp.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the first app not to see it, got %d", rec.Code)
+	}
+}
+
+// Test the in-memory store basic operations
+func TestMemoryRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+
+	if err := repo.Save(ctx, Cat{ID: "id2", Name: "Felix"}); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	cats, err := repo.List(ctx)
+	if err != nil || len(cats) != 2 {
+		t.Errorf("Expected 2 cats, got %d (%v)", len(cats), err)
+	}
+
+	if cat, err := repo.Get(ctx, "id2"); err != nil || cat.Name != "Felix" {
+		t.Errorf("Expected Felix, got %+v (%v)", cat, err)
+	}
+
+	if err := repo.Delete(ctx, "id1"); err != nil {
+		t.Errorf("Failed to delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, "id1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := repo.Delete(ctx, "id1"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+// Test a cancelled context stops the store calls
+func TestMemoryRepoCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	repo := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+
+	if _, err := repo.List(ctx); err != context.Canceled {
+		t.Errorf("List: expected context.Canceled, got %v", err)
+	}
+	if _, err := repo.Get(ctx, "id1"); err != context.Canceled {
+		t.Errorf("Get: expected context.Canceled, got %v", err)
+	}
+	if err := repo.Save(ctx, Cat{ID: "id2"}); err != context.Canceled {
+		t.Errorf("Save: expected context.Canceled, got %v", err)
+	}
+	if err := repo.Delete(ctx, "id1"); err != context.Canceled {
+		t.Errorf("Delete: expected context.Canceled, got %v", err)
+	}
+
+	if _, err := repo.Get(context.Background(), "id1"); err != nil {
+		t.Errorf("The cancelled calls should not change the store, got %v", err)
+	}
+}
+
+// Test the handlers pass the request context down to the store
+func TestHandlersUseRequestContext(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest("GET", "/api/cats/id1", nil).WithContext(ctx)
+	req.SetPathValue("catId", "id1")
+
+	if statusCode, _ := getCat(withStore(store, req)); statusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, statusCode)
+	}
+
+	req = httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix"}`)).WithContext(ctx)
+	if statusCode, _ := createCat(withStore(store, req)); statusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, statusCode)
+	}
+	if len(storedCats(store)) != 1 {
+		t.Error("A cancelled creation should not store the cat")
+	}
+}
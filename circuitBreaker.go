@@ -0,0 +1,98 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// States of a circuit breaker
+const (
+	breakerClosed   = "closed"    // The calls go through
+	breakerOpen     = "open"      // The calls are refused until the cooldown is over
+	breakerHalfOpen = "half-open" // A single call probes whether the remote is back
+)
+
+// Stops calling a remote failing in a row, then lets a single call probe it once the cooldown
+// is over: its success closes the breaker, its failure opens it again
+type CircuitBreaker struct {
+	name string
+	// Consecutive failures opening the breaker, 0 never opens it
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	lock     sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now, state: breakerClosed}
+}
+
+func (breaker *CircuitBreaker) State() string {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+	return breaker.state
+}
+
+// Whether the call can go, a true answer must be followed by its Record
+func (breaker *CircuitBreaker) Allow() bool {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	switch breaker.state {
+	case breakerOpen:
+		if breaker.now().Sub(breaker.openedAt) < breaker.cooldown {
+			return false
+		}
+		breaker.moveTo(breakerHalfOpen)
+		breaker.probing = true
+		return true
+	case breakerHalfOpen:
+		// The probe is still running
+		if breaker.probing {
+			return false
+		}
+		breaker.probing = true
+		return true
+	}
+	return true
+}
+
+// Outcome of an allowed call
+func (breaker *CircuitBreaker) Record(err error) {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+
+	breaker.probing = false
+	if err == nil {
+		breaker.failures = 0
+		if breaker.state != breakerClosed {
+			breaker.moveTo(breakerClosed)
+		}
+		return
+	}
+
+	breaker.failures++
+	if breaker.state == breakerHalfOpen || (breaker.threshold > 0 && breaker.failures >= breaker.threshold) {
+		breaker.openedAt = breaker.now()
+		if breaker.state != breakerOpen {
+			breaker.moveTo(breakerOpen)
+		}
+	}
+}
+
+func (breaker *CircuitBreaker) moveTo(state string) {
+	switch state {
+	case breakerOpen:
+		Logger.Warnf("Circuit breaker of the %s open after %d failures in a row, calls refused for %v", breaker.name, breaker.failures, breaker.cooldown)
+	case breakerHalfOpen:
+		Logger.Infof("Circuit breaker of the %s half-open, probing with the next call", breaker.name)
+	case breakerClosed:
+		Logger.Infof("Circuit breaker of the %s closed, the calls go through again", breaker.name)
+	}
+	breaker.state = state
+}
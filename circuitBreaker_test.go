@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// CIRCUIT BREAKER TESTS
+// =============================================================================
+
+// Test the breaker opens after the failures in a row, then probes once the cooldown is over
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker("test", 3, time.Minute)
+	breaker.now = func() time.Time { return now }
+	failure := errors.New("connection refused")
+
+	// A success in between resets the count
+	for _, err := range []error{failure, failure, nil, failure, failure} {
+		if !breaker.Allow() {
+			t.Fatal("Expected the calls to go through while closed")
+		}
+		breaker.Record(err)
+	}
+	if state := breaker.State(); state != breakerClosed {
+		t.Fatalf("Expected the breaker closed, got %s", state)
+	}
+
+	breaker.Allow()
+	breaker.Record(failure)
+	if breaker.State() != breakerOpen || breaker.Allow() {
+		t.Fatalf("Expected the breaker open and refusing, got %s", breaker.State())
+	}
+
+	// A single probe once the cooldown is over, failing opens the breaker for another cooldown
+	now = now.Add(time.Minute)
+	if !breaker.Allow() || breaker.State() != breakerHalfOpen {
+		t.Fatalf("Expected a probe allowed, got %s", breaker.State())
+	}
+	if breaker.Allow() {
+		t.Error("Expected the other calls refused during the probe")
+	}
+	breaker.Record(failure)
+	if breaker.State() != breakerOpen || breaker.Allow() {
+		t.Fatalf("Expected the failed probe to open the breaker again, got %s", breaker.State())
+	}
+
+	now = now.Add(time.Minute)
+	breaker.Allow()
+	breaker.Record(nil)
+	if breaker.State() != breakerClosed || !breaker.Allow() {
+		t.Errorf("Expected the successful probe to close the breaker, got %s", breaker.State())
+	}
+}
+
+// Test a breaker without threshold never opens
+func TestCircuitBreakerDisabled(t *testing.T) {
+	breaker := NewCircuitBreaker("test", 0, time.Minute)
+	for range 10 {
+		breaker.Allow()
+		breaker.Record(errors.New("timeout"))
+	}
+	if !breaker.Allow() {
+		t.Error("Expected the calls to go through")
+	}
+}
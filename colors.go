@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Other spellings of a color, keyed in lower case, stored as the value
+var colorSynonyms = map[string]string{
+	"gray":   "Grey",
+	"ginger": "Orange",
+	"tortie": "Tortoiseshell",
+}
+
+// Trims and title-cases a color, then maps its synonyms, so " light GRAY" is stored as "Light Grey"
+func normalizeColor(color string) string {
+	words := strings.Fields(strings.ToLower(color))
+	for idx, word := range words {
+		if synonym, found := colorSynonyms[word]; found {
+			words[idx] = synonym
+			continue
+		}
+		first, size := utf8.DecodeRuneInString(word)
+		words[idx] = string(unicode.ToTitle(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// Normalizes the color of a cat about to be stored, with --normalize-colors
+func applyColorPolicy(cat *Cat) {
+	if currentConfig().NormalizeColors {
+		cat.Color = normalizeColor(cat.Color)
+	}
+}
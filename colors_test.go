@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// COLOR NORMALIZATION TESTS
+// =============================================================================
+
+// Test the colors are trimmed, title-cased and their synonyms mapped
+func TestNormalizeColor(t *testing.T) {
+	tests := []struct {
+		color    string
+		expected string
+	}{
+		{"", ""},
+		{"Grey", "Grey"},
+		{"  black ", "Black"},
+		{"ORANGE", "Orange"},
+		{"gray", "Grey"},
+		{"light  GRAY", "Light Grey"},
+		{"Ginger", "Orange"},
+		{"tortie", "Tortoiseshell"},
+		{"écaille", "Écaille"},
+	}
+
+	for _, test := range tests {
+		if color := normalizeColor(test.color); color != test.expected {
+			t.Errorf("Color %q: expected %q, got %q", test.color, test.expected, color)
+		}
+	}
+}
+
+// Test the normalized color is stored and echoed, only with --normalize-colors
+func TestNormalizeColorsFlag(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto", Color: "Grey"})
+	app := newApp()
+	send := func(method, target, body string) string {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest(method, target, strings.NewReader(body)))
+		if rec.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s: unexpected status code %d", method, target, rec.Code)
+		}
+		return rec.Body.String()
+	}
+
+	if body := send("POST", "/api/cats", `{"name": "Felix", "color": " gray "}`); !strings.Contains(body, `"color":" gray "`) {
+		t.Errorf("Expected the color as sent by default, got %s", body)
+	}
+
+	cfg := currentConfig()
+	cfg.NormalizeColors = true
+	setConfig(cfg)
+
+	if body := send("POST", "/api/cats", `{"name": "Felix", "color": " gray "}`); !strings.Contains(body, `"color":"Grey"`) {
+		t.Errorf("Expected the normalized color to be echoed on create, got %s", body)
+	}
+	if body := send("PUT", "/api/cats/id2", `{"name": "Tom", "color": "ginger"}`); !strings.Contains(body, `"color":"Orange"`) {
+		t.Errorf("Expected the normalized color on put, got %s", body)
+	}
+	send("PATCH", "/api/cats/id1", `{"color": "BLACK"}`)
+	if cat, _ := catsStore.Get(t.Context(), "id1"); cat.Color != "Black" {
+		t.Errorf("Expected the normalized color to be stored on patch, got %q", cat.Color)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Subcommands of the binary, serve being the default
+const (
+	commandServe   = "serve"
+	commandConvert = "convert"
+)
+
+// Splits the subcommand from its arguments, flags alone running the server as before
+func parseCommand(args []string) (string, []string, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return commandServe, args, nil
+	}
+	switch args[0] {
+	case commandServe, commandConvert:
+		return args[0], args[1:], nil
+	}
+	return "", nil, fmt.Errorf("unknown command '%s', must be '%s' or '%s'", args[0], commandServe, commandConvert)
+}
+
+// Prints the JSON of the YAML spec given, of the embedded one without argument, no server started
+func runConvert(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s [spec.yml]", commandConvert)
+	}
+	if len(args) == 1 {
+		cfg := currentConfig()
+		cfg.SpecFile = args[0]
+		setConfig(cfg)
+	}
+	return yml2json()
+}
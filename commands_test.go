@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// =============================================================================
+// COMMANDS TESTS
+// =============================================================================
+
+// Test the subcommand is split from its arguments, the flags alone serving
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		command  string
+		expected []string
+	}{
+		{nil, commandServe, nil},
+		{[]string{"--addr", ":9090"}, commandServe, []string{"--addr", ":9090"}},
+		{[]string{"serve", "--seed"}, commandServe, []string{"--seed"}},
+		{[]string{"convert", "openapi.yml"}, commandConvert, []string{"openapi.yml"}},
+	}
+
+	for _, test := range tests {
+		command, args, err := parseCommand(test.args)
+		if err != nil || command != test.command || !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%v: expected %s with %v, got %s with %v (%v)", test.args, test.command, test.expected, command, args, err)
+		}
+	}
+
+	if _, _, err := parseCommand([]string{"start"}); err == nil {
+		t.Error("Expected an unknown command refused")
+	}
+}
+
+// Test the convert command reads the given file and reports the unreadable ones
+func TestRunConvert(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	missing := filepath.Join(t.TempDir(), "missing.yml")
+	if err := runConvert([]string{missing}); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	if currentConfig().SpecFile != missing {
+		t.Errorf("Expected the file given converted, got %q", currentConfig().SpecFile)
+	}
+	if err := runConvert([]string{"a.yml", "b.yml"}); err == nil {
+		t.Error("Expected the usage for two files")
+	}
+
+	// Discard the printed spec
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err := runConvert([]string{"openapi.yml"}); err != nil {
+		t.Errorf("Expected the spec converted, got %v", err)
+	}
+}
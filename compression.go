@@ -0,0 +1,146 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Supported content encodings, the first one wins when the client likes several equally
+var compressionPreference = []string{"br", "gzip"}
+
+var compressors = map[string]func(io.Writer) io.WriteCloser{
+	"br":   func(out io.Writer) io.WriteCloser { return brotli.NewWriter(out) },
+	"gzip": func(out io.Writer) io.WriteCloser { return gzip.NewWriter(out) },
+}
+
+// Quality of each value listed in an Accept-Encoding or Accept-Language header, 1 when not given
+func parseQualities(header string) map[string]float64 {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, found := strings.Cut(param, "=")
+			if found && strings.TrimSpace(key) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					quality = parsed
+				} else {
+					quality = 0
+				}
+			}
+		}
+		qualities[coding] = quality
+	}
+	return qualities
+}
+
+// Best supported encoding for the client, empty for identity
+func negotiateEncoding(header string) string {
+	qualities := parseQualities(header)
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range compressionPreference {
+		quality, found := qualities[encoding]
+		if !found {
+			// The wildcard only covers the codings not listed
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// Compresses the body once the status tells there is one
+type compressedWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	code        int
+	wroteHeader bool
+}
+
+// The status is held until the first bytes, to sniff their type like net/http would
+func (w *compressedWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *compressedWriter) sendHeader(firstBytes []byte) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	header := w.Header()
+	hasBody := w.code >= http.StatusOK && w.code != http.StatusNoContent && w.code != http.StatusNotModified
+	// A partial content is a range of the identity body
+	if hasBody && w.code != http.StatusPartialContent && header.Get("Content-Encoding") == "" {
+		// net/http does not sniff the encoded bytes
+		if header.Get("Content-Type") == "" && len(firstBytes) > 0 {
+			header.Set("Content-Type", http.DetectContentType(firstBytes))
+		}
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		w.encoder = compressors[w.encoding](w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.code)
+}
+
+func (w *compressedWriter) Write(data []byte) (int, error) {
+	w.sendHeader(data)
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+// Pushes the bytes compressed so far, for the streamed responses
+func (w *compressedWriter) Flush() {
+	w.sendHeader(nil)
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressedWriter) close() {
+	w.sendHeader(nil)
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// Compresses the responses with the encoding negotiated through Accept-Encoding
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		compressed := &compressedWriter{ResponseWriter: w, encoding: encoding}
+		defer compressed.close()
+		next.ServeHTTP(compressed, r)
+	})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// =============================================================================
+// COMPRESSION TESTS
+// =============================================================================
+
+// Test the encoding is picked from the quality values
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"GZIP ; q=0.8", "gzip"},
+		{"*", "br"},
+		{"*;q=0.5, br;q=0", "gzip"},
+		{"gzip;q=0, br;q=0", ""},
+		{"gzip;q=nonsense", ""},
+		{"deflate, compress", ""},
+	}
+
+	for _, test := range tests {
+		if encoding := negotiateEncoding(test.acceptEncoding); encoding != test.expected {
+			t.Errorf("Accept-Encoding %q: expected %q, got %q", test.acceptEncoding, test.expected, encoding)
+		}
+	}
+}
+
+// Test the responses are compressed with the negotiated encoding
+func TestCompressedResponses(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"": func(body io.Reader) (io.Reader, error) { return body, nil },
+		"br": func(body io.Reader) (io.Reader, error) {
+			return brotli.NewReader(body), nil
+		},
+		"gzip": func(body io.Reader) (io.Reader, error) {
+			return gzip.NewReader(body)
+		},
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/cats/id1", nil)
+			req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if encoding := rec.Header().Get("Content-Encoding"); encoding != test.encoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", test.encoding, encoding)
+			}
+			if vary := rec.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("Expected Vary on Accept-Encoding, got %q", vary)
+			}
+			if test.encoding != "" && rec.Header().Get("Content-Length") != "" {
+				t.Error("The identity length should not be announced for a compressed body")
+			}
+
+			reader, err := decoders[test.encoding](rec.Body)
+			if err != nil {
+				t.Fatalf("Failed to decode the body: %v", err)
+			}
+			body, _ := io.ReadAll(reader)
+			if !strings.Contains(string(body), `"name":"Toto"`) {
+				t.Errorf("Expected the cat, got %q", body)
+			}
+		})
+	}
+}
+
+// Test the bodyless responses are left alone and the HTML is still sniffed
+func TestCompressionEdgeCases(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	req := httptest.NewRequest("DELETE", "/api/cats/id1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected an empty 204, got %d with %q (%q)", rec.Code, rec.Body.String(), rec.Header().Get("Content-Encoding"))
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("Expected an HTML home page, got %q", contentType)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Backends of the cats store
+const (
+	storeMemory = "memory"
+	storeRedis  = "redis"
+)
+
+// Behaviors when creating a cat into a full store
+const (
+	evictionReject = "reject"
+	evictionOldest = "oldest"
+)
+
+// Minimum level of the logged lines
+const (
+	logLevelDebug = "debug"
+	logLevelInfo  = "info"
+	logLevelWarn  = "warn"
+	logLevelError = "error"
+)
+
+// Prefix of the environment variables overriding the config file, CATS_BASE_PATH for --base-path
+const envPrefix = "CATS_"
+
+// Settings kept out of the startup log
+var secretSettings = map[string]bool{"api-key": true, "webhook-url": true}
+
+// Runtime settings of the server, from the lowest precedence: the defaults, the --config file,
+// the CATS_* environment variables and the command line flags
+type Config struct {
+	ConfigFile             string
+	Addr                   string
+	LogLevel               string
+	BasePath               string
+	TLSCert                string
+	TLSKey                 string
+	UniqueCats             bool
+	MaxCats                int
+	EvictionPolicy         string
+	SpecFile               string
+	Store                  string
+	RedisAddr              string
+	StoreRetries           int
+	StoreBackoff           time.Duration
+	MaxBodyBytes           int64
+	Seed                   bool
+	LogBuffer              int
+	APIKey                 string
+	LogColor               string
+	IDStrategy             string
+	AllowClientIDs         bool
+	FieldNaming            string
+	ShutdownTimeout        time.Duration
+	ShutdownDelay          time.Duration
+	RequestTimeout         time.Duration
+	TrackDeletes           bool
+	ValidateRequests       bool
+	IdempotencyTTL         time.Duration
+	MaxPageSize            int
+	MaxNameLength          int
+	NormalizeColors        bool
+	Deterministic          bool
+	PhotoDir               string
+	JSONNewline            bool
+	WebhookURL             string
+	DefaultColor           string
+	DefaultBirthDate       string
+	MaxConcurrent          int
+	StrictJSON             bool
+	PublicURL              string
+	DebugBodies            bool
+	DebugBodyMax           int
+	DebugRedact            string
+	OrderedEvents          bool
+	WebhookTimeout         time.Duration
+	WebhookConcurrency     int
+	WebhookBreakerFailures int
+	WebhookBreakerCooldown time.Duration
+	AdminDump              bool
+}
+
+func defaultConfig() Config {
+	return Config{
+		Addr:                   ":8080",
+		LogLevel:               logLevelDebug,
+		BasePath:               "/api",
+		EvictionPolicy:         evictionReject,
+		Store:                  storeMemory,
+		RedisAddr:              "localhost:6379",
+		StoreRetries:           2,
+		StoreBackoff:           50 * time.Millisecond,
+		MaxBodyBytes:           1 << 20,
+		LogBuffer:              defaultLogBuffer,
+		LogColor:               logColorAuto,
+		IDStrategy:             idStrategyUUID,
+		FieldNaming:            namingCamel,
+		ShutdownTimeout:        10 * time.Second,
+		RequestTimeout:         30 * time.Second,
+		IdempotencyTTL:         24 * time.Hour,
+		MaxPageSize:            defaultMaxPageSize,
+		MaxNameLength:          64,
+		PhotoDir:               "photos",
+		JSONNewline:            true,
+		DebugBodyMax:           1024,
+		WebhookTimeout:         5 * time.Second,
+		WebhookConcurrency:     2,
+		WebhookBreakerFailures: 5,
+		WebhookBreakerCooldown: 30 * time.Second,
+	}
+}
+
+// Settings in effect, replaced as a whole on reload so a request reads a consistent set
+var liveConfig atomic.Pointer[Config]
+
+func init() {
+	setConfig(defaultConfig())
+}
+
+// Copy of the settings in effect, read once per use rather than cached
+func currentConfig() Config {
+	return *liveConfig.Load()
+}
+
+func setConfig(cfg Config) {
+	liveConfig.Store(&cfg)
+}
+
+// Binds the command line flags onto the given config, current values are the defaults
+func registerFlags(flags *flag.FlagSet, cfg *Config) {
+	flags.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "YAML file of settings named like the flags, overridden by the CATS_* environment variables and the flags")
+	flags.StringVar(&cfg.Addr, "addr", cfg.Addr, "Address the server listens on, host:port or unix:///path/to/socket")
+	flags.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Minimum level of the logged lines: 'debug', 'info', 'warn' or 'error'")
+	flags.StringVar(&cfg.BasePath, "base-path", cfg.BasePath, "Path prefix the API routes are mounted under")
+	flags.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "Path to the TLS certificate (PEM), HTTPS is enabled along with --tls-key")
+	flags.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "Path to the TLS private key (PEM), HTTPS is enabled along with --tls-cert")
+	flags.BoolVar(&cfg.UniqueCats, "unique-cats", cfg.UniqueCats, "Reject the creation of a cat having the same name and birth date as an existing one")
+	flags.StringVar(&cfg.SpecFile, "spec-file", cfg.SpecFile, "OpenAPI YAML file to serve instead of the embedded one")
+	flags.StringVar(&cfg.PublicURL, "public-url", cfg.PublicURL, "Base URL the clients reach the server at, like https://cats.example.com, for the servers of the served spec, the request host when empty")
+	flags.StringVar(&cfg.Store, "store", cfg.Store, "Backend of the cats: 'memory' or 'redis'")
+	flags.StringVar(&cfg.RedisAddr, "redis-addr", cfg.RedisAddr, "Address of the redis server, with --store=redis")
+	flags.IntVar(&cfg.StoreRetries, "store-retries", cfg.StoreRetries, "Retries of a redis read or delete failing on a connection error, 0 to fail at once")
+	flags.DurationVar(&cfg.StoreBackoff, "store-retry-backoff", cfg.StoreBackoff, "Wait before the first store retry, doubled for each next one")
+	flags.Int64Var(&cfg.MaxBodyBytes, "max-body-bytes", cfg.MaxBodyBytes, "Maximum size of the request bodies, 0 for unlimited")
+	flags.StringVar(&cfg.IDStrategy, "id-strategy", cfg.IDStrategy, "IDs of the new cats: 'uuid' or 'seq' for short cat-1, cat-2... unique to this instance")
+	flags.BoolVar(&cfg.AllowClientIDs, "allow-client-ids", cfg.AllowClientIDs, "Keep the UUID id sent with a new cat, 409 when a cat already has it, rather than always assigning one")
+	flags.StringVar(&cfg.FieldNaming, "field-naming", cfg.FieldNaming, "JSON field names of the requests and responses: 'camel' (birthDate) or 'snake' (birth_date)")
+	flags.DurationVar(&cfg.ShutdownDelay, "shutdown-delay", cfg.ShutdownDelay, "Time the new requests and /ready answer 503 on a shutdown signal, before the drain closes the listener")
+	flags.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "Time given to the in-flight requests to complete when stopping")
+	flags.IntVar(&cfg.MaxConcurrent, "max-concurrent", cfg.MaxConcurrent, "Requests served at once, the next ones get a 503 with Retry-After, 0 for unlimited")
+	flags.DurationVar(&cfg.RequestTimeout, "request-timeout", cfg.RequestTimeout, "Time given to a request before answering 503, 0 for unlimited, the export is never bounded")
+	flags.BoolVar(&cfg.TrackDeletes, "track-deletes", cfg.TrackDeletes, "Answer 410 Gone rather than 404 for the recently deleted cats")
+	flags.BoolVar(&cfg.ValidateRequests, "validate-requests", cfg.ValidateRequests, "Check the API requests against the OpenAPI spec, answering 400 on mismatch")
+	flags.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "How long a creation is replayed for a retried Idempotency-Key, 0 to ignore the header")
+	flags.BoolVar(&cfg.Seed, "seed", cfg.Seed, "Load a set of example cats at startup")
+	flags.BoolVar(&cfg.DebugBodies, "debug-bodies", cfg.DebugBodies, "Log the request and response bodies at the debug level, for troubleshooting a client only")
+	flags.IntVar(&cfg.DebugBodyMax, "debug-body-max", cfg.DebugBodyMax, "Most bytes of a body logged with --debug-bodies, the rest is cut")
+	flags.StringVar(&cfg.DebugRedact, "debug-redact-fields", cfg.DebugRedact, "Comma separated JSON fields masked in the bodies logged with --debug-bodies, like 'name,breed'")
+	flags.BoolVar(&cfg.AdminDump, "admin-dump", cfg.AdminDump, "Serve the store internals, the raw cats included, at /api/admin/dump behind --api-key, for troubleshooting only")
+	flags.IntVar(&cfg.LogBuffer, "log-buffer", cfg.LogBuffer, "Number of recent log lines served by /logs, 0 to disable")
+	flags.StringVar(&cfg.LogColor, "log-color", cfg.LogColor, "Colored log levels: 'auto' for a terminal only, 'always' or 'never'")
+	flags.StringVar(&cfg.APIKey, "api-key", cfg.APIKey, "Key expected in the X-API-Key header of the protected endpoints, open when empty")
+	flags.IntVar(&cfg.MaxNameLength, "max-name-length", cfg.MaxNameLength, "Most characters in a cat name, counted after trimming the surrounding spaces")
+	flags.IntVar(&cfg.MaxPageSize, "max-page-size", cfg.MaxPageSize, "Most cat IDs listed at once, a larger ?limit= is clamped to it")
+	flags.StringVar(&cfg.DefaultColor, "default-color", cfg.DefaultColor, "Color given to a new cat sent without one, an explicit empty color is kept, none when empty")
+	flags.StringVar(&cfg.DefaultBirthDate, "default-birth-date", cfg.DefaultBirthDate, "Birth date (YYYY-MM-DD or 'unknown') given to a new cat sent without one, none when empty")
+	flags.BoolVar(&cfg.NormalizeColors, "normalize-colors", cfg.NormalizeColors, "Store the colors trimmed, title-cased and with their synonyms mapped, 'gray' as 'Grey'")
+	flags.BoolVar(&cfg.StrictJSON, "strict-json", cfg.StrictJSON, "Reject the request bodies having a field unknown to the API, like a misspelled 'colour'")
+	flags.BoolVar(&cfg.JSONNewline, "json-newline", cfg.JSONNewline, "End the JSON responses with a newline like json.Encoder, --json-newline=false for the exact document")
+	flags.BoolVar(&cfg.OrderedEvents, "ordered-events", cfg.OrderedEvents, "Publish the cat events in the order of the mutations under concurrent writers, which then go one at a time")
+	flags.StringVar(&cfg.WebhookURL, "webhook-url", cfg.WebhookURL, "URL POSTed a JSON event when a cat is created or deleted, none when empty")
+	flags.DurationVar(&cfg.WebhookTimeout, "webhook-timeout", cfg.WebhookTimeout, "Time given to a webhook delivery attempt, connection included")
+	flags.IntVar(&cfg.WebhookConcurrency, "webhook-concurrency", cfg.WebhookConcurrency, "Webhook deliveries at once, each with its own connection")
+	flags.IntVar(&cfg.WebhookBreakerFailures, "webhook-breaker-failures", cfg.WebhookBreakerFailures, "Failed webhook deliveries in a row stopping the next ones for --webhook-breaker-cooldown, 0 to always deliver")
+	flags.DurationVar(&cfg.WebhookBreakerCooldown, "webhook-breaker-cooldown", cfg.WebhookBreakerCooldown, "Time the webhook events are dropped once the breaker opens, before a single delivery probes the webhook")
+	flags.StringVar(&cfg.PhotoDir, "photo-dir", cfg.PhotoDir, "Directory of the cat photos sent along with the creations")
+	flags.BoolVar(&cfg.Deterministic, "deterministic", cfg.Deterministic, "Testing aid: UUIDs from a fixed seed and lists sorted by ID, never for a real deployment")
+	flags.IntVar(&cfg.MaxCats, "max-cats", cfg.MaxCats, "Maximum number of stored cats, 0 for unlimited")
+	flags.StringVar(&cfg.EvictionPolicy, "eviction-policy", cfg.EvictionPolicy, "When the store is full: 'reject' the creation or evict the 'oldest' cat")
+}
+
+// Checks the values the flags cannot constrain by themselves
+func (cfg Config) validate() error {
+	if cfg.LogLevel != logLevelDebug && cfg.LogLevel != logLevelInfo && cfg.LogLevel != logLevelWarn && cfg.LogLevel != logLevelError {
+		return fmt.Errorf("invalid --log-level '%s', must be '%s', '%s', '%s' or '%s'", cfg.LogLevel, logLevelDebug, logLevelInfo, logLevelWarn, logLevelError)
+	}
+	if cfg.Store != storeMemory && cfg.Store != storeRedis {
+		return fmt.Errorf("invalid --store '%s', must be '%s' or '%s'", cfg.Store, storeMemory, storeRedis)
+	}
+	if cfg.IDStrategy != idStrategyUUID && cfg.IDStrategy != idStrategySeq {
+		return fmt.Errorf("invalid --id-strategy '%s', must be '%s' or '%s'", cfg.IDStrategy, idStrategyUUID, idStrategySeq)
+	}
+	if cfg.FieldNaming != namingCamel && cfg.FieldNaming != namingSnake {
+		return fmt.Errorf("invalid --field-naming '%s', must be '%s' or '%s'", cfg.FieldNaming, namingCamel, namingSnake)
+	}
+	if cfg.PublicURL != "" {
+		if publicURL, err := url.Parse(cfg.PublicURL); err != nil || (publicURL.Scheme != "http" && publicURL.Scheme != "https") || publicURL.Host == "" {
+			return fmt.Errorf("invalid --public-url '%s', must be an absolute http or https URL", cfg.PublicURL)
+		}
+	}
+	if cfg.WebhookTimeout <= 0 {
+		return fmt.Errorf("invalid --webhook-timeout %v, must be positive", cfg.WebhookTimeout)
+	}
+	if cfg.WebhookConcurrency <= 0 {
+		return fmt.Errorf("invalid --webhook-concurrency %d, must be positive", cfg.WebhookConcurrency)
+	}
+	if cfg.WebhookBreakerFailures < 0 {
+		return fmt.Errorf("invalid --webhook-breaker-failures %d, must be positive or 0", cfg.WebhookBreakerFailures)
+	}
+	if cfg.WebhookBreakerCooldown < 0 {
+		return fmt.Errorf("invalid --webhook-breaker-cooldown %v, must be positive or 0", cfg.WebhookBreakerCooldown)
+	}
+	if cfg.DebugBodyMax <= 0 {
+		return fmt.Errorf("invalid --debug-body-max %d, must be positive", cfg.DebugBodyMax)
+	}
+	if cfg.StoreRetries < 0 {
+		return fmt.Errorf("invalid --store-retries %d, must be positive or 0", cfg.StoreRetries)
+	}
+	if cfg.StoreBackoff < 0 {
+		return fmt.Errorf("invalid --store-retry-backoff %v, must be positive or 0", cfg.StoreBackoff)
+	}
+	if cfg.ShutdownDelay < 0 {
+		return fmt.Errorf("invalid --shutdown-delay %v, must be positive or 0", cfg.ShutdownDelay)
+	}
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid --shutdown-timeout %v, must be positive or 0", cfg.ShutdownTimeout)
+	}
+	if cfg.MaxConcurrent < 0 {
+		return fmt.Errorf("invalid --max-concurrent %d, must be positive or 0", cfg.MaxConcurrent)
+	}
+	if cfg.RequestTimeout < 0 {
+		return fmt.Errorf("invalid --request-timeout %v, must be positive or 0", cfg.RequestTimeout)
+	}
+	if cfg.ValidateRequests && cfg.FieldNaming == namingSnake {
+		return fmt.Errorf("--validate-requests needs the camelCase fields described by the spec, not --field-naming %s", namingSnake)
+	}
+	if cfg.IdempotencyTTL < 0 {
+		return fmt.Errorf("invalid --idempotency-ttl %v, must be positive or 0", cfg.IdempotencyTTL)
+	}
+	if cfg.MaxBodyBytes < 0 {
+		return fmt.Errorf("invalid --max-body-bytes %d, must be positive or 0", cfg.MaxBodyBytes)
+	}
+	if cfg.DefaultBirthDate != "" && cfg.DefaultBirthDate != unknownBirthDate {
+		if verr := (CatValidator{}).Validate(Cat{Name: "default", BirthDate: cfg.DefaultBirthDate}); verr.HasErrors() {
+			return fmt.Errorf("invalid --default-birth-date '%s', must be a past YYYY-MM-DD or '%s'", cfg.DefaultBirthDate, unknownBirthDate)
+		}
+	}
+	if cfg.WebhookURL != "" {
+		if webhook, err := url.Parse(cfg.WebhookURL); err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("invalid --webhook-url '%s', must be an absolute http or https URL", cfg.WebhookURL)
+		}
+	}
+	if cfg.LogBuffer < 0 {
+		return fmt.Errorf("invalid --log-buffer %d, must be positive or 0", cfg.LogBuffer)
+	}
+	if cfg.LogColor != logColorAuto && cfg.LogColor != logColorAlways && cfg.LogColor != logColorNever {
+		return fmt.Errorf("invalid --log-color '%s', must be '%s', '%s' or '%s'", cfg.LogColor, logColorAuto, logColorAlways, logColorNever)
+	}
+	if cfg.MaxNameLength <= 0 {
+		return fmt.Errorf("invalid --max-name-length %d, must be positive", cfg.MaxNameLength)
+	}
+	if cfg.MaxPageSize <= 0 {
+		return fmt.Errorf("invalid --max-page-size %d, must be positive", cfg.MaxPageSize)
+	}
+	if cfg.MaxCats < 0 {
+		return fmt.Errorf("invalid --max-cats %d, must be positive or 0", cfg.MaxCats)
+	}
+	if cfg.EvictionPolicy != evictionReject && cfg.EvictionPolicy != evictionOldest {
+		return fmt.Errorf("invalid --eviction-policy '%s', must be '%s' or '%s'", cfg.EvictionPolicy, evictionReject, evictionOldest)
+	}
+	return nil
+}
+
+// Environment variable of a flag, CATS_MAX_CATS for max-cats
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Settings of a YAML config file, keyed by flag name
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	settings := map[string]string{}
+	for name, value := range values {
+		switch value.(type) {
+		case map[string]any, []any:
+			return nil, fmt.Errorf("invalid config file %s: '%s' must be a single value", path, name)
+		}
+		settings[name] = fmt.Sprint(value)
+	}
+	return settings, nil
+}
+
+// Parses the command line flags, then fills the ones not given from the environment, else from the config file
+func loadConfig(flags *flag.FlagSet, cfg *Config, args []string, lookupEnv func(string) (string, bool)) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	onCommandLine := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { onCommandLine[f.Name] = true })
+
+	if !onCommandLine["config"] {
+		if path, found := lookupEnv(envName("config")); found {
+			cfg.ConfigFile = path
+		}
+	}
+
+	settings := map[string]string{}
+	if cfg.ConfigFile != "" {
+		fileSettings, err := readConfigFile(cfg.ConfigFile)
+		if err != nil {
+			return err
+		}
+		for name, value := range fileSettings {
+			if name == "config" || flags.Lookup(name) == nil {
+				return fmt.Errorf("unknown setting '%s' in %s", name, cfg.ConfigFile)
+			}
+			settings[name] = value
+		}
+	}
+	flags.VisitAll(func(f *flag.Flag) {
+		if value, found := lookupEnv(envName(f.Name)); found && f.Name != "config" {
+			settings[f.Name] = value
+		}
+	})
+
+	for name, value := range settings {
+		if onCommandLine[name] {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid --%s '%s': %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+// The settings in effect, the secrets masked, for the startup log
+func describeConfig(flags *flag.FlagSet) string {
+	var settings []string
+	flags.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if secretSettings[f.Name] && value != "" {
+			value = "***"
+		}
+		settings = append(settings, f.Name+"="+value)
+	})
+	sort.Strings(settings)
+	return strings.Join(settings, " ")
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fresh flags bound on a default config
+func newConfigFlags() (*flag.FlagSet, *Config) {
+	cfg := defaultConfig()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	registerFlags(flags, &cfg)
+	return flags, &cfg
+}
+
+// Fake environment of the given variables
+func envOf(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, found := vars[name]
+		return value, found
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write the config file: %v", err)
+	}
+	return path
+}
+
+// =============================================================================
+// CONFIG SOURCES TESTS
+// =============================================================================
+
+// Test the flags win over the environment, which wins over the file, which wins over the defaults
+func TestLoadConfigPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `
+store: redis
+max-cats: 10
+request-timeout: 5s
+unique-cats: true
+base-path: /file
+`)
+	flags, cfg := newConfigFlags()
+	env := envOf(map[string]string{
+		"CATS_MAX_CATS":  "20",
+		"CATS_BASE_PATH": "/env",
+	})
+
+	if err := loadConfig(flags, cfg, []string{"--config", path, "--base-path", "/flag"}, env); err != nil {
+		t.Fatalf("Failed to load the config: %v", err)
+	}
+
+	if cfg.BasePath != "/flag" {
+		t.Errorf("Expected the flag to win, got %q", cfg.BasePath)
+	}
+	if cfg.MaxCats != 20 {
+		t.Errorf("Expected the environment over the file, got %d", cfg.MaxCats)
+	}
+	if cfg.Store != storeRedis || !cfg.UniqueCats || cfg.RequestTimeout != 5*time.Second {
+		t.Errorf("Expected the file settings, got %+v", cfg)
+	}
+	if cfg.Addr != ":8080" || cfg.LogLevel != logLevelDebug {
+		t.Errorf("Expected the defaults of the settings given nowhere, got %q and %q", cfg.Addr, cfg.LogLevel)
+	}
+}
+
+// Test the config file can be given through the environment
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := writeConfigFile(t, "addr: :9090\n")
+	flags, cfg := newConfigFlags()
+
+	if err := loadConfig(flags, cfg, nil, envOf(map[string]string{"CATS_CONFIG": path})); err != nil {
+		t.Fatalf("Failed to load the config: %v", err)
+	}
+	if cfg.Addr != ":9090" {
+		t.Errorf("Expected the address of the file, got %q", cfg.Addr)
+	}
+}
+
+// Test the mistakes of a config file are reported
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown setting", "colour: Grey\n"},
+		{"wrong type", "max-cats: many\n"},
+		{"nested value", "store:\n  type: redis\n"},
+		{"not YAML", "store: [redis\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags, cfg := newConfigFlags()
+			path := writeConfigFile(t, test.content)
+			if err := loadConfig(flags, cfg, []string{"--config", path}, envOf(nil)); err == nil {
+				t.Errorf("Expected an error for %q", test.content)
+			}
+		})
+	}
+
+	flags, cfg := newConfigFlags()
+	if err := loadConfig(flags, cfg, []string{"--config", "missing.yaml"}, envOf(nil)); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+
+	flags, cfg = newConfigFlags()
+	if err := loadConfig(flags, cfg, nil, envOf(map[string]string{"CATS_SEED": "sometimes"})); err == nil {
+		t.Error("Expected an error for an invalid environment value")
+	}
+}
+
+// Test the logged config hides the secrets
+func TestDescribeConfig(t *testing.T) {
+	flags, cfg := newConfigFlags()
+	if err := loadConfig(flags, cfg, []string{"--api-key", "mysecret", "--store", "redis"}, envOf(nil)); err != nil {
+		t.Fatalf("Failed to load the config: %v", err)
+	}
+
+	description := describeConfig(flags)
+	if strings.Contains(description, "mysecret") || !strings.Contains(description, "api-key=***") {
+		t.Errorf("Expected the API key masked, got %q", description)
+	}
+	if !strings.Contains(description, "store=redis") || !strings.Contains(description, "addr=:8080") {
+		t.Errorf("Expected the effective settings, got %q", description)
+	}
+}
+
+// Test the eviction settings are checked at startup
+func TestConfigValidate(t *testing.T) {
+	if err := defaultConfig().validate(); err != nil {
+		t.Errorf("Expected a valid default config, got %v", err)
+	}
+
+	cfg := defaultConfig()
+	cfg.EvictionPolicy = "random"
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected an error for an unknown eviction policy")
+	}
+
+	cfg = defaultConfig()
+	cfg.EvictionPolicy, cfg.MaxCats = evictionOldest, -1
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+
+	cfg = defaultConfig()
+	cfg.Store = "postgres"
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected an error for an unknown store")
+	}
+
+	cfg = defaultConfig()
+	cfg.LogLevel = "verbose"
+	if err := cfg.validate(); err == nil {
+		t.Error("Expected an error for an unknown log level")
+	}
+
+	for _, publicURL := range []string{"cats.example.com", "ftp://cats.example.com", "https://"} {
+		cfg = defaultConfig()
+		cfg.PublicURL = publicURL
+		if err := cfg.validate(); err == nil {
+			t.Errorf("Expected an error for the public URL %q", publicURL)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// How long a clear-all confirmation token can be redeemed
+const confirmationTTL = time.Minute
+
+// One-time tokens confirming a destructive request, each redeemable once before it expires
+type ConfirmationTokens struct {
+	lock    sync.Mutex
+	expires map[string]time.Time
+	now     func() time.Time
+}
+
+func NewConfirmationTokens() *ConfirmationTokens {
+	return &ConfirmationTokens{expires: map[string]time.Time{}, now: time.Now}
+}
+
+func (tokens *ConfirmationTokens) Issue(ttl time.Duration) string {
+	tokens.lock.Lock()
+	defer tokens.lock.Unlock()
+
+	// The expired tokens are forgotten along the way, the map stays small
+	now := tokens.now()
+	for token, expires := range tokens.expires {
+		if now.After(expires) {
+			delete(tokens.expires, token)
+		}
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+	encoded := hex.EncodeToString(token)
+	tokens.expires[encoded] = now.Add(ttl)
+	return encoded
+}
+
+// Spends the token, false when unknown, already spent or expired
+func (tokens *ConfirmationTokens) Redeem(token string) bool {
+	tokens.lock.Lock()
+	defer tokens.lock.Unlock()
+
+	expires, found := tokens.expires[token]
+	delete(tokens.expires, token)
+	return found && !tokens.now().After(expires)
+}
+
+// Kept in memory, a token is only redeemed on the instance which issued it
+var clearAllTokens = NewConfirmationTokens()
+
+// Answer of a clear-all waiting for its confirmation
+type ConfirmationRequired struct {
+	Error string `json:"error"`
+	// Cats the confirmed request would delete
+	Count     int       `json:"count"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Refuses a clear-all with a 409 and a fresh token, until it is resent with ?confirm=<token>
+func confirmClearAll(req *http.Request, confirm string) (int, any, bool) {
+	if confirm != "" && confirm != "false" && clearAllTokens.Redeem(confirm) {
+		return 0, nil, false
+	}
+
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		code, body := storeFailure(req, err)
+		return code, body, true
+	}
+
+	message := localize(req, "confirm_clear_all")
+	if confirm != "" && confirm != "false" {
+		message = localize(req, "confirm_expired")
+	}
+	Logger.Infof("Clear-all of %d cats waiting for a confirmation", len(cats))
+	return http.StatusConflict, ConfirmationRequired{
+		Error:     message,
+		Count:     len(cats),
+		Token:     clearAllTokens.Issue(confirmationTTL),
+		ExpiresAt: time.Now().Add(confirmationTTL).UTC(),
+	}, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// CLEAR-ALL CONFIRMATION TESTS
+// =============================================================================
+
+// Test a token is redeemed once and not after it expired
+func TestConfirmationTokens(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tokens := NewConfirmationTokens()
+	tokens.now = func() time.Time { return now }
+
+	token := tokens.Issue(time.Minute)
+	if other := tokens.Issue(time.Minute); other == token || len(token) != 32 {
+		t.Errorf("Expected distinct random tokens, got %q and %q", token, other)
+	}
+	if !tokens.Redeem(token) {
+		t.Error("Expected a fresh token to be redeemed")
+	}
+	if tokens.Redeem(token) {
+		t.Error("Expected a token to be redeemed only once")
+	}
+
+	expiring := tokens.Issue(time.Minute)
+	now = now.Add(2 * time.Minute)
+	if tokens.Redeem(expiring) || tokens.Redeem("unknown") {
+		t.Error("Expected the expired and unknown tokens to be refused")
+	}
+
+	tokens.Issue(time.Minute)
+	if len(tokens.expires) != 1 {
+		t.Errorf("Expected the expired tokens to be forgotten, got %d", len(tokens.expires))
+	}
+}
+
+// Test all the cats are only deleted with the token of a first attempt
+func TestClearAllConfirmation(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})
+	app := newAppWithStore(store)
+
+	clearAll := func(query string) (*httptest.ResponseRecorder, ConfirmationRequired) {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats"+query, nil))
+		var confirmation ConfirmationRequired
+		json.Unmarshal(rec.Body.Bytes(), &confirmation)
+		return rec, confirmation
+	}
+
+	for _, query := range []string{"", "?confirm=false", "?confirm=guessed"} {
+		rec, confirmation := clearAll(query)
+		if rec.Code != http.StatusConflict || confirmation.Count != 2 || confirmation.Token == "" || confirmation.ExpiresAt.IsZero() {
+			t.Errorf("%q: expected a 409 with the count and a token, got %d with %+v", query, rec.Code, confirmation)
+		}
+	}
+	if cats, _ := store.List(context.Background()); len(cats) != 2 {
+		t.Fatalf("Expected nothing deleted before the confirmation, got %d cats left", len(cats))
+	}
+
+	_, confirmation := clearAll("")
+	rec, _ := clearAll("?confirm=" + confirmation.Token)
+	if rec.Code != http.StatusOK || rec.Body.String() != `{"deleted":2}`+"\n" {
+		t.Errorf("Expected the 2 cats deleted, got %d with %s", rec.Code, rec.Body.String())
+	}
+
+	if rec, _ := clearAll("?confirm=" + confirmation.Token); rec.Code != http.StatusConflict {
+		t.Errorf("Expected a spent token to be refused, got %d", rec.Code)
+	}
+}
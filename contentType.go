@@ -0,0 +1,29 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"slices"
+)
+
+// Media types of the JSON request bodies
+const (
+	jsonContentType       = "application/json"
+	mergePatchContentType = "application/merge-patch+json"
+)
+
+// Content-Type of the JSON responses, JSON being UTF-8 only
+const jsonResponseType = jsonContentType + "; charset=utf-8"
+
+// Rejects with a 415 the bodies not declared with one of the given media types, a charset parameter is allowed
+func requireContentType(next http.HandlerFunc, mediaTypes ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil || !slices.Contains(mediaTypes, mediaType) {
+			Logger.Infof("Unsupported Content-Type '%s' for '%s %s'", r.Header.Get("Content-Type"), r.Method, r.URL.Path)
+			writeResponse(w, r, http.StatusUnsupportedMediaType, ErrorBody{Error: localize(r, "content_type", mediaTypes[0])})
+			return
+		}
+		next(w, r)
+	}
+}
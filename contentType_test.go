@@ -0,0 +1,110 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Request with a JSON body as the clients send it
+func newJSONRequest(method, target string, body io.Reader) *http.Request {
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", jsonContentType)
+	return req
+}
+
+// =============================================================================
+// CONTENT TYPE TESTS
+// =============================================================================
+
+// Test the mutating routes only take the JSON bodies
+func TestRequireContentType(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newApp()
+
+	tests := []struct {
+		method       string
+		target       string
+		contentType  string
+		expectedCode int
+	}{
+		{"POST", "/api/cats", "application/json", http.StatusCreated},
+		{"POST", "/api/cats", "application/json; charset=utf-8", http.StatusCreated},
+		{"POST", "/api/cats", "Application/JSON", http.StatusCreated},
+		{"POST", "/api/cats", "text/plain", http.StatusUnsupportedMediaType},
+		{"POST", "/api/cats", "", http.StatusUnsupportedMediaType},
+		{"POST", "/api/cats", "application/json; charset", http.StatusUnsupportedMediaType},
+		{"PUT", "/api/cats/id2", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"PUT", "/api/cats/id2", "application/json", http.StatusCreated},
+		{"PATCH", "/api/cats/id1", "text/plain", http.StatusUnsupportedMediaType},
+		{"PATCH", "/api/cats/id1", "application/merge-patch+json", http.StatusOK},
+		{"POST", "/api/import", "text/csv", http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.target, strings.NewReader(`{"name": "Felix"}`))
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		if rec.Code != test.expectedCode {
+			t.Errorf("%s %s as %q: expected status code %d, got %d", test.method, test.target, test.contentType, test.expectedCode, rec.Code)
+		}
+		if rec.Code == http.StatusUnsupportedMediaType && !strings.Contains(rec.Body.String(), "Content-Type must be application/json") {
+			t.Errorf("%s %s as %q: expected a clear error, got %s", test.method, test.target, test.contentType, rec.Body.String())
+		}
+	}
+
+	// The bodyless requests are left alone
+	for _, method := range []string{"GET", "DELETE"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest(method, "/api/cats/id1", nil))
+		if rec.Code == http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected no Content-Type check", method)
+		}
+	}
+}
+
+// Test the JSON responses announce their charset and end with a newline unless disabled
+func TestJSONResponseFormat(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newApp()
+	targets := []string{"/api/cats/id1", "/api/cats/unknown", "/api/export", "/openapi.json"}
+
+	for _, newline := range []bool{true, false} {
+		cfg := currentConfig()
+		cfg.JSONNewline = newline
+		setConfig(cfg)
+
+		for _, target := range targets {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+
+			if contentType := rec.Header().Get("Content-Type"); contentType != "application/json; charset=utf-8" {
+				t.Errorf("%s: expected the UTF-8 JSON type, got %q", target, contentType)
+			}
+			if body := rec.Body.String(); strings.HasSuffix(body, "\n") != newline {
+				t.Errorf("%s with --json-newline=%v: got %q at the end", target, newline, body[max(0, len(body)-3):])
+			}
+		}
+	}
+}
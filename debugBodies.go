@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Value logged in place of a field of --debug-redact-fields
+const redactedValue = "[REDACTED]"
+
+// First bytes of a body, up to the limit, along with its whole size
+type bodyCapture struct {
+	limit int
+	data  []byte
+	size  int
+}
+
+func (capture *bodyCapture) keep(data []byte) {
+	capture.size += len(data)
+	if room := capture.limit - len(capture.data); room > 0 {
+		capture.data = append(capture.data, data[:min(room, len(data))]...)
+	}
+}
+
+func (capture *bodyCapture) truncated() bool {
+	return capture.size > len(capture.data)
+}
+
+// Body as logged: the text cut at the limit, or a mention of what cannot be shown. With fields to
+// redact only a whole JSON document is logged, a cut or another format could leak them
+func (capture *bodyCapture) describe(redacted map[string]bool) string {
+	text := capture.data
+	// The cut may fall in the middle of a character
+	for capture.truncated() && len(text) > 0 && len(capture.data)-len(text) < utf8.UTFMax && !utf8.Valid(text) {
+		text = text[:len(text)-1]
+	}
+	if !utf8.Valid(text) || bytes.IndexByte(text, 0) >= 0 {
+		return fmt.Sprintf("[%d bytes of binary]", capture.size)
+	}
+
+	if len(redacted) > 0 {
+		if capture.truncated() {
+			return fmt.Sprintf("[%d bytes withheld, too long to redact]", capture.size)
+		}
+		var document any
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&document); err != nil || decoder.More() {
+			return fmt.Sprintf("[%d bytes withheld, not a JSON document to redact]", capture.size)
+		}
+		masked, _ := json.Marshal(redactFields(document, redacted))
+		return string(masked)
+	}
+
+	if capture.truncated() {
+		return fmt.Sprintf("%s... [%d bytes]", text, capture.size)
+	}
+	return string(text)
+}
+
+// Masks the values of the given fields, at any depth, the names compared ignoring the case
+func redactFields(value any, redacted map[string]bool) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, field := range value {
+			if redacted[strings.ToLower(key)] {
+				value[key] = redactedValue
+			} else {
+				value[key] = redactFields(field, redacted)
+			}
+		}
+	case []any:
+		for idx, item := range value {
+			value[idx] = redactFields(item, redacted)
+		}
+	}
+	return value
+}
+
+// Lower-cased fields of --debug-redact-fields
+func parseRedactedFields(setting string) map[string]bool {
+	redacted := map[string]bool{}
+	for _, field := range strings.Split(setting, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			redacted[strings.ToLower(field)] = true
+		}
+	}
+	return redacted
+}
+
+// Request body copying what the handler reads, nothing is read ahead of it
+type capturingBody struct {
+	io.ReadCloser
+	capture *bodyCapture
+}
+
+func (body capturingBody) Read(data []byte) (int, error) {
+	read, err := body.ReadCloser.Read(data)
+	body.capture.keep(data[:read])
+	return read, err
+}
+
+// Response writer copying what the client is sent
+type capturingWriter struct {
+	http.ResponseWriter
+	capture *bodyCapture
+}
+
+func (w *capturingWriter) Write(data []byte) (int, error) {
+	written, err := w.ResponseWriter.Write(data)
+	w.capture.keep(data[:written])
+	return written, err
+}
+
+// Keeps the streamed responses flowing
+func (w *capturingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Logs the bodies at the debug level with --debug-bodies, once the request is served. They are
+// copied as the handler reads and writes them, up to --debug-body-max bytes each
+func logBodies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := currentConfig()
+		if !cfg.DebugBodies || !Logger.enabled(logLevelDebug) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestBody := &bodyCapture{limit: cfg.DebugBodyMax}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = capturingBody{ReadCloser: r.Body, capture: requestBody}
+		}
+		responseBody := &bodyCapture{limit: cfg.DebugBodyMax}
+		next.ServeHTTP(&capturingWriter{ResponseWriter: w, capture: responseBody}, r)
+
+		redacted := parseRedactedFields(cfg.DebugRedact)
+		entry := Logger.WithFields(Fields{"method": r.Method, "path": r.URL.Path})
+		if requestBody.size > 0 {
+			entry.Debug("Request body: ", requestBody.describe(redacted))
+		}
+		if responseBody.size > 0 {
+			entry.Debug("Response body: ", responseBody.describe(redacted))
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// DEBUG BODIES TESTS
+// =============================================================================
+
+// Test a body is cut at the limit, and only logged whole and as JSON when fields are redacted
+func TestBodyCaptureDescribe(t *testing.T) {
+	describe := func(body string, limit int, redact string) string {
+		capture := &bodyCapture{limit: limit}
+		capture.keep([]byte(body[:len(body)/2]))
+		capture.keep([]byte(body[len(body)/2:]))
+		return capture.describe(parseRedactedFields(redact))
+	}
+
+	tests := []struct {
+		body     string
+		limit    int
+		redact   string
+		expected string
+	}{
+		{`{"name": "Toto"}`, 100, "", `{"name": "Toto"}`},
+		{`{"name": "Toto"}`, 8, "", `{"name":... [16 bytes]`},
+		{`{"name": "Toto", "owner": {"Name": "Bob"}, "tags": [{"name": "x"}]}`, 100, "name", `{"name":"[REDACTED]","owner":{"Name":"[REDACTED]"},"tags":[{"name":"[REDACTED]"}]}`},
+		{`{"name": "Toto"}`, 8, "name", "[16 bytes withheld, too long to redact]"},
+		{"name=Toto", 100, "name", "[9 bytes withheld, not a JSON document to redact]"},
+		{"{}\n{}\n", 100, "name", "[6 bytes withheld, not a JSON document to redact]"},
+		{"\x89PNG\x00\x01", 100, "", "[6 bytes of binary]"},
+		{"chaton é", 8, "", "chaton ... [9 bytes]"},
+	}
+	for _, test := range tests {
+		if described := describe(test.body, test.limit, test.redact); described != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.body, test.expected, described)
+		}
+	}
+}
+
+// Test the handler still reads the whole request body and the client gets the whole response
+func TestLogBodies(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.DebugBodies, cfg.DebugBodyMax = true, 4
+	setConfig(cfg)
+
+	var received string
+	handler := logBodies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Write([]byte("answered in full"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/api/cats", strings.NewReader("sent in full")))
+	if received != "sent in full" || rec.Body.String() != "answered in full" {
+		t.Errorf("Expected the bodies untouched, got %q and %q", received, rec.Body.String())
+	}
+
+	if !strings.Contains(strings.Join(logRing.Lines(), "\n"), "Response body: answ... [16 bytes]") {
+		t.Error("Expected the response body logged cut at the limit")
+	}
+}
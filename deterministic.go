@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Seed of the UUIDs given with --deterministic, the same sequence on every run
+var deterministicSeed = [32]byte{}
+
+// UUIDs drawn from a seeded source rather than the system randomness
+type SeededGenerator struct {
+	lock   sync.Mutex
+	source *rand.ChaCha8
+}
+
+func newSeededGenerator(seed [32]byte) *SeededGenerator {
+	return &SeededGenerator{source: rand.NewChaCha8(seed)}
+}
+
+func (gen *SeededGenerator) NewID() string {
+	gen.lock.Lock()
+	defer gen.lock.Unlock()
+	return uuid.Must(uuid.NewRandomFromReader(gen.source)).String()
+}
+
+// Lists the cats sorted by ID rather than in the order of the backend
+type sortedStore struct {
+	Store
+}
+
+func (store sortedStore) List(ctx context.Context) ([]Cat, error) {
+	cats, err := store.Store.List(ctx)
+	slices.SortFunc(cats, func(a, b Cat) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return cats, err
+}
+
+// Walks the sorted list, the iteration order of the backend being as random as its list
+func (store sortedStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	cats, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, cat := range cats {
+		if !yield(cat) {
+			break
+		}
+	}
+	return nil
+}
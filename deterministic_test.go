@@ -0,0 +1,59 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// =============================================================================
+// DETERMINISTIC MODE TESTS
+// =============================================================================
+
+// Test the seeded UUIDs are valid and repeat from run to run
+func TestSeededGenerator(t *testing.T) {
+	first, second := newSeededGenerator(deterministicSeed), newSeededGenerator(deterministicSeed)
+
+	seen := map[string]bool{}
+	for range 100 {
+		catID := first.NewID()
+		if parsed, err := uuid.Parse(catID); err != nil || parsed.Version() != 4 {
+			t.Fatalf("Expected a version 4 UUID, got %q (%v)", catID, err)
+		}
+		if other := second.NewID(); other != catID {
+			t.Fatalf("Expected the same sequence from the same seed, got %s and %s", catID, other)
+		}
+		seen[catID] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("Expected distinct IDs, got %d", len(seen))
+	}
+
+	if other := newSeededGenerator([32]byte{1}).NewID(); other == newSeededGenerator(deterministicSeed).NewID() {
+		t.Error("Expected another seed to give other IDs")
+	}
+}
+
+// Test the sorted store lists the cats by ID
+func TestSortedStore(t *testing.T) {
+	store := sortedStore{NewMemoryRepo(
+		Cat{ID: "c", Name: "Garfield"},
+		Cat{ID: "a", Name: "Toto"},
+		Cat{ID: "b", Name: "Felix"},
+	)}
+
+	for range 5 {
+		cats, err := store.List(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to list the cats: %v", err)
+		}
+		ids := []string{}
+		for _, cat := range cats {
+			ids = append(ids, cat.ID)
+		}
+		if !slices.Equal(ids, []string{"a", "b", "c"}) {
+			t.Fatalf("Expected the cats sorted by ID, got %v", ids)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Strong validator of a stored cat, any change of its fields changes it
+func catETag(cat Cat) string {
+	return etagOf(cat)
+}
+
+// Entity tag of any value, hashed from its canonical JSON so a map gets the same tag as the struct
+// holding the same fields, whatever the order they were set in
+func etagOf(value any) string {
+	encoded, _ := canonicalJSON(value)
+	sum := sha256.Sum256(encoded)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// Encodes the value for hashing, not for the responses: the object keys sorted at any depth, no
+// whitespace and the numbers as they were written, whatever the field naming or the indentation
+func canonicalJSON(value any) ([]byte, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	var canonical bytes.Buffer
+	err = writeCanonical(&canonical, document)
+	return canonical.Bytes(), err
+}
+
+func writeCanonical(buffer *bytes.Buffer, value any) error {
+	switch value := value.(type) {
+	case map[string]any:
+		buffer.WriteByte('{')
+		for idx, key := range slices.Sorted(maps.Keys(value)) {
+			if idx > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeCanonical(buffer, key); err != nil {
+				return err
+			}
+			buffer.WriteByte(':')
+			if err := writeCanonical(buffer, value[key]); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case []any:
+		buffer.WriteByte('[')
+		for idx, item := range value {
+			if idx > 0 {
+				buffer.WriteByte(',')
+			}
+			if err := writeCanonical(buffer, item); err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	case json.Number:
+		buffer.WriteString(value.String())
+	default:
+		// Strings, booleans and null, the HTML characters left as they are
+		encoder := json.NewEncoder(buffer)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		// Encode ends the value with a newline
+		buffer.Truncate(buffer.Len() - 1)
+	}
+	return nil
+}
+
+// Whether an If-Match or If-None-Match list holds the entity tag, or the * wildcard.
+// The weak tags only match for If-None-Match, If-Match compares strongly
+func etagListMatches(list string, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the If-Match and If-None-Match headers of a write against the current cat, nil when missing.
+// The answer is a 412 when a precondition fails
+func failedPrecondition(req *http.Request, current *Cat) (int, any, bool) {
+	if ifMatch := req.Header.Get("If-Match"); ifMatch != "" {
+		if current == nil || !etagListMatches(ifMatch, catETag(*current), false) {
+			Logger.Info("If-Match precondition failed")
+			return http.StatusPreconditionFailed, localize(req, "cat_changed"), true
+		}
+	}
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if current != nil && etagListMatches(ifNoneMatch, catETag(*current), true) {
+			Logger.Info("If-None-Match precondition failed")
+			return http.StatusPreconditionFailed, localize(req, "cat_exists"), true
+		}
+	}
+	return 0, nil, false
+}
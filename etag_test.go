@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// ETAG AND PRECONDITIONS TESTS
+// =============================================================================
+
+// Test the entity tag lists of the conditional headers
+func TestETagListMatches(t *testing.T) {
+	tests := []struct {
+		list     string
+		weak     bool
+		expected bool
+	}{
+		{`"abc"`, false, true},
+		{`"xyz", "abc"`, false, true},
+		{`*`, false, true},
+		{`"xyz"`, false, false},
+		{`W/"abc"`, false, false},
+		{`W/"abc"`, true, true},
+		{`abc`, true, false},
+	}
+
+	for _, test := range tests {
+		if matches := etagListMatches(test.list, `"abc"`, test.weak); matches != test.expected {
+			t.Errorf("%s (weak %v): expected %v, got %v", test.list, test.weak, test.expected, matches)
+		}
+	}
+}
+
+// Test the canonical encoding sorts the keys at any depth, without whitespace
+func TestCanonicalJSON(t *testing.T) {
+	value := map[string]any{
+		"weightGrams": 4200,
+		"name":        "Toto <3",
+		"owner":       map[string]any{"since": json.Number("2020"), "city": "Lyon", "tags": []any{"b", "a", nil, true}},
+	}
+	encoded, err := canonicalJSON(value)
+	expected := `{"name":"Toto <3","owner":{"city":"Lyon","since":2020,"tags":["b","a",null,true]},"weightGrams":4200}`
+	if err != nil || string(encoded) != expected {
+		t.Errorf("Expected %s, got %s (%v)", expected, encoded, err)
+	}
+}
+
+// Test the same cat always hashes identically, as a struct or as a map of all its fields
+func TestCatETagStable(t *testing.T) {
+	cat := Cat{
+		ID:          "id1",
+		Name:        "Toto",
+		BirthDate:   "2020-01-01",
+		Color:       "Black",
+		WeightGrams: 4200,
+		UpdatedAt:   time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+	}
+	etag := catETag(cat)
+	for range 20 {
+		if again := catETag(cat); again != etag {
+			t.Fatalf("Expected the same tag %s, got %s", etag, again)
+		}
+	}
+
+	// The map iteration order changes from one run to the other
+	for range 20 {
+		if projected := etagOf(projectCat(cat, catFieldNames)); projected != etag {
+			t.Fatalf("Expected the projection of all the fields tagged %s, got %s", etag, projected)
+		}
+	}
+
+	cat.Color = "Grey"
+	if changed := catETag(cat); changed == etag {
+		t.Error("Expected a changed cat to get another tag")
+	}
+}
+
+// Test a cat read with its ETag is answered 304 while unchanged
+func TestGetCatETag(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected a tagged cat, got %d with %q", rec.Code, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/api/cats/id1", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotModified, rec.Code)
+	}
+
+	catsStore.Save(req.Context(), Cat{ID: "id1", Name: "Felix"})
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected the changed cat with a new tag, got %d", rec.Code)
+	}
+
+	// A projection is another representation, left untagged
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1?fields=name", nil))
+	if rec.Header().Get("ETag") != "" {
+		t.Error("Expected no tag for a projection")
+	}
+}
+
+// Test the upsert creates or replaces the cat under the path ID
+func TestPutCat(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+	app := newApp()
+
+	put := func(body string, header ...string) *httptest.ResponseRecorder {
+		req := newJSONRequest("PUT", "/api/cats/my-cat", strings.NewReader(body))
+		for idx := 0; idx+1 < len(header); idx += 2 {
+			req.Header.Set(header[idx], header[idx+1])
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := put(`{"name": "Toto", "color": "Grey"}`)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Location") != "/api/cats/my-cat" {
+		t.Fatalf("Expected a created cat, got %d: %s", rec.Code, rec.Body.String())
+	}
+	created := storedCats(catsStore)["my-cat"]
+
+	time.Sleep(time.Millisecond)
+	rec = put(`{"name": "Felix"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var replaced Cat
+	json.NewDecoder(rec.Body).Decode(&replaced)
+	if replaced.Name != "Felix" || replaced.Color != "" {
+		t.Errorf("Expected the whole cat replaced, got %+v", replaced)
+	}
+	if !replaced.CreatedAt.Equal(created.CreatedAt) || !replaced.UpdatedAt.After(created.UpdatedAt) {
+		t.Errorf("Expected the creation time kept and the update time moved, got %+v", replaced)
+	}
+	if rec.Header().Get("ETag") != catETag(storedCats(catsStore)["my-cat"]) {
+		t.Error("Expected the tag of the stored cat")
+	}
+
+	if rec := put(`{"id": "other", "name": "Felix"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected an id mismatch refused, got %d", rec.Code)
+	}
+	if rec := put(`{"color": "Grey"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected an invalid cat refused, got %d", rec.Code)
+	}
+	if len(storedCats(catsStore)) != 1 {
+		t.Errorf("Expected a single cat, got %d", len(storedCats(catsStore)))
+	}
+}
+
+// Test the If-None-Match and If-Match preconditions of the writes
+func TestPutCatPreconditions(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+	app := newApp()
+
+	write := func(method string, header string, value string, body string) *httptest.ResponseRecorder {
+		req := newJSONRequest(method, "/api/cats/my-cat", strings.NewReader(body))
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := write("PUT", "If-Match", "*", `{"name": "Toto"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected If-Match to need an existing cat, got %d", rec.Code)
+	}
+
+	rec := write("PUT", "If-None-Match", "*", `{"name": "Toto"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected the cat created, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+
+	if rec := write("PUT", "If-None-Match", "*", `{"name": "Felix"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected the existing cat kept, got %d", rec.Code)
+	}
+	if storedCats(catsStore)["my-cat"].Name != "Toto" {
+		t.Errorf("Expected Toto untouched, got %+v", storedCats(catsStore)["my-cat"])
+	}
+
+	// A lost update is refused
+	rec = write("PATCH", "If-Match", etag, `{"color": "Grey"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the patch with the current tag, got %d", rec.Code)
+	}
+	if rec := write("PUT", "If-Match", etag, `{"name": "Felix"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected a stale tag refused, got %d", rec.Code)
+	}
+	if rec := write("PATCH", "If-Match", etag, `{"color": "Black"}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected a stale tag refused, got %d", rec.Code)
+	}
+
+	// The tag answered by the patch
+	rec = write("PUT", "If-Match", `"stale", `+rec.Header().Get("ETag"), `{"name": "Felix"}`)
+	if rec.Code != http.StatusOK || storedCats(catsStore)["my-cat"].Name != "Felix" {
+		t.Errorf("Expected the replacement with the current tag, got %d", rec.Code)
+	}
+}
+
+// Store slow to answer its reads, so the concurrent requests all read before any of them writes
+type slowReadStore struct {
+	Store
+}
+
+func (store slowReadStore) Get(ctx context.Context, catID string) (Cat, error) {
+	cat, err := store.Store.Get(ctx, catID)
+	time.Sleep(20 * time.Millisecond)
+	return cat, err
+}
+
+func (store slowReadStore) List(ctx context.Context) ([]Cat, error) {
+	cats, err := store.Store.List(ctx)
+	time.Sleep(20 * time.Millisecond)
+	return cats, err
+}
+
+// Test concurrent conditional writes of the same version let a single one through, the others 412
+func TestConcurrentPreconditions(t *testing.T) {
+	const writers = 20
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	app := newAppWithStore(slowReadStore{store})
+	current, _ := store.Get(t.Context(), "id1")
+	etag := catETag(current)
+
+	run := func(newRequest func(idx int) *http.Request) map[int]int {
+		var lock sync.Mutex
+		var group sync.WaitGroup
+		statuses := map[int]int{}
+		for idx := range writers {
+			group.Go(func() {
+				rec := httptest.NewRecorder()
+				app.ServeHTTP(rec, newRequest(idx))
+				lock.Lock()
+				statuses[rec.Code]++
+				lock.Unlock()
+			})
+		}
+		group.Wait()
+		return statuses
+	}
+
+	statuses := run(func(idx int) *http.Request {
+		req := newJSONRequest("PUT", "/api/cats/id2", strings.NewReader(fmt.Sprintf(`{"name": "Felix %d"}`, idx)))
+		req.Header.Set("If-None-Match", "*")
+		return req
+	})
+	if statuses[http.StatusCreated] != 1 || statuses[http.StatusPreconditionFailed] != writers-1 {
+		t.Errorf("Expected a single creation and %d 412, got %v", writers-1, statuses)
+	}
+
+	statuses = run(func(idx int) *http.Request {
+		req := newJSONRequest("PATCH", "/api/cats/id1", strings.NewReader(fmt.Sprintf(`{"color": "Grey %d"}`, idx)))
+		req.Header.Set("If-Match", etag)
+		return req
+	})
+	if statuses[http.StatusOK] != 1 || statuses[http.StatusPreconditionFailed] != writers-1 {
+		t.Errorf("Expected a single patch and %d 412, got %v", writers-1, statuses)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	eventCreated = "created"
+	eventDeleted = "deleted"
+)
+
+const (
+	// Events held for a slow stream client, the next ones are skipped for it while full
+	eventClientBuffer = 16
+	sseContentType    = "text/event-stream"
+)
+
+// Comment sent on an idle stream so the proxies keep it open
+var sseKeepAlive = 15 * time.Second
+
+// Lifecycle event of a cat, the deleted ones have no cat
+type CatEvent struct {
+	// Increasing by one from an event to the next, a gap tells the client it missed some
+	Sequence uint64    `json:"sequence"`
+	Event    string    `json:"event"`
+	ID       string    `json:"id"`
+	Cat      *Cat      `json:"cat,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// In-process pub/sub of the cat events, each subscriber with its own buffered channel
+type EventBroker struct {
+	lock    sync.Mutex
+	clients map[chan CatEvent]bool
+}
+
+func NewEventBroker() *EventBroker {
+	return &EventBroker{clients: map[chan CatEvent]bool{}}
+}
+
+func (broker *EventBroker) Subscribe() chan CatEvent {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	events := make(chan CatEvent, eventClientBuffer)
+	broker.clients[events] = true
+	return events
+}
+
+func (broker *EventBroker) Unsubscribe(events chan CatEvent) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+	delete(broker.clients, events)
+}
+
+// Hands the event to every subscriber without waiting on any
+func (broker *EventBroker) Publish(event CatEvent) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	for events := range broker.clients {
+		select {
+		case events <- event:
+		default:
+			Logger.Debugf("Stream client too slow, event '%s' of the cat '%s' skipped", event.Event, event.ID)
+		}
+	}
+}
+
+// Kept in memory, a client only sees the changes made through this instance
+var catEvents = NewEventBroker()
+
+// Held from a mutation to the publication of its events with --ordered-events
+var eventOrder sync.Mutex
+
+// Serializes the mutations along with their events under --ordered-events, so concurrent writers
+// publish in the order they committed. Returns the unlock, to defer once the events are published
+func lockEventOrder() func() {
+	if !currentConfig().OrderedEvents {
+		return func() {}
+	}
+	eventOrder.Lock()
+	return eventOrder.Unlock
+}
+
+// Last sequence number given, numbered and published under the same lock so the subscribers
+// receive the events in the order of their numbers
+var eventSequence struct {
+	lock sync.Mutex
+	last uint64
+}
+
+// Tells the live streams and the webhook about a created or deleted cat
+func publishCatEvent(event string, catID string, cat *Cat) {
+	eventSequence.lock.Lock()
+	defer eventSequence.lock.Unlock()
+
+	eventSequence.last++
+	catEvent := CatEvent{Sequence: eventSequence.last, Event: event, ID: catID, Cat: cat, Time: time.Now().UTC()}
+	catEvents.Publish(catEvent)
+	notifyWebhook(catEvent)
+}
+
+// Streams the cat events as Server-Sent Events until the client leaves
+func streamCatEvents(res http.ResponseWriter, req *http.Request) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		writeResponse(res, req, http.StatusInternalServerError, ErrorBody{Error: localize(req, "streaming_unsupported")})
+		return
+	}
+
+	events := catEvents.Subscribe()
+	defer catEvents.Unsubscribe(events)
+	Logger.Info("Event stream opened")
+
+	res.Header().Set("content-type", sseContentType)
+	res.Header().Set("cache-control", "no-cache")
+	res.WriteHeader(http.StatusOK)
+	fmt.Fprint(res, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			Logger.Info("Event stream closed")
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(res, ": keep-alive\n\n")
+		case event := <-events:
+			data, err := json.Marshal(outputNaming(event))
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Event, data)
+		}
+		flusher.Flush()
+	}
+}
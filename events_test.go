@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Store recording the order its writes committed in, slow to answer so the writers race
+type commitOrderStore struct {
+	Store
+	lock      *sync.Mutex
+	committed *[]string
+}
+
+func (store commitOrderStore) Save(ctx context.Context, cat Cat) error {
+	store.lock.Lock()
+	err := store.Store.Save(ctx, cat)
+	*store.committed = append(*store.committed, cat.ID)
+	store.lock.Unlock()
+
+	time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+	return err
+}
+
+func (store commitOrderStore) Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error {
+	store.lock.Lock()
+	var saved []Cat
+	err := store.Store.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+		change, err := decide(cats)
+		saved = change.Save
+		return change, err
+	})
+	if err == nil {
+		for _, cat := range saved {
+			*store.committed = append(*store.committed, cat.ID)
+		}
+	}
+	store.lock.Unlock()
+
+	time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+	return err
+}
+
+// Reads the stream up to the next event, skipping the comments
+func nextSSEEvent(t *testing.T, reader *bufio.Reader) (string, string) {
+	t.Helper()
+	var event, data string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read the stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// =============================================================================
+// CAT EVENTS TESTS
+// =============================================================================
+
+// Test each subscriber gets the events, a full one being skipped rather than waited for
+func TestEventBroker(t *testing.T) {
+	broker := NewEventBroker()
+	fast, slow := broker.Subscribe(), broker.Subscribe()
+
+	for range eventClientBuffer {
+		broker.Publish(CatEvent{Event: eventCreated, ID: "id1"})
+	}
+	<-fast
+
+	done := make(chan struct{})
+	go func() {
+		broker.Publish(CatEvent{Event: eventDeleted, ID: "id1"})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("A full subscriber blocked the publication")
+	}
+	if len(fast) != eventClientBuffer || len(slow) != eventClientBuffer {
+		t.Errorf("Expected both buffers full, got %d and %d", len(fast), len(slow))
+	}
+
+	broker.Unsubscribe(fast)
+	broker.Unsubscribe(slow)
+	if len(broker.clients) != 0 {
+		t.Errorf("Expected no subscriber left, got %d", len(broker.clients))
+	}
+}
+
+// Test the stream sends the creations and deletions, and unsubscribes once the client leaves
+func TestStreamCatEvents(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	server := httptest.NewServer(newApp())
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "/api/cats/events")
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	if contentType := res.Header.Get("Content-Type"); contentType != sseContentType {
+		t.Errorf("Expected Content-Type %q, got %q", sseContentType, contentType)
+	}
+	reader := bufio.NewReader(res.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("Expected the stream to open with a comment, got %q", line)
+	}
+
+	req, _ := http.NewRequest("DELETE", server.URL+"/api/cats/id1", nil)
+	if _, err := http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if _, err := http.Post(server.URL+"/api/cats", jsonContentType, strings.NewReader(`{"name":"Felix"}`)); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+
+	if event, data := nextSSEEvent(t, reader); event != eventDeleted || !strings.Contains(data, `"id":"id1"`) {
+		t.Errorf("Expected the deletion of id1, got %s %s", event, data)
+	}
+	if event, data := nextSSEEvent(t, reader); event != eventCreated || !strings.Contains(data, `"name":"Felix"`) {
+		t.Errorf("Expected the creation of Felix, got %s %s", event, data)
+	}
+
+	res.Body.Close()
+	deadline := time.Now().Add(time.Second)
+	for {
+		catEvents.lock.Lock()
+		subscribers := len(catEvents.clients)
+		catEvents.lock.Unlock()
+		if subscribers == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The stream was not unsubscribed after the client left")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Test concurrent writers publish numbered events in the order their mutations committed
+func TestOrderedEvents(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.OrderedEvents = true
+	setConfig(cfg)
+
+	var lock sync.Mutex
+	var committed []string
+	app := newAppWithStore(commitOrderStore{Store: NewMemoryRepo(), lock: &lock, committed: &committed})
+
+	// Fewer than the buffer of a subscriber, none is skipped however slow the reading
+	const writers = eventClientBuffer - 4
+	events := catEvents.Subscribe()
+	defer catEvents.Unsubscribe(events)
+	received := make(chan []CatEvent)
+	go func() {
+		var created []CatEvent
+		for event := range events {
+			// Other tests may publish meanwhile
+			if event.Event == eventCreated && strings.HasPrefix(event.Cat.Name, "Writer") {
+				if created = append(created, event); len(created) == writers {
+					break
+				}
+			}
+		}
+		received <- created
+	}()
+
+	var writing sync.WaitGroup
+	for idx := range writers {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Writer `+strconv.Itoa(idx)+`"}`)))
+			if rec.Code != http.StatusCreated {
+				t.Errorf("Expected the cat created, got %d", rec.Code)
+			}
+		}()
+	}
+	writing.Wait()
+
+	var created []CatEvent
+	select {
+	case created = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an event for each writer")
+	}
+	for idx, event := range created {
+		if idx > 0 && event.Sequence <= created[idx-1].Sequence {
+			t.Errorf("Expected increasing sequence numbers, got %d after %d", event.Sequence, created[idx-1].Sequence)
+		}
+		if event.ID != committed[idx] {
+			t.Fatalf("Expected the event %d about the cat committed %d, %s, got %s", idx, idx, committed[idx], event.ID)
+		}
+	}
+}
+
+// Test a cat created by PUT or by an import, and one dropped by a replacing import, are published
+func TestWriteEvents(t *testing.T) {
+	events := catEvents.Subscribe()
+	defer catEvents.Unsubscribe(events)
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "events-kept", Name: "Toto"}, Cat{ID: "events-dropped", Name: "Felix"}))
+
+	for _, req := range []*http.Request{
+		newJSONRequest("PUT", "/api/cats/events-put", strings.NewReader(`{"name": "Tom"}`)),
+		newJSONRequest("POST", "/api/import?mode=replace", strings.NewReader(`[
+			{"id": "events-kept", "name": "Toto"}, {"id": "events-put", "name": "Tom"}, {"id": "events-imported", "name": "Garfield"}
+		]`)),
+	} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: unexpected status code %d", req.Method, req.URL.Path, rec.Code)
+		}
+	}
+
+	// Other tests may publish meanwhile, only the events of these cats are kept
+	expected := []string{"created events-put", "deleted events-dropped", "created events-imported"}
+	var received []string
+	timeout := time.After(time.Second)
+	for len(received) < len(expected) {
+		select {
+		case event := <-events:
+			if strings.HasPrefix(event.ID, "events-") {
+				received = append(received, event.Event+" "+event.ID)
+			}
+		case <-timeout:
+			t.Fatalf("Expected %v, got %v", expected, received)
+		}
+	}
+	if !slices.Equal(received, expected) {
+		t.Errorf("Expected %v, got %v", expected, received)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Conventions of the JSON field names
+const (
+	namingCamel = "camel" // birthDate, as declared by the struct tags
+	namingSnake = "snake" // birth_date
+)
+
+// Only the multi-word field names are renamed, data used as keys like `Grey` is left alone
+var camelCaseKey = regexp.MustCompile(`^[a-z][a-z0-9]*([A-Z][a-z0-9]*)+$`)
+
+func camelToSnake(key string) string {
+	if !camelCaseKey.MatchString(key) {
+		return key
+	}
+	var builder strings.Builder
+	for _, char := range key {
+		if unicode.IsUpper(char) {
+			builder.WriteByte('_')
+			char = unicode.ToLower(char)
+		}
+		builder.WriteRune(char)
+	}
+	return builder.String()
+}
+
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	for idx := 1; idx < len(parts); idx++ {
+		if parts[idx] != "" {
+			parts[idx] = strings.ToUpper(parts[idx][:1]) + parts[idx][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// Renames the object keys of a decoded JSON document, at any depth
+func renameKeys(value any, rename func(string) string) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		renamed := make(map[string]any, len(typed))
+		for key, item := range typed {
+			renamed[rename(key)] = renameKeys(item, rename)
+		}
+		return renamed
+	case []any:
+		for idx, item := range typed {
+			typed[idx] = renameKeys(item, rename)
+		}
+		return typed
+	}
+	return value
+}
+
+// Decodes keeping the numbers as written
+func decodeGeneric(reader io.Reader) (any, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+	var value any
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// Decodes a request body written with the configured field names
+func decodeJSON(body io.Reader, target any) error {
+	if currentConfig().FieldNaming != namingSnake {
+		return decodeStrictly(body, target)
+	}
+
+	value, err := decodeGeneric(body)
+	if err != nil {
+		return err
+	}
+	converted, err := json.Marshal(renameKeys(value, snakeToCamel))
+	if err != nil {
+		return err
+	}
+	return decodeStrictly(bytes.NewReader(converted), target)
+}
+
+// Value to encode in a response so it has the configured field names
+func outputNaming(body any) any {
+	if currentConfig().FieldNaming != namingSnake {
+		return body
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return body
+	}
+	value, err := decodeGeneric(bytes.NewReader(encoded))
+	if err != nil {
+		return body
+	}
+	return renameKeys(value, camelToSnake)
+}
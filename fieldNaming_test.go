@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// FIELD NAMING TESTS
+// =============================================================================
+
+// Test the conversions between the two conventions
+func TestFieldNameConversions(t *testing.T) {
+	tests := []struct {
+		camel string
+		snake string
+	}{
+		{"name", "name"},
+		{"birthDate", "birth_date"},
+		{"createdAt", "created_at"},
+		{"duplicateOf", "duplicate_of"},
+	}
+
+	for _, test := range tests {
+		if snake := camelToSnake(test.camel); snake != test.snake {
+			t.Errorf("Expected %s for %s, got %s", test.snake, test.camel, snake)
+		}
+		if camel := snakeToCamel(test.snake); camel != test.camel {
+			t.Errorf("Expected %s for %s, got %s", test.camel, test.snake, camel)
+		}
+	}
+
+	// Keys which are data rather than field names
+	for _, key := range []string{"Grey", "ID", "x-total"} {
+		if renamed := camelToSnake(key); renamed != key {
+			t.Errorf("Expected %s unchanged, got %s", key, renamed)
+		}
+	}
+}
+
+// Test the snake_case policy applies to the requests and the responses
+func TestSnakeCaseFieldNaming(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.FieldNaming = namingSnake
+	setConfig(cfg)
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Toto", "birth_date": "2023-04-16"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `"birth_date":"2023-04-16"`) || !strings.Contains(body, `"created_at"`) || strings.Contains(body, "birthDate") {
+		t.Errorf("Expected snake_case fields, got %s", body)
+	}
+
+	location := rec.Header().Get("Location")
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("PATCH", location, strings.NewReader(`{"birth_date": null}`)))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "birth_date") {
+		t.Errorf("Expected the birth date cleared, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	if !strings.Contains(rec.Body.String(), `"updated_at"`) {
+		t.Errorf("Expected snake_case fields in the export, got %s", rec.Body.String())
+	}
+
+	// The default stays camelCase
+	cfg.FieldNaming = namingCamel
+	setConfig(cfg)
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix", "birthDate": "2020-01-01"}`)))
+	if !strings.Contains(rec.Body.String(), `"birthDate":"2020-01-01"`) {
+		t.Errorf("Expected camelCase fields, got %s", rec.Body.String())
+	}
+}
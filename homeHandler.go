@@ -1,25 +1,33 @@
 package main
 
-import "net/http"
+import (
+	"net/http"
+	"strconv"
+)
+
+// Number of stored cats for the landing page, read at each render
+func homeCatCount(req *http.Request) string {
+	cats, err := storeOf(req.Context()).List(req.Context())
+	if err != nil {
+		Logger.Warn("Unable to count the cats for the home page: ", err)
+		return "unavailable"
+	}
+	return strconv.Itoa(len(cats))
+}
 
 func getHomeHandler(res http.ResponseWriter, req *http.Request) {
+	catCount := homeCatCount(req)
 
 	res.WriteHeader(http.StatusOK)
 	res.Header().Add("Content-Type", "text/html")
 	res.Write([]byte(`
 		<html>
 		<title>Cats API</title>
-		<link rel="icon" href="data:image/svg+xml,<svg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 100 100'><text y='0.9em' font-size='80'>😺</text></svg>">
-		<style>
-		html, body {
-			width: 100%;
-		}
-		a {
-			text-decoration: none;
-		}
-		</style>
+		<link rel="icon" href="/favicon.ico">
+		<link rel="stylesheet" href="/static/home.css">
 		<body>
 			<h2>Software version: ` + version + `</h2>
+			<h3>🐈 Cats in the store: ` + catCount + `</h3>
 			<br/>
 			<a href="swagger/"><h3>🖥️ Swagger OpenAPI UI</h3></a>
 		<body>
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// HOME HANDLER TESTS
+// =============================================================================
+
+// Test the home page shows the number of cats stored at the time, along with the version and the Swagger link
+func TestHomeCatCount(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})
+	app := newAppWithStore(store)
+
+	home := func() string {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Body.String()
+	}
+
+	body := home()
+	for _, expected := range []string{"Cats API", "Software version: " + version, "Swagger OpenAPI UI", "Cats in the store: 2"} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected %q in the home page, got %s", expected, body)
+		}
+	}
+
+	store.Delete(context.Background(), "id1")
+	if body := home(); !strings.Contains(body, "Cats in the store: 1") {
+		t.Errorf("Expected the count read at render time, got %s", body)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// Strategies of the cat IDs
+const (
+	idStrategyUUID = "uuid"
+	idStrategySeq  = "seq"
+)
+
+// Source of the IDs given to the new cats
+type IDGenerator interface {
+	NewID() string
+}
+
+// Long opaque IDs, unique across instances
+type UUIDGenerator struct{}
+
+func (UUIDGenerator) NewID() string {
+	return uuid.New().String()
+}
+
+// Short incrementing IDs (cat-1, cat-2, ...), only unique within this process
+type SequentialGenerator struct {
+	last atomic.Int64
+}
+
+func (gen *SequentialGenerator) NewID() string {
+	return "cat-" + strconv.FormatInt(gen.last.Add(1), 10)
+}
+
+func newIDGenerator(strategy string) IDGenerator {
+	if strategy == idStrategySeq {
+		return &SequentialGenerator{}
+	}
+	return UUIDGenerator{}
+}
+
+var idGenerator IDGenerator = UUIDGenerator{}
+
+// ID of a new cat, the one sent by the client with --allow-client-ids when it is a valid UUID.
+// The second value tells the client ID is already taken by one of the cats, a generated ID
+// colliding with a cat (a sequence restarted over an imported cat-1) is drawn again instead
+func pickCatID(cats []Cat, clientID string) (string, bool) {
+	isTaken := func(catID string) bool {
+		return slices.ContainsFunc(cats, func(cat Cat) bool { return cat.ID == catID })
+	}
+	if currentConfig().AllowClientIDs && uuid.Validate(clientID) == nil {
+		return clientID, isTaken(clientID)
+	}
+	catID := idGenerator.NewID()
+	for isTaken(catID) {
+		catID = idGenerator.NewID()
+	}
+	return catID, false
+}
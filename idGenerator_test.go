@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// =============================================================================
+// ID GENERATOR TESTS
+// =============================================================================
+
+// Test the sequential IDs are short, incrementing and never repeated across goroutines
+func TestSequentialGenerator(t *testing.T) {
+	gen := newIDGenerator(idStrategySeq)
+	if first, second := gen.NewID(), gen.NewID(); first != "cat-1" || second != "cat-2" {
+		t.Errorf("Expected cat-1 then cat-2, got %s then %s", first, second)
+	}
+
+	const workers, perWorker = 8, 100
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	seen := map[string]bool{}
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				catID := gen.NewID()
+				lock.Lock()
+				seen[catID] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != workers*perWorker {
+		t.Errorf("Expected %d distinct IDs, got %d", workers*perWorker, len(seen))
+	}
+}
+
+// Test the UUID strategy stays the default
+func TestUUIDGenerator(t *testing.T) {
+	gen := newIDGenerator(defaultConfig().IDStrategy)
+	if catID := gen.NewID(); len(catID) != 36 || gen.NewID() == catID {
+		t.Errorf("Expected distinct UUIDs, got %s", catID)
+	}
+}
+
+// Test the handlers take their IDs from the configured generator
+func TestCreateCatUsesIDGenerator(t *testing.T) {
+	// Save original database state
+	originalStore, originalGenerator := catsStore, idGenerator
+	defer func() {
+		// Restore original state
+		catsStore, idGenerator = originalStore, originalGenerator
+	}()
+
+	catsStore = NewMemoryRepo()
+	idGenerator = newIDGenerator(idStrategySeq)
+
+	if catID := mustCreateCat(t, catsStore, `{"name": "Toto"}`); catID != "cat-1" {
+		t.Errorf("Expected cat-1, got %s", catID)
+	}
+
+	statusCode, _ := importCats(httptest.NewRequest("POST", "/api/import", strings.NewReader(`[{"name": "Felix"}]`)))
+	if statusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, statusCode)
+	}
+	if _, found := storedCats(catsStore)["cat-2"]; !found {
+		t.Errorf("Expected the imported cat as cat-2, got %v", storedCats(catsStore))
+	}
+}
+
+// Test a generated ID already taken by a stored cat is skipped rather than refused
+func TestGeneratedIDCollision(t *testing.T) {
+	originalGenerator := idGenerator
+	defer func() { idGenerator = originalGenerator }()
+	idGenerator = newIDGenerator(idStrategySeq)
+
+	store := NewMemoryRepo(Cat{ID: "cat-1", Name: "Toto"}, Cat{ID: "cat-2", Name: "Tata"})
+	if catID := mustCreateCat(t, store, `{"name": "Titi"}`); catID != "cat-3" {
+		t.Errorf("Expected cat-3 after the taken IDs, got %s", catID)
+	}
+
+	idGenerator = newIDGenerator(idStrategySeq)
+	statusCode, body := createCats(withStore(store, newJSONRequest("POST", "/api/cats/batch", strings.NewReader(`[{"name": "Felix"}]`))))
+	if statusCode != http.StatusCreated {
+		t.Fatalf("Expected the batch created, got %d with %v", statusCode, body)
+	}
+	if _, found := storedCats(store)["cat-4"]; !found {
+		t.Errorf("Expected the batch cat as cat-4 after a restarted sequence, got %v", storedCats(store))
+	}
+}
+
+// Test a client UUID is only kept with --allow-client-ids, and refused when already taken
+func TestAllowClientIDs(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	clientID := "6f1c2b9e-4d3a-4f5e-9a8b-7c6d5e4f3a2b"
+	app := newAppWithStore(NewMemoryRepo())
+	create := func(body string) (int, Cat) {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(body)))
+		var cat Cat
+		json.NewDecoder(rec.Body).Decode(&cat)
+		return rec.Code, cat
+	}
+
+	if code, cat := create(`{"id": "` + clientID + `", "name": "Toto"}`); code != http.StatusCreated || cat.ID == clientID {
+		t.Errorf("Expected the client ID ignored by default, got %d with %s", code, cat.ID)
+	}
+
+	cfg := currentConfig()
+	cfg.AllowClientIDs = true
+	setConfig(cfg)
+
+	if code, cat := create(`{"id": "` + clientID + `", "name": "Toto"}`); code != http.StatusCreated || cat.ID != clientID {
+		t.Errorf("Expected the client ID kept, got %d with %s", code, cat.ID)
+	}
+	if code, _ := create(`{"id": "` + clientID + `", "name": "Tata"}`); code != http.StatusConflict {
+		t.Errorf("Expected a 409 for a taken ID, got %d", code)
+	}
+	if code, cat := create(`{"id": "not-a-uuid", "name": "Titi"}`); code != http.StatusCreated || cat.ID == "not-a-uuid" {
+		t.Errorf("Expected an invalid client ID replaced, got %d with %s", code, cat.ID)
+	}
+}
+
+// Test concurrent creations with the same client UUID store a single cat, the others get a 409
+func TestConcurrentClientIDs(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.AllowClientIDs = true
+	setConfig(cfg)
+
+	const writers = 20
+	clientID := "6f1c2b9e-4d3a-4f5e-9a8b-7c6d5e4f3a2b"
+	store := NewMemoryRepo()
+	app := newAppWithStore(slowReadStore{store})
+
+	var lock sync.Mutex
+	var group sync.WaitGroup
+	statuses := map[int]int{}
+	for idx := range writers {
+		group.Go(func() {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(fmt.Sprintf(`{"id": "%s", "name": "Toto %d"}`, clientID, idx))))
+			lock.Lock()
+			statuses[rec.Code]++
+			lock.Unlock()
+		})
+	}
+	group.Wait()
+
+	if statuses[http.StatusCreated] != 1 || statuses[http.StatusConflict] != writers-1 {
+		t.Errorf("Expected a single creation and %d 409, got %v", writers-1, statuses)
+	}
+	if cats, _ := store.List(t.Context()); len(cats) != 1 {
+		t.Errorf("Expected a single cat stored, got %v", cats)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// States of an Idempotency-Key when a request comes with it
+const (
+	keyNew        = iota // First use, the request goes on
+	keyInProgress        // Another request with the key is running
+	keyDone              // Answered before, the answer is replayed
+)
+
+type idempotentAnswer struct {
+	code    int
+	body    any
+	done    bool
+	expires time.Time
+}
+
+// Answers of the requests made with an Idempotency-Key, forgotten after their TTL
+type IdempotencyCache struct {
+	lock    sync.Mutex
+	answers map[string]idempotentAnswer
+	now     func() time.Time
+}
+
+func NewIdempotencyCache() *IdempotencyCache {
+	return &IdempotencyCache{answers: map[string]idempotentAnswer{}, now: time.Now}
+}
+
+// Claims the key for a new request, or tells why it cannot be
+func (cache *IdempotencyCache) Reserve(key string, ttl time.Duration) (int, idempotentAnswer) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	now := cache.now()
+	for cachedKey, answer := range cache.answers {
+		if now.After(answer.expires) {
+			delete(cache.answers, cachedKey)
+		}
+	}
+
+	if answer, found := cache.answers[key]; found {
+		if answer.done {
+			return keyDone, answer
+		}
+		return keyInProgress, answer
+	}
+	cache.answers[key] = idempotentAnswer{expires: now.Add(ttl)}
+	return keyNew, idempotentAnswer{}
+}
+
+func (cache *IdempotencyCache) Complete(key string, code int, body any, ttl time.Duration) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.answers[key] = idempotentAnswer{code: code, body: body, done: true, expires: cache.now().Add(ttl)}
+}
+
+// Frees the key of a failed request so it can be retried
+func (cache *IdempotencyCache) Release(key string) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	delete(cache.answers, key)
+}
+
+var idempotencyKeys = NewIdempotencyCache()
+
+// Replays the answer of a creation retried with the same Idempotency-Key rather than creating twice,
+// only the successful creations are kept
+func idempotentCreate(create ServiceFunc) ServiceFunc {
+	return func(req *http.Request) (int, any) {
+		key := req.Header.Get("Idempotency-Key")
+		dryRun, _ := strconv.ParseBool(req.URL.Query().Get("dryRun"))
+		ttl := currentConfig().IdempotencyTTL
+		if key == "" || dryRun || ttl <= 0 {
+			return create(req)
+		}
+
+		state, answer := idempotencyKeys.Reserve(key, ttl)
+		switch state {
+		case keyDone:
+			Logger.Infof("Replaying the creation for the Idempotency-Key '%s'", key)
+			return answer.code, answer.body
+		case keyInProgress:
+			return http.StatusConflict, localize(req, "request_in_progress")
+		}
+
+		created := false
+		defer func() {
+			if !created {
+				idempotencyKeys.Release(key)
+			}
+		}()
+
+		code, body := create(req)
+		if code == http.StatusCreated {
+			idempotencyKeys.Complete(key, code, body, ttl)
+			created = true
+		}
+		return code, body
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// IDEMPOTENCY TESTS
+// =============================================================================
+
+// Test a retried creation returns the first cat instead of a duplicate
+func TestIdempotentCreate(t *testing.T) {
+	// Save original state
+	originalStore, originalKeys := catsStore, idempotencyKeys
+	defer func() {
+		// Restore original state
+		catsStore, idempotencyKeys = originalStore, originalKeys
+	}()
+
+	catsStore = NewMemoryRepo()
+	idempotencyKeys = NewIdempotencyCache()
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	idempotencyKeys.now = func() time.Time { return clock }
+	app := newApp()
+
+	post := func(key string, body string) *httptest.ResponseRecorder {
+		req := newJSONRequest("POST", "/api/cats", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := post("key-1", `{"name": "Toto"}`)
+	retry := post("key-1", `{"name": "Toto"}`)
+	if first.Code != http.StatusCreated || retry.Code != http.StatusCreated {
+		t.Fatalf("Expected two 201, got %d and %d", first.Code, retry.Code)
+	}
+	if first.Header().Get("Location") != retry.Header().Get("Location") || first.Body.String() != retry.Body.String() {
+		t.Errorf("Expected the same cat, got %s and %s", first.Body.String(), retry.Body.String())
+	}
+	if len(storedCats(catsStore)) != 1 {
+		t.Errorf("Expected a single stored cat, got %d", len(storedCats(catsStore)))
+	}
+
+	// Another key or no key creates
+	post("key-2", `{"name": "Toto"}`)
+	post("", `{"name": "Toto"}`)
+	if len(storedCats(catsStore)) != 3 {
+		t.Errorf("Expected 3 stored cats, got %d", len(storedCats(catsStore)))
+	}
+
+	// A failed creation does not hold the key
+	if rec := post("key-3", `{"color": "Grey"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+	if rec := post("key-3", `{"name": "Felix"}`); rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), "Felix") {
+		t.Errorf("Expected the retry to create Felix, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// The key is forgotten after its TTL
+	clock = clock.Add(currentConfig().IdempotencyTTL + time.Second)
+	post("key-1", `{"name": "Toto"}`)
+	if len(storedCats(catsStore)) != 5 {
+		t.Errorf("Expected an expired key to create again, got %d cats", len(storedCats(catsStore)))
+	}
+}
+
+// Test a key still being processed is not used twice
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	cache := NewIdempotencyCache()
+
+	if state, _ := cache.Reserve("key", time.Minute); state != keyNew {
+		t.Fatalf("Expected a new key, got %d", state)
+	}
+	if state, _ := cache.Reserve("key", time.Minute); state != keyInProgress {
+		t.Errorf("Expected the key in progress, got %d", state)
+	}
+
+	cache.Complete("key", http.StatusCreated, "done", time.Minute)
+	if state, answer := cache.Reserve("key", time.Minute); state != keyDone || answer.body != "done" {
+		t.Errorf("Expected the answer replayed, got %d (%v)", state, answer.body)
+	}
+}
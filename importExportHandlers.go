@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Ways to load a dataset into the store
+const (
+	importMerge   = "merge"   // Adds or overwrites the imported cats
+	importReplace = "replace" // The imported cats become the whole store
+)
+
+// Handling of the imported cats having the name and birth date of another one
+const (
+	onDuplicateInsert = "insert" // Imported anyway, only reported
+	onDuplicateSkip   = "skip"   // Left out of the import
+	onDuplicateError  = "error"  // Nothing is imported
+)
+
+// Number of exported cats between two flushes
+const exportFlushEvery = 100
+
+// Record of the payload matching another cat, either stored or earlier in the payload
+type DuplicateCat struct {
+	Index       int    `json:"index"`
+	Name        string `json:"name"`
+	BirthDate   string `json:"birthDate,omitempty"`
+	DuplicateOf string `json:"duplicateOf"`
+}
+
+type ImportResult struct {
+	Imported   int            `json:"imported"`
+	Skipped    int            `json:"skipped,omitempty"`
+	Duplicates []DuplicateCat `json:"duplicates,omitempty"`
+}
+
+// Body of an import refused because of duplicates
+type ImportConflict struct {
+	Message    string         `json:"message"`
+	Duplicates []DuplicateCat `json:"duplicates"`
+}
+
+// Streams the whole store as a JSON array, cat by cat
+func exportCats(res http.ResponseWriter, req *http.Request) {
+	Logger.Info("Exporting the cats")
+	streamCats(res, req, streamArray, nil)
+}
+
+// Checks every record before anything is stored, the IDs are kept or generated
+func prepareImport(validator CatValidator, cats []Cat) ValidationError {
+	var verr ValidationError
+	seenIDs := map[string]int{}
+	now := time.Now().UTC()
+
+	for idx := range cats {
+		cat := &cats[idx]
+		applyInputPolicies(cat)
+		for _, fieldErr := range validator.Validate(*cat).Errors {
+			verr.Add(fmt.Sprintf("[%d].%s", idx, fieldErr.Field), fieldErr.Message)
+		}
+
+		if cat.ID == "" {
+			cat.ID = idGenerator.NewID()
+		}
+		if firstIdx, seen := seenIDs[cat.ID]; seen {
+			verr.Add(fmt.Sprintf("[%d].id", idx), validator.message("duplicate_id", firstIdx))
+		}
+		seenIDs[cat.ID] = idx
+
+		if cat.CreatedAt.IsZero() {
+			cat.CreatedAt = now
+		}
+		if cat.UpdatedAt.IsZero() {
+			cat.UpdatedAt = cat.CreatedAt
+		}
+	}
+	return verr
+}
+
+// Compares each record to the stored cats it does not overwrite and to the previous records
+func findImportDuplicates(stored []Cat, cats []Cat) []DuplicateCat {
+	importedIDs := map[string]bool{}
+	for _, cat := range cats {
+		importedIDs[cat.ID] = true
+	}
+	known := []Cat{}
+	for _, cat := range stored {
+		if !importedIDs[cat.ID] {
+			known = append(known, cat)
+		}
+	}
+
+	var duplicates []DuplicateCat
+	for idx, cat := range cats {
+		if otherID, found := findDuplicateCat(known, cat); found {
+			duplicates = append(duplicates, DuplicateCat{Index: idx, Name: cat.Name, BirthDate: cat.BirthDate, DuplicateOf: otherID})
+			continue
+		}
+		known = append(known, cat)
+	}
+	return duplicates
+}
+
+// Drops the reported records from the payload
+func withoutDuplicates(cats []Cat, duplicates []DuplicateCat) []Cat {
+	skipped := map[int]bool{}
+	for _, duplicate := range duplicates {
+		skipped[duplicate.Index] = true
+	}
+	results := []Cat{}
+	for idx, cat := range cats {
+		if !skipped[idx] {
+			results = append(results, cat)
+		}
+	}
+	return results
+}
+
+// Loads an exported dataset, all or nothing
+func importCats(req *http.Request) (int, any) {
+	params := newQueryParams(req)
+	mode := params.String("mode", importMerge)
+	if mode != importMerge && mode != importReplace {
+		params.Invalid("mode", "import_mode", importMerge, importReplace)
+	}
+	onDuplicate := params.String("onDuplicate", onDuplicateInsert)
+	if onDuplicate != onDuplicateInsert && onDuplicate != onDuplicateSkip && onDuplicate != onDuplicateError {
+		params.Invalid("onDuplicate", "duplicate_mode", onDuplicateInsert, onDuplicateSkip, onDuplicateError)
+	}
+	if err := params.Err(); err != nil {
+		return errorResponse(req, err)
+	}
+	// Under --unique-cats the duplicates are refused like on a creation, they can only be skipped
+	if currentConfig().UniqueCats && onDuplicate == onDuplicateInsert {
+		onDuplicate = onDuplicateError
+	}
+
+	var cats []Cat
+	if err := decodeJSON(req.Body, &cats); err != nil {
+		Logger.Info("Unable to parse the JSON input for import")
+		return decodeFailure(req, err)
+	}
+
+	Logger.Infof("Importing %d cats in %s mode", len(cats), mode)
+
+	if verr := prepareImport(requestValidator(req), cats); verr.HasErrors() {
+		Logger.Info("Invalid import: ", verr)
+		return http.StatusUnprocessableEntity, verr
+	}
+
+	// Checked against the store the import lands in, a concurrent creation cannot push it
+	// over --max-cats in between
+	decoded := cats
+	var result ImportResult
+	var droppedIDs []string
+	var created []bool
+	defer lockEventOrder()()
+	err := storeOf(req.Context()).Transact(req.Context(), func(storedByID map[string]Cat) (StoreChange, error) {
+		// The stored cats are all dropped when replacing
+		var stored []Cat
+		if mode == importMerge {
+			stored = slices.Collect(maps.Values(storedByID))
+		}
+
+		cats = decoded
+		result = ImportResult{Duplicates: findImportDuplicates(stored, cats)}
+		if len(result.Duplicates) > 0 {
+			Logger.Infof("%d duplicated cats in the import, %s them", len(result.Duplicates), onDuplicate)
+			switch onDuplicate {
+			case onDuplicateError:
+				return StoreChange{}, refusedWrite{http.StatusConflict, ImportConflict{
+					Message:    localize(req, "duplicate_cats"),
+					Duplicates: result.Duplicates,
+				}}
+			case onDuplicateSkip:
+				cats = withoutDuplicates(cats, result.Duplicates)
+				result.Skipped = len(result.Duplicates)
+			}
+		}
+
+		if maxCats := currentConfig().MaxCats; maxCats > 0 {
+			after := map[string]bool{}
+			for _, cat := range stored {
+				after[cat.ID] = true
+			}
+			for _, cat := range cats {
+				after[cat.ID] = true
+			}
+			if len(after) > maxCats {
+				Logger.Infof("No room for the import, %d cats over %d", len(after), maxCats)
+				return StoreChange{}, refusedWrite{http.StatusInsufficientStorage, localize(req, "store_full")}
+			}
+		}
+
+		created = make([]bool, len(cats))
+		for idx, cat := range cats {
+			_, found := storedByID[cat.ID]
+			created[idx] = !found
+		}
+
+		// Those imported again are overwritten rather than dropped, and keep their photo
+		droppedIDs = nil
+		if mode == importReplace {
+			importedIDs := map[string]bool{}
+			for _, cat := range cats {
+				importedIDs[cat.ID] = true
+			}
+			for catID := range storedByID {
+				if !importedIDs[catID] {
+					droppedIDs = append(droppedIDs, catID)
+				}
+			}
+		}
+		return StoreChange{Save: cats, Delete: droppedIDs}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
+	}
+	deletePhotos(droppedIDs)
+	for _, catID := range droppedIDs {
+		publishCatEvent(eventDeleted, catID, nil)
+	}
+	for idx := range cats {
+		if created[idx] {
+			publishCatEvent(eventCreated, cats[idx].ID, &cats[idx])
+		}
+	}
+
+	Logger.Infof("%d cats imported into the DB", len(cats))
+	result.Imported = len(cats)
+	return http.StatusOK, result
+}
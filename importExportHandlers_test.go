@@ -0,0 +1,319 @@
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// IMPORT/EXPORT TESTS
+// =============================================================================
+
+// Test the export can be imported back into another store
+func TestExportImportRoundTrip(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"},
+		Cat{ID: "id2", Name: "Felix"},
+	)
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var exported []Cat
+	if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil {
+		t.Fatalf("Expected a JSON array: %v\n%s", err, rec.Body.String())
+	}
+	if len(exported) != 2 {
+		t.Fatalf("Expected 2 exported cats, got %d", len(exported))
+	}
+
+	// Loading into an empty instance
+	catsStore = NewMemoryRepo()
+	payload := rec.Body.String()
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/import", strings.NewReader(payload)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	stored := storedCats(catsStore)
+	if len(stored) != 2 || stored["id1"].Name != "Toto" || stored["id2"].Name != "Felix" {
+		t.Errorf("Expected the exported cats back, got %+v", stored)
+	}
+}
+
+// Test an empty store exports an empty array
+func TestExportEmptyStore(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+
+	rec := httptest.NewRecorder()
+	exportCats(rec, httptest.NewRequest("GET", "/api/export", nil))
+
+	if body := strings.TrimSpace(rec.Body.String()); body != "[]" {
+		t.Errorf("Expected an empty array, got %q", body)
+	}
+}
+
+// Test the merge and replace modes
+func TestImportModes(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	payload := `[{"id": "id2", "name": "Felix2"}, {"name": "NoID"}]`
+
+	tests := []struct {
+		mode     string
+		expected int
+	}{
+		{"", 3},
+		{"merge", 3},
+		{"replace", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})
+
+			statusCode, response := importCats(httptest.NewRequest("POST", "/api/import?mode="+test.mode, strings.NewReader(payload)))
+			if statusCode != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d (%v)", http.StatusOK, statusCode, response)
+			}
+			if response.(ImportResult).Imported != 2 {
+				t.Errorf("Expected 2 imported cats, got %v", response)
+			}
+
+			stored := storedCats(catsStore)
+			if len(stored) != test.expected {
+				t.Errorf("Expected %d stored cats, got %d", test.expected, len(stored))
+			}
+			if stored["id2"].Name != "Felix2" {
+				t.Errorf("Expected the imported cat to overwrite, got %+v", stored["id2"])
+			}
+		})
+	}
+}
+
+// Test any invalid record leaves the store untouched
+func TestImportIsTransactional(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	tests := []struct {
+		name         string
+		target       string
+		payload      string
+		expectedCode int
+	}{
+		{"invalid record", "/api/import?mode=replace", `[{"id": "new", "name": "Ok"}, {"name": "Bad", "birthDate": "1997"}]`, http.StatusUnprocessableEntity},
+		{"duplicated IDs", "/api/import", `[{"id": "new", "name": "Ok"}, {"id": "new", "name": "Again"}]`, http.StatusUnprocessableEntity},
+		{"not an array", "/api/import", `{"id": "new", "name": "Ok"}`, http.StatusBadRequest},
+		{"unknown mode", "/api/import?mode=append", `[{"id": "new", "name": "Ok"}]`, http.StatusBadRequest},
+		{"unknown duplicate handling", "/api/import?onDuplicate=merge", `[{"id": "new", "name": "Ok"}]`, http.StatusBadRequest},
+		{"duplicate with error", "/api/import?onDuplicate=error", `[{"id": "new", "name": "Ok"}, {"name": "Toto"}]`, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+
+			statusCode, _ := importCats(httptest.NewRequest("POST", test.target, strings.NewReader(test.payload)))
+			if statusCode != test.expectedCode {
+				t.Errorf("Expected status code %d, got %d", test.expectedCode, statusCode)
+			}
+
+			stored := storedCats(catsStore)
+			if len(stored) != 1 || stored["id1"].Name != "Toto" {
+				t.Errorf("Store should be unchanged, got %+v", stored)
+			}
+		})
+	}
+}
+
+// Test the invalid records are reported with their position
+func TestImportValidationPaths(t *testing.T) {
+	verr := prepareImport(CatValidator{}, []Cat{{Name: "Ok"}, {BirthDate: "1997"}})
+
+	expected := []string{"[1].name", "[1].birthDate"}
+	if len(verr.Errors) != len(expected) {
+		t.Fatalf("Expected errors on %v, got %+v", expected, verr.Errors)
+	}
+	for idx, field := range expected {
+		if verr.Errors[idx].Field != field {
+			t.Errorf("Expected an error on %s, got %s", field, verr.Errors[idx].Field)
+		}
+	}
+}
+
+// Test the duplicates are refused under --unique-cats unless skipped, like on a creation
+func TestImportUniqueCats(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.UniqueCats = true
+	setConfig(cfg)
+
+	payload := `[{"id": "new1", "name": "Toto", "birthDate": "2023-04-16"}, {"id": "new2", "name": "Felix"}]`
+	tests := []struct {
+		target       string
+		expectedCode int
+		expectedIDs  []string
+	}{
+		{"/api/import", http.StatusConflict, []string{"id1"}},
+		{"/api/import?onDuplicate=insert", http.StatusConflict, []string{"id1"}},
+		{"/api/import?onDuplicate=skip", http.StatusOK, []string{"id1", "new2"}},
+	}
+
+	for _, test := range tests {
+		store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"})
+		rec := httptest.NewRecorder()
+		newAppWithStore(store).ServeHTTP(rec, newJSONRequest("POST", test.target, strings.NewReader(payload)))
+		if rec.Code != test.expectedCode {
+			t.Errorf("%s: expected status code %d, got %d", test.target, test.expectedCode, rec.Code)
+		}
+		if cats, _ := store.List(t.Context()); !slices.Equal(sortedIDs(cats), test.expectedIDs) {
+			t.Errorf("%s: expected %v stored, got %v", test.target, test.expectedIDs, sortedIDs(cats))
+		}
+	}
+}
+
+// Test the invalid parameters are all reported at once
+func TestImportInvalidParams(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newAppWithStore(NewMemoryRepo()).ServeHTTP(rec, newJSONRequest("POST", "/api/import?mode=append&onDuplicate=merge", strings.NewReader(`[]`)))
+	body := rec.Body.String()
+	if rec.Code != http.StatusBadRequest || !strings.Contains(body, "mode 'append'") || !strings.Contains(body, "onDuplicate 'merge'") {
+		t.Errorf("Expected both parameters reported, got %d: %s", rec.Code, body)
+	}
+}
+
+// Test the duplicates by name and birth date are inserted, skipped or refused on demand
+func TestImportDuplicates(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	payload := `[
+		{"id": "new1", "name": "Toto", "birthDate": "2023-04-16"},
+		{"id": "new2", "name": "Felix"},
+		{"id": "new3", "name": "Felix"},
+		{"id": "id2", "name": "Garfield"}
+	]`
+
+	tests := []struct {
+		name         string
+		target       string
+		expectedCode int
+		expectedIDs  []string
+	}{
+		{"insert by default", "/api/import", http.StatusOK, []string{"id1", "id2", "new1", "new2", "new3"}},
+		{"skip", "/api/import?onDuplicate=skip", http.StatusOK, []string{"id1", "id2", "new2"}},
+		{"error", "/api/import?onDuplicate=error", http.StatusConflict, []string{"id1", "id2"}},
+		{"replace compares the payload only", "/api/import?mode=replace&onDuplicate=skip", http.StatusOK, []string{"id2", "new1", "new2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// The overwritten id2 cannot be a duplicate of its new self
+			catsStore = NewMemoryRepo(
+				Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"},
+				Cat{ID: "id2", Name: "Garfield"},
+			)
+
+			statusCode, response := importCats(httptest.NewRequest("POST", test.target, strings.NewReader(payload)))
+			if statusCode != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d (%v)", test.expectedCode, statusCode, response)
+			}
+
+			var duplicates []DuplicateCat
+			switch body := response.(type) {
+			case ImportResult:
+				duplicates = body.Duplicates
+				if body.Imported+body.Skipped != 4 {
+					t.Errorf("Expected every record imported or skipped, got %+v", body)
+				}
+			case ImportConflict:
+				duplicates = body.Duplicates
+			}
+			if test.name != "replace compares the payload only" {
+				expected := []DuplicateCat{
+					{Index: 0, Name: "Toto", BirthDate: "2023-04-16", DuplicateOf: "id1"},
+					{Index: 2, Name: "Felix", DuplicateOf: "new2"},
+				}
+				if !slices.Equal(duplicates, expected) {
+					t.Errorf("Expected the duplicates %+v, got %+v", expected, duplicates)
+				}
+			}
+
+			storedIDs := slices.Sorted(maps.Keys(storedCats(catsStore)))
+			if !slices.Equal(storedIDs, test.expectedIDs) {
+				t.Errorf("Expected the stored cats %v, got %v", test.expectedIDs, storedIDs)
+			}
+		})
+	}
+}
+
+// Test an import leaving more cats than --max-cats is refused whole, the overwritten ones not counted twice
+func TestImportMaxCats(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.MaxCats = 3
+	setConfig(cfg)
+
+	tests := []struct {
+		mode         string
+		payload      string
+		expectedCode int
+		expectedIDs  []string
+	}{
+		{"merge", `[{"id": "id3", "name": "Tom"}, {"id": "id4", "name": "Garfield"}]`, http.StatusInsufficientStorage, []string{"id1", "id2"}},
+		{"merge", `[{"id": "id2", "name": "Felix2"}, {"id": "id3", "name": "Tom"}]`, http.StatusOK, []string{"id1", "id2", "id3"}},
+		{"replace", `[{"id": "id3", "name": "Tom"}, {"id": "id4", "name": "Garfield"}, {"id": "id5", "name": "Grumpy"}]`, http.StatusOK, []string{"id3", "id4", "id5"}},
+	}
+
+	for _, test := range tests {
+		store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})
+		rec := httptest.NewRecorder()
+		newAppWithStore(store).ServeHTTP(rec, newJSONRequest("POST", "/api/import?mode="+test.mode, strings.NewReader(test.payload)))
+		if rec.Code != test.expectedCode {
+			t.Errorf("%s %s: expected status code %d, got %d", test.mode, test.payload, test.expectedCode, rec.Code)
+		}
+		if cats, _ := store.List(t.Context()); !slices.Equal(sortedIDs(cats), test.expectedIDs) {
+			t.Errorf("%s %s: expected %v stored, got %v", test.mode, test.payload, test.expectedIDs, sortedIDs(cats))
+		}
+	}
+}
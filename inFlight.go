@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Number of requests being served, the ones drained on shutdown
+var inFlightRequests atomic.Int64
+
+// Keeps the in-flight gauge up to date around each request
+func countInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlightRequests.Add(1)
+		defer inFlightRequests.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requests never turned away by --max-concurrent: the probes, and the event streams idling most of their life
+func isConcurrencyExempt(req *http.Request) bool {
+	switch req.URL.Path {
+	case "/health", "/metrics", apiPath("/cats/events"):
+		return true
+	}
+	return false
+}
+
+// Serves at most `limit` requests at once, answering 503 to the others rather than queuing them.
+// A limit of 0 leaves the requests unbounded
+func limitConcurrency(limit int, next http.Handler) http.Handler {
+	if limit <= 0 {
+		return next
+	}
+
+	slots := make(chan struct{}, limit)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isConcurrencyExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+			defer func() { <-slots }()
+			next.ServeHTTP(w, r)
+		default:
+			Logger.Warnf("Too many concurrent requests, '%s %s' turned away", r.Method, r.URL.Path)
+			w.Header().Set("Retry-After", "1")
+			writeResponse(w, r, http.StatusServiceUnavailable, ErrorBody{Error: localize(r, "server_busy")})
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// IN-FLIGHT REQUESTS TESTS
+// =============================================================================
+
+// Test the gauge counts the requests while they are served
+func TestCountInFlight(t *testing.T) {
+	var during int64
+	handler := countInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		during = inFlightRequests.Load()
+	}))
+
+	before := inFlightRequests.Load()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if during != before+1 {
+		t.Errorf("Expected %d requests in flight, got %d", before+1, during)
+	}
+	if after := inFlightRequests.Load(); after != before {
+		t.Errorf("Expected the gauge back to %d, got %d", before, after)
+	}
+}
+
+// Test a request outliving the timeout is cut by the drain
+func TestDrainServerTimeout(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	server := &http.Server{Handler: countInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+
+	requestDone := make(chan error)
+	go func() {
+		_, err := http.Get("http://" + listener.Addr().String())
+		requestDone <- err
+	}()
+	<-started
+
+	begin := time.Now()
+	if err := drainServer(server, 50*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("Expected the drain to time out, got %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Errorf("The drain should stop at the timeout, took %v", elapsed)
+	}
+
+	if err := <-requestDone; err == nil {
+		t.Error("Expected the stuck request to be cut")
+	}
+}
+
+// Test an idle server stops right away
+func TestDrainServerIdle(t *testing.T) {
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go server.Serve(listener)
+
+	if err := drainServer(server, time.Second); err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+// Test the requests over the limit are turned away at once while the slow ones hold their slot
+func TestLimitConcurrency(t *testing.T) {
+	const limit, requests = 2, 5
+	entered, release := make(chan struct{}, requests), make(chan struct{})
+	slow := limitConcurrency(limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+	}))
+
+	var wg sync.WaitGroup
+	codes := make(chan *httptest.ResponseRecorder, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			slow.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+			codes <- rec
+		}()
+	}
+
+	// The turned away requests answer while the others are still held
+	for range requests - limit {
+		rec := <-codes
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected a 503 with Retry-After, got %d", rec.Code)
+		}
+	}
+	if len(entered) != limit {
+		t.Errorf("Expected %d requests served at once, got %d", limit, len(entered))
+	}
+
+	// The probe goes through a full server
+	go func() {
+		rec := httptest.NewRecorder()
+		slow.ServeHTTP(rec, httptest.NewRequest("GET", "/health", nil))
+		codes <- rec
+	}()
+	select {
+	case <-entered:
+	case <-time.After(time.Second):
+		t.Error("Expected /health to be exempt")
+	}
+
+	close(release)
+	wg.Wait()
+	for range limit + 1 {
+		if rec := <-codes; rec.Code != http.StatusOK {
+			t.Errorf("Expected the held requests to complete, got %d", rec.Code)
+		}
+	}
+
+	// The slots are given back
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected a free slot after completion, got %d", rec.Code)
+	}
+}
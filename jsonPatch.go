@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Media type of the JSON Patch bodies (RFC 6902)
+const jsonPatchContentType = "application/json-patch+json"
+
+// Fields a JSON Patch can change, the ID and the timestamps belong to the server
+var patchableFields = []string{"name", "birthDate", "color", "breed", "weightGrams"}
+
+// Single operation of a JSON Patch
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Field targeted by a path like /color, named as the clients see it
+func patchedField(path string) (string, bool) {
+	field, found := strings.CutPrefix(path, "/")
+	if !found {
+		return "", false
+	}
+	if currentConfig().FieldNaming == namingSnake {
+		field = snakeToCamel(field)
+	}
+	return field, slices.Contains(patchableFields, field)
+}
+
+// Current value of a patchable field, as decoded from JSON
+func fieldValue(cat Cat, field string) any {
+	values := map[string]any{
+		"name":        cat.Name,
+		"birthDate":   cat.BirthDate,
+		"color":       cat.Color,
+		"breed":       cat.Breed,
+		"weightGrams": cat.WeightGrams,
+	}
+	encoded, _ := json.Marshal(values[field])
+	var value any
+	json.Unmarshal(encoded, &value)
+	return value
+}
+
+// Applies a JSON Patch (RFC 6902) in order: add and replace set a field, remove clears it and
+// a failed test stops everything with a 409. The answer tells why the patch is refused.
+func applyJSONPatch(req *http.Request, cat *Cat, operations []PatchOperation) (int, any, bool) {
+	var verr ValidationError
+	for idx, operation := range operations {
+		if _, valid := patchedField(operation.Path); !valid {
+			verr.Add(fmt.Sprintf("[%d].path", idx), localize(req, "one_of", "/"+strings.Join(patchableFields, ", /")))
+		}
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				verr.Add(fmt.Sprintf("[%d].value", idx), localize(req, "required"))
+			}
+		case "remove":
+		default:
+			verr.Add(fmt.Sprintf("[%d].op", idx), localize(req, "patch_op"))
+		}
+	}
+	if verr.HasErrors() {
+		Logger.Info("Invalid JSON Patch: ", verr)
+		return http.StatusUnprocessableEntity, verr, true
+	}
+
+	for idx, operation := range operations {
+		field, _ := patchedField(operation.Path)
+		switch operation.Op {
+		case "test":
+			var expected any
+			if err := json.Unmarshal(operation.Value, &expected); err != nil || !reflect.DeepEqual(fieldValue(*cat, field), expected) {
+				Logger.Infof("JSON Patch test %d failed on %s", idx, operation.Path)
+				return http.StatusConflict, localize(req, "patch_test_failed", idx, operation.Path), true
+			}
+			continue
+		case "remove":
+			operation.Value = json.RawMessage("null")
+		}
+
+		// The merge patch of a single field has the same checks
+		if err := applyMergePatch(cat, map[string]json.RawMessage{field: operation.Value}); err != nil {
+			Logger.Info("Invalid JSON Patch: ", err)
+			return http.StatusBadRequest, localizeError(req, err), true
+		}
+	}
+	return 0, nil, false
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// JSON PATCH TESTS
+// =============================================================================
+
+// Test the JSON Patch operations through PATCH, told apart by their Content-Type
+func TestPatchCatJSONPatch(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	tests := []struct {
+		name         string
+		patch        string
+		expectedCode int
+		expectedCat  Cat
+		expectedBody string
+	}{
+		{
+			name:         "replace and add",
+			patch:        `[{"op": "replace", "path": "/color", "value": "Black"}, {"op": "add", "path": "/weightGrams", "value": 4200}]`,
+			expectedCode: http.StatusOK,
+			expectedCat:  Cat{ID: "id1", Name: "Toto", Color: "Black", Breed: "Siamese", WeightGrams: 4200},
+		},
+		{
+			name:         "remove",
+			patch:        `[{"op": "remove", "path": "/breed"}]`,
+			expectedCode: http.StatusOK,
+			expectedCat:  Cat{ID: "id1", Name: "Toto", Color: "Grey"},
+		},
+		{
+			name:         "passed test",
+			patch:        `[{"op": "test", "path": "/color", "value": "Grey"}, {"op": "replace", "path": "/name", "value": "Felix"}]`,
+			expectedCode: http.StatusOK,
+			expectedCat:  Cat{ID: "id1", Name: "Felix", Color: "Grey", Breed: "Siamese"},
+		},
+		{
+			name:         "failed test",
+			patch:        `[{"op": "replace", "path": "/name", "value": "Felix"}, {"op": "test", "path": "/color", "value": "Black"}]`,
+			expectedCode: http.StatusConflict,
+			expectedBody: "The test of operation 1 failed",
+		},
+		{
+			name:         "read-only path",
+			patch:        `[{"op": "replace", "path": "/id", "value": "id2"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `"field":"[0].path"`,
+		},
+		{
+			name:         "unsupported op",
+			patch:        `[{"op": "move", "from": "/color", "path": "/breed"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `"field":"[0].op"`,
+		},
+		{
+			name:         "missing value",
+			patch:        `[{"op": "add", "path": "/color"}]`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `"field":"[0].value"`,
+		},
+		{
+			name:         "wrong type",
+			patch:        `[{"op": "replace", "path": "/weightGrams", "value": "heavy"}]`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "The weightGrams must be an integer",
+		},
+		{
+			name:         "not an array",
+			patch:        `{"color": "Black"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "Invalid JSON input",
+		},
+	}
+
+	original := Cat{ID: "id1", Name: "Toto", Color: "Grey", Breed: "Siamese"}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			catsStore = NewMemoryRepo(original)
+
+			req := httptest.NewRequest("PATCH", "/api/cats/id1", strings.NewReader(test.patch))
+			req.Header.Set("Content-Type", jsonPatchContentType)
+			rec := httptest.NewRecorder()
+			newApp().ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", test.expectedCode, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), test.expectedBody) {
+				t.Errorf("Expected %q in the answer, got %s", test.expectedBody, rec.Body.String())
+			}
+
+			stored, _ := catsStore.Get(t.Context(), "id1")
+			stored.UpdatedAt = original.UpdatedAt
+			if test.expectedCode != http.StatusOK {
+				test.expectedCat = original
+			}
+			if stored != test.expectedCat {
+				t.Errorf("Expected the stored cat %+v, got %+v", test.expectedCat, stored)
+			}
+		})
+	}
+}
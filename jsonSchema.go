@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version of JSON Schema the generated schemas follow
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// Schema of a Go type as encoding/json writes it, the struct fields without omitempty or omitzero being required
+func jsonSchemaOf(goType reflect.Type) map[string]any {
+	switch {
+	case goType == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case goType.Kind() == reflect.Pointer:
+		return jsonSchemaOf(goType.Elem())
+	}
+
+	switch goType.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": jsonSchemaOf(goType.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object"}
+	case reflect.Struct:
+		return structSchema(goType)
+	}
+	return map[string]any{}
+}
+
+func structSchema(goType reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+
+	for idx := range goType.NumField() {
+		field := goType.Field(idx)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = jsonSchemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
+
+// Schema of the cats, with the constraints of the validation the struct cannot tell
+func catSchema() map[string]any {
+	schema := jsonSchemaOf(reflect.TypeFor[Cat]())
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = "Cat"
+
+	properties := schema["properties"].(map[string]any)
+	properties["birthDate"].(map[string]any)["format"] = "date"
+	properties["breed"].(map[string]any)["enum"] = catBreeds
+	properties["weightGrams"].(map[string]any)["minimum"] = 0
+	properties["weightGrams"].(map[string]any)["maximum"] = maxWeightGrams
+	for _, serverAssigned := range []string{"createdAt", "updatedAt"} {
+		properties[serverAssigned].(map[string]any)["description"] = "Assigned by the server"
+	}
+	return schema
+}
+
+// Serves the JSON Schema of a cat, for the tools not reading OpenAPI
+func getCatSchema(req *http.Request) (int, any) {
+	return http.StatusOK, catSchema()
+}
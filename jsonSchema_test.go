@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// JSON SCHEMA TESTS
+// =============================================================================
+
+// Test the schema follows the json tags of a struct
+func TestJSONSchemaOf(t *testing.T) {
+	type sample struct {
+		Name     string            `json:"name"`
+		Count    int               `json:"count,omitempty"`
+		Ratio    float64           `json:"ratio"`
+		Tags     []string          `json:"tags,omitempty"`
+		Seen     time.Time         `json:"seen,omitzero"`
+		Extra    map[string]string `json:"extra,omitempty"`
+		Internal string            `json:"-"`
+		Untagged bool
+		hidden   string
+	}
+
+	schema := jsonSchemaOf(reflect.TypeFor[sample]())
+	properties := schema["properties"].(map[string]any)
+
+	expectedTypes := map[string]string{
+		"name": "string", "count": "integer", "ratio": "number", "tags": "array",
+		"seen": "string", "extra": "object", "Untagged": "boolean",
+	}
+	if len(properties) != len(expectedTypes) {
+		t.Errorf("Expected %d properties, got %v", len(expectedTypes), properties)
+	}
+	for name, expectedType := range expectedTypes {
+		property, found := properties[name].(map[string]any)
+		if !found || property["type"] != expectedType {
+			t.Errorf("Expected %s of type %s, got %v", name, expectedType, properties[name])
+		}
+	}
+	if items := properties["tags"].(map[string]any)["items"].(map[string]any); items["type"] != "string" {
+		t.Errorf("Expected string items, got %v", items)
+	}
+	if format := properties["seen"].(map[string]any)["format"]; format != "date-time" {
+		t.Errorf("Expected a date-time, got %v", format)
+	}
+	if required := schema["required"].([]string); !slices.Equal(required, []string{"name", "ratio", "Untagged"}) {
+		t.Errorf("Expected the fields always written as required, got %v", required)
+	}
+}
+
+// Test the cat schema is served with its validation constraints
+func TestGetCatSchema(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/schema", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var schema struct {
+		Schema     string `json:"$schema"`
+		Required   []string
+		Properties map[string]struct {
+			Type    string
+			Format  string
+			Enum    []string
+			Maximum int
+		}
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&schema); err != nil {
+		t.Fatalf("Expected a JSON schema: %v", err)
+	}
+
+	if schema.Schema != jsonSchemaDialect || !slices.Equal(schema.Required, []string{"name"}) {
+		t.Errorf("Expected a schema requiring the name, got %+v", schema)
+	}
+	if len(schema.Properties) != reflect.TypeFor[Cat]().NumField() {
+		t.Errorf("Expected a property per field, got %v", schema.Properties)
+	}
+	if breed := schema.Properties["breed"]; !slices.Equal(breed.Enum, catBreeds) {
+		t.Errorf("Expected the known breeds, got %v", breed.Enum)
+	}
+	if weight := schema.Properties["weightGrams"]; weight.Type != "integer" || weight.Maximum != maxWeightGrams {
+		t.Errorf("Expected the weight bounds, got %+v", weight)
+	}
+	if birthDate := schema.Properties["birthDate"]; birthDate.Format != "date" {
+		t.Errorf("Expected a date, got %+v", birthDate)
+	}
+}
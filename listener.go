@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// Scheme of --addr for a Unix domain socket, unix:///tmp/cats.sock
+const unixScheme = "unix://"
+
+// Listens on the TCP address, or on the Unix socket path given with the unix:// scheme.
+// The socket file is removed when the listener is closed
+func listen(addr string) (net.Listener, error) {
+	socketPath, isUnix := strings.CutPrefix(addr, unixScheme)
+	if !isUnix {
+		return net.Listen("tcp", addr)
+	}
+
+	if err := removeStaleSocket(socketPath); err != nil {
+		return nil, err
+	}
+	return net.Listen("unix", socketPath)
+}
+
+// A socket file left by a crashed instance would make the listen fail, a live one is kept
+func removeStaleSocket(socketPath string) error {
+	info, err := os.Lstat(socketPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", socketPath)
+	}
+
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", socketPath)
+	}
+	Logger.Warnf("Removing the stale socket %s", socketPath)
+	return os.Remove(socketPath)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// =============================================================================
+// LISTENER TESTS
+// =============================================================================
+
+// Test the API is served over a Unix socket, removed on shutdown
+func TestListenUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cats.sock")
+	listener, err := listen(unixScheme + socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	server := &http.Server{Handler: newApp()}
+	go server.Serve(listener)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	res, err := client.Get("http://cats/health")
+	if err != nil {
+		t.Fatalf("Failed to call over the socket: %v", err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d: %s", http.StatusOK, res.StatusCode, body)
+	}
+
+	if _, err := listen(unixScheme + socketPath); err == nil {
+		t.Error("Expected a socket in use to be kept")
+	}
+
+	server.Shutdown(context.Background())
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the socket removed on shutdown, got %v", err)
+	}
+}
+
+// Test a socket left behind is replaced but not another file
+func TestListenStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "cats.sock")
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	// As after a crash
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listen(unixScheme + socketPath)
+	if err != nil {
+		t.Fatalf("Expected the stale socket replaced, got %v", err)
+	}
+	listener.Close()
+
+	filePath := filepath.Join(t.TempDir(), "cats.txt")
+	os.WriteFile(filePath, []byte("not a socket"), 0o600)
+	if _, err := listen(unixScheme + filePath); err == nil {
+		t.Error("Expected a regular file to be left alone")
+	}
+	if _, err := os.Stat(filePath); err != nil {
+		t.Errorf("Expected the file kept, got %v", err)
+	}
+}
+
+// Test a plain address still listens on TCP
+func TestListenTCP(t *testing.T) {
+	listener, err := listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	if network := listener.Addr().Network(); network != "tcp" {
+		t.Errorf("Expected a TCP listener, got %s", network)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Languages of the error messages, the first one being the default
+var supportedLanguages = []string{"en", "fr"}
+
+// Messages answered to the clients by their code, in each supported language. The placeholders
+// are filled with the values given by the call site, in the same order in every language
+var messageCatalog = map[string]map[string]string{
+	"cat_not_found":         {"en": "Cat not found", "fr": "Chat introuvable"},
+	"cat_deleted":           {"en": "Cat deleted", "fr": "Chat supprimé"},
+	"store_empty":           {"en": "The store has no cat", "fr": "Le stockage n'a aucun chat"},
+	"invalid_json":          {"en": "Invalid JSON input", "fr": "Entrée JSON invalide"},
+	"body_too_large":        {"en": "Request body too large", "fr": "Corps de la requête trop volumineux"},
+	"unknown_field":         {"en": "Unknown field: %s", "fr": "Champ inconnu : %s"},
+	"cat_exists":            {"en": "The cat already exists", "fr": "Le chat existe déjà"},
+	"id_exists":             {"en": "A cat with this ID already exists", "fr": "Un chat a déjà cet ID"},
+	"duplicate_cat":         {"en": "A cat with the same name and birth date already exists", "fr": "Un chat a déjà ce nom et cette date de naissance"},
+	"duplicate_cats":        {"en": "Some cats have the same name and birth date as another one", "fr": "Des chats ont le même nom et la même date de naissance qu'un autre"},
+	"store_full":            {"en": "The cats store is full", "fr": "Le stockage des chats est plein"},
+	"cat_changed":           {"en": "The cat was changed since it was read", "fr": "Le chat a été modifié depuis sa lecture"},
+	"id_mismatch":           {"en": "The id of the body does not match the one of the path", "fr": "L'id du corps ne correspond pas à celui du chemin"},
+	"unknown_projection":    {"en": "has the unknown field '%s', must be among: %s", "fr": "a le champ inconnu '%s', doit être parmi : %s"},
+	"name_cleared":          {"en": "The name is required and cannot be cleared", "fr": "Le nom est requis et ne peut pas être effacé"},
+	"not_string":            {"en": "The %s must be a string", "fr": "Le champ %s doit être une chaîne"},
+	"not_integer":           {"en": "The %s must be an integer", "fr": "Le champ %s doit être un entier"},
+	"patch_test_failed":     {"en": "The test of operation %d failed, %s differs", "fr": "Le test de l'opération %d a échoué, %s diffère"},
+	"batch_id_required":     {"en": "The id is required and must be a string", "fr": "L'id est requis et doit être une chaîne"},
+	"batch_get_too_many":    {"en": "At most %d ids can be fetched at once", "fr": "Au plus %d ids peuvent être lus à la fois"},
+	"batch_patch_too_many":  {"en": "At most %d cats can be patched at once", "fr": "Au plus %d chats peuvent être modifiés à la fois"},
+	"photo_type":            {"en": "The photo must be a JPEG or a PNG", "fr": "La photo doit être un JPEG ou un PNG"},
+	"photo_too_large":       {"en": "The photo is larger than 512 KiB", "fr": "La photo dépasse 512 Kio"},
+	"photo_form":            {"en": "The form needs the cat as JSON in its 'cat' part", "fr": "Le formulaire doit porter le chat en JSON dans sa partie 'cat'"},
+	"photo_not_found":       {"en": "Photo not found", "fr": "Photo introuvable"},
+	"request_interrupted":   {"en": "Request interrupted", "fr": "Requête interrompue"},
+	"request_timed_out":     {"en": "Request timed out", "fr": "Délai de la requête dépassé"},
+	"request_in_progress":   {"en": "A request with the same Idempotency-Key is in progress", "fr": "Une requête avec la même Idempotency-Key est en cours"},
+	"invalid_request":       {"en": "The request does not match the spec: %s", "fr": "La requête ne correspond pas à la spécification : %s"},
+	"invalid_api_key":       {"en": "Missing or invalid API key", "fr": "Clé d'API manquante ou invalide"},
+	"confirm_clear_all":     {"en": "Deleting all the cats needs a confirmation, resend with ?confirm=<token>", "fr": "Supprimer tous les chats demande une confirmation, renvoyez avec ?confirm=<token>"},
+	"confirm_expired":       {"en": "Unknown or expired confirmation token, resend with the new one", "fr": "Jeton de confirmation inconnu ou expiré, renvoyez avec le nouveau"},
+	"server_busy":           {"en": "Server busy", "fr": "Serveur occupé"},
+	"server_stopping":       {"en": "Server shutting down", "fr": "Le serveur s'arrête"},
+	"server_starting":       {"en": "Server is starting", "fr": "Le serveur démarre"},
+	"streaming_unsupported": {"en": "Streaming unsupported", "fr": "Diffusion non prise en charge"},
+	"spec_unavailable":      {"en": "Spec unavailable", "fr": "Spécification indisponible"},
+	"invalid_spec":          {"en": "Invalid spec: %s", "fr": "Spécification invalide : %s"},
+	"unknown_route":         {"en": "No such route, see /swagger/ for the API", "fr": "Route inconnue, voir /swagger/ pour l'API"},
+	"method_not_allowed":    {"en": "Method %s not allowed, use one of: %s", "fr": "Méthode %s non autorisée, utilisez l'une de : %s"},
+	"content_type":          {"en": "Content-Type must be %s", "fr": "Le Content-Type doit être %s"},
+	"internal_error":        {"en": http.StatusText(http.StatusInternalServerError), "fr": "Erreur interne du serveur"},
+	"invalid_query":         {"en": "Invalid query parameter: %s", "fr": "Paramètre de requête invalide : %s"},
+	"backfill_date":         {"en": "The default strategy needs a past date=YYYY-MM-DD", "fr": "La stratégie default demande une date passée date=AAAA-MM-JJ"},
+	"backfill_strategy":     {"en": "The strategy must be '%s' or '%s'", "fr": "La stratégie doit être '%s' ou '%s'"},
+	"backfill_disabled":     {"en": "The backfill is disabled, start with --api-key", "fr": "Le backfill est désactivé, démarrez avec --api-key"},
+	"dump_disabled":         {"en": "The store dump is disabled, start with --admin-dump", "fr": "Le dump du stockage est désactivé, démarrez avec --admin-dump"},
+
+	// Rules of a query parameter, following its name and value
+	"not_integer_param": {"en": "must be an integer", "fr": "doit être un entier"},
+	"not_boolean_param": {"en": "must be true or false", "fr": "doit être true ou false"},
+	"positive":          {"en": "must be a positive integer", "fr": "doit être un entier positif"},
+	"positive_or_zero":  {"en": "must be a positive integer or 0", "fr": "doit être un entier positif ou 0"},
+	"import_mode":       {"en": "must be '%s' or '%s'", "fr": "doit être '%s' ou '%s'"},
+	"duplicate_mode":    {"en": "must be '%s', '%s' or '%s'", "fr": "doit être '%s', '%s' ou '%s'"},
+
+	// Validation messages, following the field name
+	"required":        {"en": "is required", "fr": "est requis"},
+	"date_layout":     {"en": "must be YYYY-MM-DD", "fr": "doit être au format AAAA-MM-JJ"},
+	"future_date":     {"en": "cannot be in the future", "fr": "ne peut pas être dans le futur"},
+	"negative":        {"en": "cannot be negative", "fr": "ne peut pas être négatif"},
+	"one_of":          {"en": "must be one of: %s", "fr": "doit être l'un de : %s"},
+	"control_chars":   {"en": "cannot contain control characters", "fr": "ne peut pas contenir de caractères de contrôle"},
+	"too_long":        {"en": "has too many characters, the limit is %d", "fr": "a trop de caractères, la limite est %d"},
+	"id_taken":        {"en": "is already taken by another cat", "fr": "est déjà pris par un autre chat"},
+	"same_birth_date": {"en": "has the same birth date as the cat %s", "fr": "a la même date de naissance que le chat %s"},
+	"at_most":         {"en": "must be at most %d", "fr": "doit être au plus %d"},
+	"duplicate_id":    {"en": "duplicates the id of the record %d", "fr": "reprend l'id de l'enregistrement %d"},
+	"patch_op":        {"en": "must be add, replace, remove or test", "fr": "doit être add, replace, remove ou test"},
+}
+
+// Best supported language for the client, matching on the primary subtag (fr-CA is fr)
+func negotiateLanguage(header string) string {
+	qualities := map[string]float64{}
+	for tag, quality := range parseQualities(header) {
+		primary, _, _ := strings.Cut(tag, "-")
+		qualities[primary] = max(qualities[primary], quality)
+	}
+
+	best, bestQuality := supportedLanguages[0], 0.0
+	for _, language := range supportedLanguages {
+		quality, found := qualities[language]
+		if !found {
+			quality = qualities["*"]
+		}
+		if quality > bestQuality {
+			best, bestQuality = language, quality
+		}
+	}
+	return best
+}
+
+// Message of the catalog in the language, the args filling its placeholders
+func translate(language string, code string, args ...any) string {
+	text, found := messageCatalog[code][language]
+	if !found {
+		text = messageCatalog[code][supportedLanguages[0]]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// Language asked by the client of the request
+func requestLanguage(req *http.Request) string {
+	return negotiateLanguage(req.Header.Get("Accept-Language"))
+}
+
+// Message of the catalog in the language of the client
+func localize(req *http.Request, code string, args ...any) string {
+	return translate(requestLanguage(req), code, args...)
+}
+
+// Text of an error answered to the client, in its language when the error carries a catalog code
+func localizeError(req *http.Request, err error) string {
+	var clientErr clientError
+	if errors.As(err, &clientErr) {
+		return localize(req, clientErr.code, clientErr.args...)
+	}
+	var unknownErr UnknownFieldError
+	if errors.As(err, &unknownErr) {
+		return localize(req, "unknown_field", unknownErr.Field)
+	}
+	return err.Error()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// LOCALIZATION TESTS
+// =============================================================================
+
+// Test the language is picked from the quality values and the primary subtags
+func TestNegotiateLanguage(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "en"},
+		{"fr", "fr"},
+		{"fr-CA", "fr"},
+		{"FR-fr, en;q=0.5", "fr"},
+		{"en-US, fr;q=0.9", "en"},
+		{"de, fr;q=0.3", "fr"},
+		{"de", "en"},
+		{"*", "en"},
+		{"en;q=0, *", "fr"},
+	}
+
+	for _, test := range tests {
+		if language := negotiateLanguage(test.acceptLanguage); language != test.expected {
+			t.Errorf("Accept-Language %q: expected %q, got %q", test.acceptLanguage, test.expected, language)
+		}
+	}
+}
+
+// Test the messages are translated with their values, the unknown languages falling back to English
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		language string
+		code     string
+		args     []any
+		expected string
+	}{
+		{"fr", "cat_not_found", nil, "Chat introuvable"},
+		{"fr", "one_of", []any{"Bengal, Persian"}, "doit être l'un de : Bengal, Persian"},
+		{"fr", "content_type", []any{"application/json"}, "Le Content-Type doit être application/json"},
+		{"en", "batch_get_too_many", []any{100}, "At most 100 ids can be fetched at once"},
+		{"de", "photo_not_found", nil, "Photo not found"},
+	}
+
+	for _, test := range tests {
+		if message := translate(test.language, test.code, test.args...); message != test.expected {
+			t.Errorf("%s in %s: expected %q, got %q", test.code, test.language, test.expected, message)
+		}
+	}
+}
+
+// Test every message of the catalog exists in each language with the same placeholders
+func TestMessageCatalog(t *testing.T) {
+	placeholders := regexp.MustCompile(`%[a-z]`)
+	for code, messages := range messageCatalog {
+		expected := placeholders.FindAllString(messages[supportedLanguages[0]], -1)
+		for _, language := range supportedLanguages {
+			message, found := messages[language]
+			if !found || message == "" {
+				t.Errorf("%s: missing in %s", code, language)
+				continue
+			}
+			if found := placeholders.FindAllString(message, -1); strings.Join(found, "") != strings.Join(expected, "") {
+				t.Errorf("%s: expected the placeholders %v in %s, got %v", code, expected, language, found)
+			}
+		}
+	}
+}
+
+// Test the error responses follow Accept-Language, the successful ones are left alone
+func TestLocalizedErrors(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	send := func(req *http.Request, acceptLanguage string) *httptest.ResponseRecorder {
+		req.Header.Set("Accept-Language", acceptLanguage)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(httptest.NewRequest("GET", "/api/cats/id2", nil), "fr-FR,fr;q=0.9")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != `"Chat introuvable"`+"\n" {
+		t.Errorf("Expected a French 404, got %d with %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Language") != "fr" || !strings.Contains(rec.Header().Get("Vary"), "Accept-Language") {
+		t.Errorf("Expected the language announced, got %q varying on %q", rec.Header().Get("Content-Language"), rec.Header().Get("Vary"))
+	}
+
+	rec = send(httptest.NewRequest("GET", "/api/cats/id2", nil), "")
+	if rec.Body.String() != `"Cat not found"`+"\n" || rec.Header().Get("Content-Language") != "en" {
+		t.Errorf("Expected an English 404 by default, got %s", rec.Body.String())
+	}
+
+	rec = send(newJSONRequest("POST", "/api/cats", strings.NewReader(`{"birthDate": "soon"}`)), "fr")
+	var verr ValidationError
+	json.NewDecoder(rec.Body).Decode(&verr)
+	expected := []FieldError{{Field: "name", Message: "est requis"}, {Field: "birthDate", Message: "doit être au format AAAA-MM-JJ"}}
+	if rec.Code != http.StatusUnprocessableEntity || len(verr.Errors) != 2 || verr.Errors[0] != expected[0] || verr.Errors[1] != expected[1] {
+		t.Errorf("Expected the French validation errors, got %d with %+v", rec.Code, verr)
+	}
+
+	rec = send(httptest.NewRequest("GET", "/nonsense", nil), "fr")
+	var body ErrorBody
+	if json.NewDecoder(rec.Body).Decode(&body); body.Error != "Route inconnue, voir /swagger/ pour l'API" || body.Path != "/nonsense" {
+		t.Errorf("Expected the French route error, got %+v", body)
+	}
+
+	tests := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		expected    string
+	}{
+		{"GET", "/api/cats/id1/photo", "", "", "Photo introuvable"},
+		{"POST", "/api/cats/batchGet", "application/json", `{"ids": [` + strings.Repeat(`"id1", `, maxBatchGetIDs) + `"id1"]}`, "Au plus 100 ids peuvent être lus à la fois"},
+		{"PATCH", "/api/cats/id1", "application/merge-patch+json", `{"name": null}`, "Le nom est requis et ne peut pas être effacé"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		var message string
+		if json.NewDecoder(send(req, "fr").Body).Decode(&message); message != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.method, test.path, test.expected, message)
+		}
+	}
+
+	rec = send(httptest.NewRequest("GET", "/api/cats/id1", nil), "fr")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Language") != "" {
+		t.Errorf("Expected the cat untouched, got %d in %q", rec.Code, rec.Header().Get("Content-Language"))
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Number of log lines kept when --log-buffer is not given
+const defaultLogBuffer = 200
+
+// Color codes of the console output
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Keeps the last log lines in memory, the oldest ones are overwritten
+type LogRing struct {
+	lock  sync.Mutex
+	lines []string
+	start int
+	count int
+}
+
+func NewLogRing(size int) *LogRing {
+	return &LogRing{lines: make([]string, size)}
+}
+
+// Receives the logger output, a write may hold several lines
+func (ring *LogRing) Write(data []byte) (int, error) {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	// Served as JSON, the console colors are dropped
+	text := ansiEscape.ReplaceAllString(string(data), "")
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		ring.push(line)
+	}
+	return len(data), nil
+}
+
+func (ring *LogRing) push(line string) {
+	// A zero size disables the capture
+	if len(ring.lines) == 0 {
+		return
+	}
+	ring.lines[(ring.start+ring.count)%len(ring.lines)] = line
+	if ring.count < len(ring.lines) {
+		ring.count++
+	} else {
+		ring.start = (ring.start + 1) % len(ring.lines)
+	}
+}
+
+// Kept lines, oldest first
+func (ring *LogRing) Lines() []string {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+	return ring.ordered()
+}
+
+func (ring *LogRing) ordered() []string {
+	results := make([]string, ring.count)
+	for idx := range results {
+		results[idx] = ring.lines[(ring.start+idx)%len(ring.lines)]
+	}
+	return results
+}
+
+// Changes the capacity, keeping the most recent lines
+func (ring *LogRing) Resize(size int) {
+	ring.lock.Lock()
+	defer ring.lock.Unlock()
+
+	kept := ring.ordered()
+	if len(kept) > size {
+		kept = kept[len(kept)-size:]
+	}
+	ring.lines = make([]string, size)
+	ring.start = 0
+	ring.count = copy(ring.lines, kept)
+}
+
+// Sized from --log-buffer once the flags are parsed
+var logRing = NewLogRing(defaultLogBuffer)
+
+// Last log lines of the application, oldest first
+func getLogs(req *http.Request) (int, any) {
+	return http.StatusOK, logRing.Lines()
+}
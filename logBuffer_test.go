@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// LOG BUFFER TESTS
+// =============================================================================
+
+// Test the ring keeps the most recent lines in order
+func TestLogRing(t *testing.T) {
+	ring := NewLogRing(3)
+	ring.Write([]byte("one\n"))
+	ring.Write([]byte("two\nthree\n"))
+
+	if lines := ring.Lines(); !slices.Equal(lines, []string{"one", "two", "three"}) {
+		t.Errorf("Expected all the lines, got %v", lines)
+	}
+
+	ring.Write([]byte("four\n"))
+	ring.Write([]byte("five\n"))
+	if lines := ring.Lines(); !slices.Equal(lines, []string{"three", "four", "five"}) {
+		t.Errorf("Expected the oldest lines overwritten, got %v", lines)
+	}
+
+	ring.Resize(2)
+	if lines := ring.Lines(); !slices.Equal(lines, []string{"four", "five"}) {
+		t.Errorf("Expected the recent lines kept on shrink, got %v", lines)
+	}
+
+	ring.Resize(4)
+	ring.Write([]byte("six\n"))
+	if lines := ring.Lines(); !slices.Equal(lines, []string{"four", "five", "six"}) {
+		t.Errorf("Expected the lines kept on growth, got %v", lines)
+	}
+
+	ring.Resize(0)
+	ring.Write([]byte("seven\n"))
+	if lines := ring.Lines(); len(lines) != 0 {
+		t.Errorf("Expected no capture with a zero size, got %v", lines)
+	}
+}
+
+// Test the /logs endpoint serves the captured application logs
+func TestLogsEndpoint(t *testing.T) {
+	app := newApp()
+	Logger.Info("Marker for the logs endpoint")
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/logs", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var lines []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &lines); err != nil {
+		t.Fatalf("Expected a JSON array of lines: %v", err)
+	}
+	if !slices.ContainsFunc(lines, func(line string) bool { return strings.HasSuffix(line, "Marker for the logs endpoint") }) {
+		t.Errorf("Expected the logged marker, got %v", lines)
+	}
+}
+
+// Test the /logs endpoint requires the API key once configured
+func TestLogsEndpointAPIKey(t *testing.T) {
+	// Save original config state
+	originalConfig := currentConfig()
+	defer func() {
+		// Restore original state
+		setConfig(originalConfig)
+	}()
+
+	cfg := currentConfig()
+	cfg.APIKey = "secret"
+	setConfig(cfg)
+	app := newApp()
+
+	tests := []struct {
+		name         string
+		key          string
+		expectedCode int
+	}{
+		{"no key", "", http.StatusUnauthorized},
+		{"wrong key", "guess", http.StatusUnauthorized},
+		{"right key", "secret", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/logs", nil)
+			if test.key != "" {
+				req.Header.Set("X-API-Key", test.key)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode {
+				t.Errorf("Expected status code %d, got %d", test.expectedCode, rec.Code)
+			}
+		})
+	}
+}
@@ -1,14 +1,204 @@
 package main
 
-import "gitlab.com/ggpack/logchain-go"
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
 
-func initLogging() logchain.Logger {
-	params := logchain.Params{
-		"template":  "{{.timestamp}} " + version + " {{.levelLetter}} {{.fileLine}} {{.msg}}",
-		"verbosity": 3,
+// Console coloring of the log levels
+const (
+	logColorAuto   = "auto"
+	logColorAlways = "always"
+	logColorNever  = "never"
+)
+
+// ANSI codes wrapping the level and message of a line
+const (
+	ansiReset  = "\x1b[0m"
+	ansiGray   = "\x1b[90m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiRed    = "\x1b[31m"
+)
+
+// Format of a log line, the colored one also pads the file location to align the messages
+func logTemplate(colored bool) string {
+	if !colored {
+		return "{{.timestamp}} " + version + " {{.levelLetter}} {{.fileLine}} {{.msg}}"
+	}
+	levelColor := `{{if eq .levelLetter "E"}}` + ansiRed +
+		`{{else if eq .levelLetter "W"}}` + ansiYellow +
+		`{{else if eq .levelLetter "I"}}` + ansiGreen +
+		`{{else}}` + ansiGray + `{{end}}`
+	return "{{.timestamp}} " + version + " " + levelColor + "{{.levelLetter}}" + ansiReset +
+		` {{printf "%-24s" .fileLine}} ` + levelColor + "{{.msg}}" + ansiReset
+}
+
+// Colors for an interactive terminal only, unless forced either way
+func wantsLogColor(mode string, out *os.File) bool {
+	switch mode {
+	case logColorAlways:
+		return true
+	case logColorNever:
+		return false
+	}
+	info, err := out.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Verbosity of logchain for each --log-level
+var logVerbosity = map[string]int{
+	logLevelError: 0,
+	logLevelWarn:  1,
+	logLevelInfo:  2,
+	logLevelDebug: 3,
+}
+
+// Templates of the lines, by whether they are colored
+var logLines = map[bool]*template.Template{
+	false: template.Must(template.New("line").Parse(logTemplate(false))),
+	true:  template.Must(template.New("line").Parse(logTemplate(true))),
+}
+
+// Whether stdout is a terminal, for --log-color=auto
+var logToTerminal = wantsLogColor(logColorAuto, os.Stdout)
+
+// Logger of the app. The level and colors are read from the config in effect for each line, a
+// reload changes them without swapping the logger under the requests
+type AppLogger struct {
+	out io.Writer
+}
+
+// The lines are also captured for the /logs endpoint
+func initLogging() AppLogger {
+	return AppLogger{out: io.MultiWriter(os.Stdout, logRing)}
+}
+
+// Whether the lines of the level are written with the --log-level in effect
+func (logger AppLogger) enabled(level string) bool {
+	return logger.out != nil && logVerbosity[level] <= logVerbosity[currentConfig().LogLevel]
+}
+
+// Template of the lines with the --log-color in effect
+func (logger AppLogger) line() *template.Template {
+	switch currentConfig().LogColor {
+	case logColorAlways:
+		return logLines[true]
+	case logColorNever:
+		return logLines[false]
+	}
+	return logLines[logToTerminal]
+}
+
+// Key-value pairs of a log line, for the log processors to index rather than a dumped struct
+type Fields map[string]any
+
+// Line of log carrying fields, written by one of its level methods
+type FieldEntry struct {
+	logger AppLogger
+	fields Fields
+}
+
+func (logger AppLogger) WithField(key string, value any) FieldEntry {
+	return FieldEntry{logger: logger, fields: Fields{key: value}}
+}
+
+func (logger AppLogger) WithFields(fields Fields) FieldEntry {
+	return FieldEntry{logger: logger, fields: maps.Clone(fields)}
+}
+
+func (logger AppLogger) Debug(args ...any) {
+	FieldEntry{logger: logger}.log(logLevelDebug, "D", fmt.Sprint(args...))
+}
+
+func (logger AppLogger) Debugf(format string, args ...any) {
+	FieldEntry{logger: logger}.log(logLevelDebug, "D", fmt.Sprintf(format, args...))
+}
+
+func (logger AppLogger) Info(args ...any) {
+	FieldEntry{logger: logger}.log(logLevelInfo, "I", fmt.Sprint(args...))
+}
+
+func (logger AppLogger) Infof(format string, args ...any) {
+	FieldEntry{logger: logger}.log(logLevelInfo, "I", fmt.Sprintf(format, args...))
+}
+
+func (logger AppLogger) Warn(args ...any) {
+	FieldEntry{logger: logger}.log(logLevelWarn, "W", fmt.Sprint(args...))
+}
+
+func (logger AppLogger) Warnf(format string, args ...any) {
+	FieldEntry{logger: logger}.log(logLevelWarn, "W", fmt.Sprintf(format, args...))
+}
+
+func (logger AppLogger) Error(args ...any) {
+	FieldEntry{logger: logger}.log(logLevelError, "E", fmt.Sprint(args...))
+}
+
+func (logger AppLogger) Errorf(format string, args ...any) {
+	FieldEntry{logger: logger}.log(logLevelError, "E", fmt.Sprintf(format, args...))
+}
+
+func (entry FieldEntry) WithField(key string, value any) FieldEntry {
+	fields := maps.Clone(entry.fields)
+	fields[key] = value
+	return FieldEntry{logger: entry.logger, fields: fields}
+}
+
+func (entry FieldEntry) Debug(args ...any) {
+	entry.log(logLevelDebug, "D", fmt.Sprint(args...))
+}
+
+func (entry FieldEntry) Info(args ...any) {
+	entry.log(logLevelInfo, "I", fmt.Sprint(args...))
+}
+
+func (entry FieldEntry) Warn(args ...any) {
+	entry.log(logLevelWarn, "W", fmt.Sprint(args...))
+}
+
+func (entry FieldEntry) Error(args ...any) {
+	entry.log(logLevelError, "E", fmt.Sprint(args...))
+}
+
+// Writes the line with the same template as the others, logchain knowing nothing of the fields.
+// The location is the one of the caller of the level method
+func (entry FieldEntry) log(level string, letter string, message string) {
+	if !entry.logger.enabled(level) {
+		return
+	}
+	_, file, line, _ := runtime.Caller(2)
+	var builder strings.Builder
+	entry.logger.line().Execute(&builder, map[string]any{
+		"timestamp":   time.Now().UTC().Format("2006-01-02 15:04:05.000"),
+		"levelLetter": letter,
+		"fileLine":    filepath.Base(file) + ":" + strconv.Itoa(line),
+		"msg":         message + formatFields(entry.fields),
+	})
+	builder.WriteByte('\n')
+	io.WriteString(entry.logger.out, builder.String())
+}
+
+// Fields as " key=value" sorted by key, the values holding spaces or quotes being quoted
+func formatFields(fields Fields) string {
+	var builder strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(fields)) {
+		value := fmt.Sprint(fields[key])
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		builder.WriteString(" " + key + "=" + value)
 	}
-	chainer := logchain.NewLogChainer(params)
-	return chainer.InitLogging()
+	return builder.String()
 }
 
 var Logger = initLogging()
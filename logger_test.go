@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// =============================================================================
+// LOGGER TESTS
+// =============================================================================
+
+// Test the colored template wraps each level in its color and aligns the messages
+func TestLogTemplate(t *testing.T) {
+	render := func(colored bool, letter string, fileLine string) string {
+		var buffer bytes.Buffer
+		tpl := template.Must(template.New("line").Parse(logTemplate(colored)))
+		tpl.Execute(&buffer, map[string]any{
+			"timestamp": "2024-05-01 10:30:00.000", "levelLetter": letter, "fileLine": fileLine, "msg": "hello",
+		})
+		return buffer.String()
+	}
+
+	if line := render(false, "E", "app.go:12"); strings.Contains(line, "\x1b") || !strings.HasSuffix(line, " E app.go:12 hello") {
+		t.Errorf("Expected a plain line, got %q", line)
+	}
+
+	for letter, color := range map[string]string{"E": ansiRed, "W": ansiYellow, "I": ansiGreen, "D": ansiGray} {
+		line := render(true, letter, "app.go:12")
+		if !strings.Contains(line, color+letter+ansiReset) || !strings.HasSuffix(line, color+"hello"+ansiReset) {
+			t.Errorf("Expected the %s level in its color, got %q", letter, line)
+		}
+	}
+
+	short, long := render(true, "I", "app.go:1"), render(true, "I", "allCatsHandlers.go:123")
+	if strings.Index(short, "hello") != strings.Index(long, "hello") {
+		t.Errorf("Expected aligned messages, got %q and %q", short, long)
+	}
+}
+
+// Test the colors are automatic for a terminal only
+func TestWantsLogColor(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to open a pipe: %v", err)
+	}
+	defer reader.Close()
+	defer writer.Close()
+
+	if wantsLogColor(logColorAuto, writer) {
+		t.Error("Expected no colors when piped")
+	}
+	if !wantsLogColor(logColorAlways, writer) {
+		t.Error("Expected forced colors")
+	}
+	if wantsLogColor(logColorNever, writer) {
+		t.Error("Expected colors turned off")
+	}
+}
+
+// Test the captured lines lose their console colors
+func TestLogRingStripsColors(t *testing.T) {
+	ring := NewLogRing(2)
+	ring.Write([]byte("2024-05-01 " + ansiGreen + "I" + ansiReset + " " + ansiGreen + "hello" + ansiReset + "\n"))
+
+	if lines := ring.Lines(); len(lines) != 1 || lines[0] != "2024-05-01 I hello" {
+		t.Errorf("Expected a plain line, got %q", lines)
+	}
+}
+
+// Test the fields are sorted and quoted only when needed
+func TestFormatFields(t *testing.T) {
+	fields := Fields{"name": "Toto le chat", "cat_id": "id1", "color": "", "weight": 4200}
+	if formatted, expected := formatFields(fields), ` cat_id=id1 color="" name="Toto le chat" weight=4200`; formatted != expected {
+		t.Errorf("Expected %q, got %q", expected, formatted)
+	}
+}
+
+// Test the lines with fields follow the level and point at their caller
+func TestFieldEntry(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.LogLevel, cfg.LogColor = logLevelInfo, logColorNever
+	setConfig(cfg)
+
+	var buffer bytes.Buffer
+	logger := AppLogger{out: &buffer}
+
+	logger.WithField("cat_id", "id1").Debug("Hidden")
+	logger.WithFields(Fields{"cat_id": "id1"}).WithField("name", "Toto").Info("Cat found")
+
+	line := strings.TrimSpace(buffer.String())
+	if strings.Contains(line, "Hidden") || strings.Count(line, "\n") != 0 {
+		t.Fatalf("Expected only the info line, got %q", buffer.String())
+	}
+	if !strings.Contains(line, " I logger_test.go:") || !strings.HasSuffix(line, "Cat found cat_id=id1 name=Toto") {
+		t.Errorf("Expected the fields after the message at the caller location, got %q", line)
+	}
+}
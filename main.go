@@ -1,22 +1,199 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 var version string = "0.0.0-local"
 
+// Makes sure the certificate and key are usable before listening
+func checkTLSKeyPair(certFile, keyFile string) error {
+	_, err := tls.LoadX509KeyPair(certFile, keyFile)
+	return err
+}
+
+// Connects the configured backend, the in-memory store is already there
+func initStore(cfg Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if cfg.Store == storeRedis {
+		repo, err := NewRedisRepo(ctx, cfg.RedisAddr)
+		if err != nil {
+			return err
+		}
+		catsStore = retryingStore{repo}
+		Logger.Infof("Using the redis store at %s", cfg.RedisAddr)
+	}
+
+	if cfg.Seed {
+		if err := seedStore(ctx, catsStore); err != nil {
+			return fmt.Errorf("unable to seed the store: %w", err)
+		}
+		Logger.Infof("Seeded the store with %d example cats", len(seedCats))
+	}
+	return nil
+}
+
+// Loads what the requests need, the server is not ready before.
+// The spec only serves the docs, the API works without it
+func initialize(cfg Config) error {
+	if err := initStore(cfg); err != nil {
+		return fmt.Errorf("unable to init the store: %w", err)
+	}
+	if _, err := reloadSpec(); err != nil {
+		Logger.Warn("The spec is unavailable, /openapi.json and /swagger/ answer 503: ", err)
+	}
+	return nil
+}
+
+// Blocks until an interrupt or termination signal, then stops the server gracefully
+func waitForShutdown(server *http.Server, timeout time.Duration, done chan<- struct{}) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	stopAccepting(currentConfig().ShutdownDelay)
+	if err := drainServer(server, timeout); err != nil {
+		Logger.Error("Graceful shutdown failed: ", err)
+	}
+	close(done)
+}
+
+// Answers 503 to the new requests, and to the readiness probe, for the delay before the drain
+// so the load balancers stop routing here while the listener is still open
+func stopAccepting(delay time.Duration) {
+	shuttingDown.Store(true)
+	if delay > 0 {
+		Logger.Infof("Refusing the new requests for %v before draining", delay)
+		time.Sleep(delay)
+	}
+}
+
+// Lets the in-flight requests complete, then force-closes the connections left after the timeout
+func drainServer(server *http.Server, timeout time.Duration) error {
+	Logger.Infof("Shutting down the server, draining %d requests", inFlightRequests.Load())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		Logger.Warnf("%d requests still running after %v, closing them", inFlightRequests.Load(), timeout)
+		server.Close()
+	}
+	return err
+}
+
 func main() {
+	command, args, err := parseCommand(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if command == commandConvert {
+		if err := runConvert(args); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	serve(args)
+}
+
+// Runs the API until a shutdown signal, the args being the flags
+func serve(args []string) {
+	cfg := defaultConfig()
+	registerFlags(flag.CommandLine, &cfg)
+	if err := loadConfig(flag.CommandLine, &cfg, args, os.LookupEnv); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.validate(); err != nil {
+		log.Fatal(err)
+	}
+	setConfig(cfg)
+	logRing.Resize(cfg.LogBuffer)
+	idGenerator = newIDGenerator(cfg.IDStrategy)
+	if cfg.Deterministic && cfg.IDStrategy == idStrategyUUID {
+		idGenerator = newSeededGenerator(deterministicSeed)
+	}
+	watchReloads(args)
+	startWebhookWorkers()
+
 	Logger.Info("Starting the server")
+	Logger.Info("Config: ", describeConfig(flag.CommandLine))
+
+	useTLS := cfg.TLSCert != "" && cfg.TLSKey != ""
+	if useTLS {
+		if err := checkTLSKeyPair(cfg.TLSCert, cfg.TLSKey); err != nil {
+			log.Fatalf("Unable to load the TLS certificate/key pair: %v", err)
+		}
+	} else if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		Logger.Warn("Both --tls-cert and --tls-key are needed for HTTPS, falling back to HTTP")
+	}
+	if cfg.Deterministic {
+		Logger.Warn("--deterministic makes the IDs predictable, it is meant for the tests and must never be enabled in a real deployment")
+	}
 
+	shutdownTracing, err := initTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Unable to init the tracing: %v", err)
+	}
+
+	// Listen first so the probes answer during the initialization
+	initializing.Store(true)
 	app := newApp()
 
-	server := http.Server{
-		Addr:    ":8080",
+	server := &http.Server{
+		Addr:    cfg.Addr,
 		Handler: app,
 	}
 
-	log.Printf("HTTP server listening on %v", server.Addr)
-	server.ListenAndServe()
+	listener, err := listen(cfg.Addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go waitForShutdown(server, cfg.ShutdownTimeout, done)
+
+	served := make(chan error, 1)
+	go func() {
+		if useTLS {
+			log.Printf("HTTPS server listening on %v", server.Addr)
+			served <- server.ServeTLS(listener, cfg.TLSCert, cfg.TLSKey)
+		} else {
+			log.Printf("HTTP server listening on %v", server.Addr)
+			served <- server.Serve(listener)
+		}
+	}()
+
+	if err := initialize(cfg); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Deterministic {
+		catsStore = sortedStore{catsStore}
+	}
+	if tracingConfigured(os.LookupEnv) {
+		catsStore = tracedStore{catsStore}
+	}
+	initializing.Store(false)
+	Logger.Info("Ready to serve")
+
+	if err := <-served; err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		Logger.Warn("Unable to flush the traces: ", err)
+	}
+	Logger.Info("Server stopped")
 }
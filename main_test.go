@@ -2,12 +2,21 @@ package main
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // =============================================================================
@@ -16,18 +25,8 @@ import (
 
 // Test actual createCat function
 func TestActualCreateCat(t *testing.T) {
-	// Save original database state
-	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
-		originalDB[k] = v
-	}
-	defer func() {
-		// Restore original state
-		catsDatabase = originalDB
-	}()
-
 	// Clear database for test
-	catsDatabase = make(map[string]Cat)
+	store := NewMemoryRepo()
 
 	// Create test cat
 	testCat := Cat{
@@ -46,31 +45,46 @@ func TestActualCreateCat(t *testing.T) {
 	req.Header.Set("Content-Type", "application/json")
 
 	// Call actual function
-	statusCode, response := createCat(req)
+	statusCode, response := createCat(withStore(store, req))
 
 	// Assertions
 	if statusCode != http.StatusCreated {
 		t.Errorf("Expected status code %d, got %d", http.StatusCreated, statusCode)
 	}
 
-	// Check response is a string (cat ID)
-	responseStr, ok := response.(string)
+	// Check response is the created cat along with its location
+	created, ok := response.(Response)
+	if !ok {
+		t.Errorf("Expected Response, got %T", response)
+		return
+	}
+
+	createdCat, ok := created.Body.(Cat)
 	if !ok {
-		t.Errorf("Expected string response, got %T", response)
+		t.Errorf("Expected Cat body, got %T", created.Body)
 		return
 	}
 
+	responseStr := createdCat.ID
 	if responseStr == "" {
 		t.Error("Expected non-empty cat ID")
 	}
 
+	if location := created.Header.Get("Location"); location != "/api/cats/"+responseStr {
+		t.Errorf("Expected Location '/api/cats/%s', got '%s'", responseStr, location)
+	}
+
+	if createdCat.Name != testCat.Name {
+		t.Errorf("Expected created cat name %s, got %s", testCat.Name, createdCat.Name)
+	}
+
 	// Check cat was saved to database
-	if len(catsDatabase) != 1 {
-		t.Errorf("Expected 1 cat in database, got %d", len(catsDatabase))
+	if len(storedCats(store)) != 1 {
+		t.Errorf("Expected 1 cat in database, got %d", len(storedCats(store)))
 	}
 
 	// Verify the cat in database
-	savedCat, exists := catsDatabase[responseStr]
+	savedCat, exists := storedCats(store)[responseStr]
 	if !exists {
 		t.Error("Created cat not found in database")
 		return
@@ -106,32 +120,20 @@ func TestActualCreateCatInvalidJSON(t *testing.T) {
 
 // Test actual deleteCat function with existing cat
 func TestActualDeleteCatExists(t *testing.T) {
-	// Save original database state
-	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
-		originalDB[k] = v
-	}
-	defer func() {
-		// Restore original state
-		catsDatabase = originalDB
-	}()
-
 	// Set up test cat in database
 	testCatID := "test-cat-id-123"
 	testCat := Cat{
 		Name: "TestCat",
 		ID:   testCatID,
 	}
-	catsDatabase = map[string]Cat{
-		testCatID: testCat,
-	}
+	store := NewMemoryRepo(testCat)
 
 	// Create request with path parameter
 	req := httptest.NewRequest("DELETE", "/api/cats/"+testCatID, nil)
 	req.SetPathValue("catId", testCatID)
 
 	// Call actual function
-	statusCode, response := deleteCat(req)
+	statusCode, response := deleteCat(withStore(store, req))
 
 	// Assertions
 	if statusCode != http.StatusNoContent {
@@ -143,29 +145,19 @@ func TestActualDeleteCatExists(t *testing.T) {
 	}
 
 	// Check cat was deleted from database
-	if _, exists := catsDatabase[testCatID]; exists {
+	if _, exists := storedCats(store)[testCatID]; exists {
 		t.Error("Cat should have been deleted from database")
 	}
 
-	if len(catsDatabase) != 0 {
-		t.Errorf("Expected empty database, got %d items", len(catsDatabase))
+	if len(storedCats(store)) != 0 {
+		t.Errorf("Expected empty database, got %d items", len(storedCats(store)))
 	}
 }
 
 // Test actual deleteCat function with non-existent cat
 func TestActualDeleteCatNotExists(t *testing.T) {
-	// Save original database state
-	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
-		originalDB[k] = v
-	}
-	defer func() {
-		// Restore original state
-		catsDatabase = originalDB
-	}()
-
 	// Clear database
-	catsDatabase = make(map[string]Cat)
+	store := NewMemoryRepo()
 
 	nonExistentID := "non-existent-cat-id"
 
@@ -174,7 +166,7 @@ func TestActualDeleteCatNotExists(t *testing.T) {
 	req.SetPathValue("catId", nonExistentID)
 
 	// Call actual function
-	statusCode, response := deleteCat(req)
+	statusCode, response := deleteCat(withStore(store, req))
 
 	// Assertions
 	if statusCode != http.StatusNotFound {
@@ -188,18 +180,8 @@ func TestActualDeleteCatNotExists(t *testing.T) {
 
 // Test complete CRUD operations
 func TestActualCRUDOperations(t *testing.T) {
-	// Save original database state
-	originalDB := make(map[string]Cat)
-	for k, v := range catsDatabase {
-		originalDB[k] = v
-	}
-	defer func() {
-		// Restore original state
-		catsDatabase = originalDB
-	}()
-
 	// Clear database
-	catsDatabase = make(map[string]Cat)
+	store := NewMemoryRepo()
 
 	// Create cat
 	testCat := Cat{
@@ -212,18 +194,18 @@ func TestActualCRUDOperations(t *testing.T) {
 	createReq := httptest.NewRequest("POST", "/api/cats", bytes.NewBuffer(jsonData))
 	createReq.Header.Set("Content-Type", "application/json")
 
-	statusCode, response := createCat(createReq)
+	statusCode, response := createCat(withStore(store, createReq))
 	if statusCode != http.StatusCreated {
 		t.Fatalf("Failed to create cat: status %d", statusCode)
 	}
 
-	catID := response.(string)
+	catID := response.(Response).Body.(Cat).ID
 
 	// Verify cat exists with getCat
 	getReq := httptest.NewRequest("GET", "/api/cats/"+catID, nil)
 	getReq.SetPathValue("catId", catID)
 
-	statusCode, _ = getCat(getReq)
+	statusCode, _ = getCat(withStore(store, getReq))
 	if statusCode != http.StatusOK {
 		t.Errorf("Failed to get cat: status %d", statusCode)
 	}
@@ -232,7 +214,7 @@ func TestActualCRUDOperations(t *testing.T) {
 	deleteReq := httptest.NewRequest("DELETE", "/api/cats/"+catID, nil)
 	deleteReq.SetPathValue("catId", catID)
 
-	statusCode, _ = deleteCat(deleteReq)
+	statusCode, _ = deleteCat(withStore(store, deleteReq))
 	if statusCode != http.StatusNoContent {
 		t.Errorf("Failed to delete cat: status %d", statusCode)
 	}
@@ -241,12 +223,73 @@ func TestActualCRUDOperations(t *testing.T) {
 	getReq2 := httptest.NewRequest("GET", "/api/cats/"+catID, nil)
 	getReq2.SetPathValue("catId", catID)
 
-	statusCode, _ = getCat(getReq2)
+	statusCode, _ = getCat(withStore(store, getReq2))
 	if statusCode != http.StatusNotFound {
 		t.Errorf("Expected cat to be deleted, got status %d", statusCode)
 	}
 }
 
+// =============================================================================
+// STORE TESTS
+// =============================================================================
+
+// Test the store starts empty unless seeding is asked for
+func TestInitStoreSeed(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+	if err := initStore(defaultConfig()); err != nil {
+		t.Fatalf("Failed to init the store: %v", err)
+	}
+	if len(storedCats(catsStore)) != 0 {
+		t.Errorf("Expected an empty store, got %v", storedCats(catsStore))
+	}
+
+	cfg := defaultConfig()
+	cfg.Seed = true
+	if err := initStore(cfg); err != nil {
+		t.Fatalf("Failed to init the store: %v", err)
+	}
+
+	stored := storedCats(catsStore)
+	if len(stored) != len(seedCats) {
+		t.Fatalf("Expected %d seeded cats, got %d", len(seedCats), len(stored))
+	}
+	for _, seed := range seedCats {
+		cat := stored[seed.ID]
+		if cat.Name != seed.Name || cat.CreatedAt.IsZero() {
+			t.Errorf("Expected the seeded %s with timestamps, got %+v", seed.Name, cat)
+		}
+	}
+	if !seedCats[0].CreatedAt.IsZero() {
+		t.Error("Seeding should not alter the example cats")
+	}
+}
+
+// Test an unreachable redis aborts the startup
+func TestRedisStoreUnreachable(t *testing.T) {
+	originalStore := catsStore
+	defer func() {
+		catsStore = originalStore
+	}()
+
+	cfg := currentConfig()
+	cfg.Store = storeRedis
+	cfg.RedisAddr = "127.0.0.1:1"
+
+	if err := initStore(cfg); err == nil {
+		t.Error("Expected an error for an unreachable redis")
+	}
+	if catsStore != originalStore {
+		t.Error("The store should be unchanged on error")
+	}
+}
+
 // =============================================================================
 // YML2JSON FUNCTION TESTS
 // =============================================================================
@@ -307,28 +350,21 @@ array:
   - item3
 `
 
-	// Save original file
-	originalExists := false
-	var originalContent []byte
-	if content, err := os.ReadFile("openapi.yml"); err == nil {
-		originalExists = true
-		originalContent = content
-	}
-
-	// Write test YAML
-	err := os.WriteFile("openapi.yml", []byte(simpleYAML), 0644)
+	// Write test YAML as an external spec
+	specFile := filepath.Join(t.TempDir(), "openapi.yml")
+	err := os.WriteFile(specFile, []byte(simpleYAML), 0644)
 	if err != nil {
 		t.Fatalf("Failed to write test YAML: %v", err)
 	}
 
-	// Restore original file after test
+	// Restore the embedded spec after test
+	originalConfig := currentConfig()
 	defer func() {
-		if originalExists {
-			os.WriteFile("openapi.yml", originalContent, 0644)
-		} else {
-			os.Remove("openapi.yml")
-		}
+		setConfig(originalConfig)
 	}()
+	cfg := currentConfig()
+	cfg.SpecFile = specFile
+	setConfig(cfg)
 
 	// Capture stdout
 	old := os.Stdout
@@ -464,3 +500,60 @@ func TestMainWorkflow(t *testing.T) {
 		t.Errorf("Expected server address :8080, got %s", expectedAddr)
 	}
 }
+
+// =============================================================================
+// TLS TESTS
+// =============================================================================
+
+// Writes a self-signed certificate and its key as PEM files into a temp dir
+func writeSelfSignedPair(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+// Test a valid key pair is accepted
+func TestCheckTLSKeyPairValid(t *testing.T) {
+	certFile, keyFile := writeSelfSignedPair(t)
+
+	if err := checkTLSKeyPair(certFile, keyFile); err != nil {
+		t.Errorf("Expected a valid key pair, got %v", err)
+	}
+}
+
+// Test missing or mismatched files are reported at startup
+func TestCheckTLSKeyPairInvalid(t *testing.T) {
+	certFile, _ := writeSelfSignedPair(t)
+	_, otherKeyFile := writeSelfSignedPair(t)
+
+	if err := checkTLSKeyPair("missing-cert.pem", "missing-key.pem"); err == nil {
+		t.Error("Expected an error for missing files")
+	}
+
+	if err := checkTLSKeyPair(certFile, otherKeyFile); err == nil {
+		t.Error("Expected an error for a key not matching the certificate")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Behavior wrapped around a handler, like the access log or the body limit
+type Middleware func(http.Handler) http.Handler
+
+// Middlewares in the order a request goes through them, the first one being the outermost
+type Chain []Middleware
+
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain(middlewares)
+}
+
+// New chain going on with more middlewares, inside the ones of this chain
+func (chain Chain) Append(middlewares ...Middleware) Chain {
+	return append(append(Chain{}, chain...), middlewares...)
+}
+
+// Wraps the handler, the last middleware of the chain being the closest to it
+func (chain Chain) Then(handler http.Handler) http.Handler {
+	for idx := len(chain) - 1; idx >= 0; idx-- {
+		handler = chain[idx](handler)
+	}
+	return handler
+}
+
+// Middlewares of the app from the outermost. The order matters: the recovery catches the panics
+// of all the others, the access log sees the 503 of the limits, the bodies are logged as bounded
+// and uncompressed, the trace knows the route the slashes were normalized for and the timeout
+// only bounds the handler
+func appMiddlewares(router *http.ServeMux) Chain {
+	maxConcurrent := currentConfig().MaxConcurrent
+	return NewChain(
+		recoverPanics,
+		countInFlight,
+		logReq,
+		func(next http.Handler) http.Handler { return limitConcurrency(maxConcurrent, next) },
+		awaitInit,
+		refuseWhenStopping,
+		compressResponses,
+		limitBody,
+		logBodies,
+		normalizeSlashes,
+		func(next http.Handler) http.Handler { return traceRequests(router, next) },
+		timeoutRequests,
+	)
+}
+
+// Answers a 500 for a panic escaping a middleware, the handlers recover their own.
+// Once the response started only the log tells about it
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			recov := recover()
+			if recov == nil {
+				return
+			}
+			if recov == http.ErrAbortHandler {
+				panic(recov)
+			}
+			Logger.Errorf("Recovering from a panic serving '%s': %v", r.URL.Path, recov)
+			if recorder.code == 0 {
+				writeResponse(recorder, r, http.StatusInternalServerError, ErrorBody{Error: localize(r, "internal_error")})
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// Middleware noting its name on the way in and out
+func tracingMiddleware(name string, calls *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+" in")
+			next.ServeHTTP(w, r)
+			*calls = append(*calls, name+" out")
+		})
+	}
+}
+
+// =============================================================================
+// MIDDLEWARE TESTS
+// =============================================================================
+
+// Test the first middleware of a chain is the outermost, an appended one the innermost
+func TestChainOrder(t *testing.T) {
+	var calls []string
+	chain := NewChain(tracingMiddleware("first", &calls), tracingMiddleware("second", &calls))
+	longer := chain.Append(tracingMiddleware("third", &calls))
+
+	handler := longer.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	expected := []string{"first in", "second in", "third in", "handler", "third out", "second out", "first out"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("Expected %v, got %v", expected, calls)
+	}
+	if len(chain) != 2 {
+		t.Errorf("Expected the original chain left alone, got %d middlewares", len(chain))
+	}
+}
+
+// Test a panic out of a middleware answers a 500 rather than dropping the connection
+func TestRecoverPanics(t *testing.T) {
+	panicking := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("broken middleware")
+		})
+	}
+	handler := NewChain(recoverPanics, panicking).Then(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	var body ErrorBody
+	if json.NewDecoder(rec.Body).Decode(&body); rec.Code != http.StatusInternalServerError || body.Error != http.StatusText(http.StatusInternalServerError) {
+		t.Errorf("Expected a 500, got %d with %+v", rec.Code, body)
+	}
+}
@@ -1,16 +1,325 @@
 package main
 
-import "net/http"
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
 
 func getCat(req *http.Request) (int, any) {
 	catID := req.PathValue("catId")
-	Logger.Info("Getting the cat: ", catID)
+	Logger.WithField("cat_id", catID).Info("Getting the cat")
 
-	if cat, found := catsDatabase[catID]; found {
-		Logger.Info("Cat found")
-		return http.StatusOK, cat
+	params := newQueryParams(req)
+	fields := parseCatFields(params)
+	if err := params.Err(); err != nil {
+		Logger.Info("Invalid projection: ", err)
+		return errorResponse(req, err)
+	}
+
+	if cat, err := storeOf(req.Context()).Get(req.Context(), catID); err == nil {
+		Logger.WithField("cat_id", catID).Info("Cat found")
+		header := http.Header{}
+		var body any = cat
+		etag := ""
+		if fields != nil {
+			body = projectCat(cat, fields)
+		} else {
+			// The tag is the one of the whole cat, not of a projection
+			etag = catETag(cat)
+			header.Set("ETag", etag)
+		}
+
+		lastModified := catLastModified(cat)
+		if !lastModified.IsZero() {
+			header.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		}
+		if notModified(req, etag, lastModified) {
+			Logger.WithField("cat_id", catID).Info("Cat not modified")
+			return http.StatusNotModified, Response{Header: header}
+		}
+		return http.StatusOK, Response{Header: header, Body: body}
+	} else if err == ErrNotFound {
+		return catNotFound(req, catID)
 	} else {
-		Logger.Info("Cat not found")
-		return http.StatusNotFound, "Cat not found"
+		return storeFailure(req, err)
+	}
+}
+
+// JSON names of the Cat fields, the ones a projection can ask for
+var catFieldNames = []string{"id", "name", "birthDate", "color", "breed", "weightGrams", "createdAt", "updatedAt"}
+
+// Reads the comma separated list of the `fields` param, named like the responses. Nil without
+// a projection or with an unknown field, recorded in the params
+func parseCatFields(params *QueryParams) []string {
+	if !params.Has("fields") {
+		return nil
+	}
+	fields := []string{}
+	for _, field := range strings.Split(params.String("fields", ""), ",") {
+		field = strings.TrimSpace(field)
+		if currentConfig().FieldNaming == namingSnake {
+			field = snakeToCamel(field)
+		}
+		if !slices.Contains(catFieldNames, field) {
+			params.Invalid("fields", "unknown_projection", field, strings.Join(catFieldNames, ", "))
+			return nil
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Keeps only the given fields of the cat, the unset ones stay omitted
+func projectCat(cat Cat, fields []string) map[string]json.RawMessage {
+	encoded, _ := json.Marshal(cat)
+	var all map[string]json.RawMessage
+	json.Unmarshal(encoded, &all)
+
+	projected := map[string]json.RawMessage{}
+	for _, field := range fields {
+		if value, found := all[field]; found {
+			projected[field] = value
+		}
+	}
+	return projected
+}
+
+// Modification time at the HTTP date precision, zero when unknown
+func catLastModified(cat Cat) time.Time {
+	lastModified := cat.UpdatedAt
+	if lastModified.IsZero() {
+		lastModified = cat.CreatedAt
+	}
+	return lastModified.UTC().Truncate(time.Second)
+}
+
+// Whether the client copy is still fresh, If-None-Match taking precedence over If-Modified-Since
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := req.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagListMatches(ifNoneMatch, etag, true)
+	}
+	return !lastModified.IsZero() && notModifiedSince(req, lastModified)
+}
+
+// Whether the client copy, dated by If-Modified-Since, is still fresh
+func notModifiedSince(req *http.Request, lastModified time.Time) bool {
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// Applies a JSON Merge Patch (RFC 7386): a null field is cleared, an omitted one is left unchanged
+func applyMergePatch(cat *Cat, patch map[string]json.RawMessage) error {
+	if raw, found := patch["name"]; found {
+		if isJSONNull(raw) {
+			return invalidInput("name_cleared")
+		}
+		if err := json.Unmarshal(raw, &cat.Name); err != nil {
+			return invalidInput("not_string", "name")
+		}
+	}
+
+	clearableFields := map[string]*string{
+		"color":     &cat.Color,
+		"birthDate": &cat.BirthDate,
+		"breed":     &cat.Breed,
+	}
+	for field, target := range clearableFields {
+		raw, found := patch[field]
+		if !found {
+			continue
+		}
+		if isJSONNull(raw) {
+			*target = ""
+		} else if err := json.Unmarshal(raw, target); err != nil {
+			return invalidInput("not_string", field)
+		}
+	}
+
+	if raw, found := patch["weightGrams"]; found {
+		if isJSONNull(raw) {
+			cat.WeightGrams = 0
+		} else if err := json.Unmarshal(raw, &cat.WeightGrams); err != nil {
+			return invalidInput("not_integer", "weightGrams")
+		}
+	}
+	return nil
+}
+
+// Body of a PATCH, a JSON Patch or else a merge patch, applied on the cat each attempt of the write reads
+type catPatch struct {
+	operations []PatchOperation
+	// A map keeps the difference between a null and an omitted field
+	merge map[string]json.RawMessage
+}
+
+// Reads the body of a PATCH in the format told by its Content-Type
+func readPatchBody(req *http.Request) (catPatch, int, any, bool) {
+	var patch catPatch
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == jsonPatchContentType {
+		if err := decodeJSON(req.Body, &patch.operations); err != nil {
+			Logger.Info("Unable to parse the JSON Patch for cat patch")
+			code, failure := decodeFailure(req, err)
+			return patch, code, failure, true
+		}
+		return patch, 0, nil, false
+	}
+
+	if err := decodeJSON(req.Body, &patch.merge); err != nil {
+		Logger.Info("Unable to parse the JSON input for cat patch")
+		code, failure := decodeFailure(req, err)
+		return patch, code, failure, true
+	}
+	if err := checkPatchFields(patch.merge); err != nil {
+		code, failure := decodeFailure(req, err)
+		return patch, code, failure, true
+	}
+	return patch, 0, nil, false
+}
+
+func (patch catPatch) apply(req *http.Request, cat *Cat) (int, any, bool) {
+	if patch.merge == nil {
+		return applyJSONPatch(req, cat, patch.operations)
+	}
+	if err := applyMergePatch(cat, patch.merge); err != nil {
+		Logger.Info("Invalid cat patch: ", err)
+		return http.StatusBadRequest, localizeError(req, err), true
+	}
+	return 0, nil, false
+}
+
+func patchCat(req *http.Request) (int, any) {
+	catID := req.PathValue("catId")
+	Logger.WithField("cat_id", catID).Info("Patching the cat")
+
+	// A missing cat is answered before a faulty body
+	if _, err := storeOf(req.Context()).Get(req.Context(), catID); err == ErrNotFound {
+		return catNotFound(req, catID)
+	} else if err != nil {
+		return storeFailure(req, err)
+	}
+	patch, code, failure, failed := readPatchBody(req)
+	if failed {
+		return code, failure
+	}
+
+	// The precondition is checked against the cat the write replaces, two patches of the same
+	// version cannot both go through
+	var cat Cat
+	err := storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		current, found := stored[catID]
+		if !found {
+			code, failure := catNotFound(req, catID)
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+		if code, failure, failed := failedPrecondition(req, &current); failed {
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+
+		// Working on a copy, the stored cat is untouched on error
+		cat = current
+		if code, failure, failed := patch.apply(req, &cat); failed {
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+		applyInputPolicies(&cat)
+		if verr := requestValidator(req).Validate(cat); verr.HasErrors() {
+			Logger.WithFields(Fields{"cat_id": catID, "errors": verr.Error()}).Info("Invalid patched cat")
+			return StoreChange{}, refusedWrite{http.StatusUnprocessableEntity, verr}
+		}
+
+		cat.UpdatedAt = time.Now().UTC()
+		return StoreChange{Save: []Cat{cat}}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
+	}
+	Logger.WithFields(cat.LogFields()).Info("Cat patched in the DB")
+	return http.StatusOK, Response{Header: http.Header{"Etag": {catETag(cat)}}, Body: cat}
+}
+
+// Creates the cat under the given ID or replaces it whole, If-None-Match: * creating only
+// and If-Match only replacing the version read by the client
+func putCat(req *http.Request) (int, any) {
+	catID := req.PathValue("catId")
+	Logger.WithField("cat_id", catID).Info("Putting the cat")
+
+	var cat Cat
+	if err := decodeJSON(req.Body, &cat); err != nil {
+		Logger.Info("Unable to parse the JSON input for cat put")
+		return decodeFailure(req, err)
+	}
+	if cat.ID != "" && cat.ID != catID {
+		return http.StatusBadRequest, localize(req, "id_mismatch")
+	}
+	cat.ID = catID
+	applyInputPolicies(&cat)
+
+	if verr := requestValidator(req).Validate(cat); verr.HasErrors() {
+		Logger.WithFields(Fields{"cat_id": catID, "errors": verr.Error()}).Info("Invalid cat")
+		return http.StatusUnprocessableEntity, verr
+	}
+
+	// Everything is checked against the store the write lands in: two creations with
+	// If-None-Match: * or two replacements of the same If-Match version cannot both go through
+	var current *Cat
+	var evictedIDs []string
+	defer lockEventOrder()()
+	err := storeOf(req.Context()).Transact(req.Context(), func(stored map[string]Cat) (StoreChange, error) {
+		current, evictedIDs = nil, nil
+		if existing, found := stored[catID]; found {
+			current = &existing
+		}
+		if code, failure, failed := failedPrecondition(req, current); failed {
+			return StoreChange{}, refusedWrite{code, failure}
+		}
+		others := []Cat{}
+		for otherID, other := range stored {
+			if otherID != catID {
+				others = append(others, other)
+			}
+		}
+
+		// Client timestamps are ignored
+		cat.UpdatedAt = time.Now().UTC()
+		if current != nil {
+			cat.CreatedAt = current.CreatedAt
+			if code, refusal, refused := refuseDuplicateCat(req, others, cat); refused {
+				return StoreChange{}, refusedWrite{code, refusal}
+			}
+			return StoreChange{Save: []Cat{cat}}, nil
+		}
+
+		cat.CreatedAt = cat.UpdatedAt
+		if code, refusal, refused := refuseNewCat(req, others, cat); refused {
+			return StoreChange{}, refusedWrite{code, refusal}
+		}
+		// Only a new cat can evict
+		evictedIDs = catsToEvict(others)
+		return StoreChange{Save: []Cat{cat}, Delete: evictedIDs}, nil
+	})
+	if err != nil {
+		return transactFailure(req, err)
+	}
+	announceEvictions(evictedIDs)
+
+	header := http.Header{"Etag": {catETag(cat)}}
+	if current != nil {
+		Logger.WithFields(cat.LogFields()).Info("Cat replaced in the DB")
+		return http.StatusOK, Response{Header: header, Body: cat}
 	}
+	Logger.WithFields(cat.LogFields()).Info("Cat saved into the DB")
+	publishCatEvent(eventCreated, catID, &cat)
+	header.Set("Location", apiPath("/cats/"+catID))
+	return http.StatusCreated, Response{Header: header, Body: cat}
 }
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// ONE CAT HANDLERS TESTS
+// =============================================================================
+
+// Test getCat dates the cat and answers 304 when the client copy is fresh
+func TestGetCatLastModified(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 10, 30, 0, 500_000_000, time.UTC)
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", CreatedAt: updatedAt.Add(-time.Hour), UpdatedAt: updatedAt},
+		Cat{ID: "id2", Name: "Felix"},
+	)
+	app := newAppWithStore(store)
+
+	tests := []struct {
+		name          string
+		catID         string
+		modifiedSince string
+		expectedCode  int
+	}{
+		{"no condition", "id1", "", http.StatusOK},
+		{"same second", "id1", "Wed, 01 May 2024 10:30:00 GMT", http.StatusNotModified},
+		{"later", "id1", "Wed, 01 May 2024 11:00:00 GMT", http.StatusNotModified},
+		{"earlier", "id1", "Wed, 01 May 2024 10:29:59 GMT", http.StatusOK},
+		{"invalid date", "id1", "yesterday", http.StatusOK},
+		{"unknown time", "id2", "Wed, 01 May 2024 10:30:00 GMT", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/cats/"+test.catID, nil)
+			if test.modifiedSince != "" {
+				req.Header.Set("If-Modified-Since", test.modifiedSince)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", test.expectedCode, rec.Code)
+			}
+
+			lastModified := rec.Header().Get("Last-Modified")
+			if test.catID == "id2" && lastModified != "" {
+				t.Errorf("Expected no Last-Modified for an unknown time, got %s", lastModified)
+			}
+			if test.catID == "id1" && lastModified != "Wed, 01 May 2024 10:30:00 GMT" {
+				t.Errorf("Expected Last-Modified at the second, got '%s'", lastModified)
+			}
+
+			if test.expectedCode == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("Expected no body, got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+// Test getCat returns only the requested fields
+func TestGetCatFields(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto", Color: "Grey"})
+	app := newAppWithStore(store)
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expected     map[string]string
+	}{
+		{"all by default", "", http.StatusOK, map[string]string{"id": "id1", "name": "Toto", "color": "Grey"}},
+		{"subset", "?fields=name,color", http.StatusOK, map[string]string{"name": "Toto", "color": "Grey"}},
+		{"spaces", "?fields=name,%20id", http.StatusOK, map[string]string{"name": "Toto", "id": "id1"}},
+		{"unset field", "?fields=birthDate", http.StatusOK, map[string]string{}},
+		{"unknown field", "?fields=name,owner", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1"+test.query, nil))
+
+			if rec.Code != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d", test.expectedCode, rec.Code)
+			}
+			if test.expected == nil {
+				return
+			}
+
+			var fields map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&fields); err != nil {
+				t.Fatalf("Expected a JSON object: %v", err)
+			}
+			if len(fields) != len(test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, fields)
+			}
+			for field, value := range test.expected {
+				if fields[field] != value {
+					t.Errorf("Expected %s '%s', got '%s'", field, value, fields[field])
+				}
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/id1?fields=name,owner", nil))
+	var message string
+	json.NewDecoder(rec.Body).Decode(&message)
+	if !strings.Contains(message, "Invalid query parameter: fields 'name,owner' has the unknown field 'owner'") {
+		t.Errorf("Expected the unknown field reported like the other query parameters, got %q", message)
+	}
+}
+
+// =============================================================================
+// MERGE PATCH TESTS
+// =============================================================================
+
+// Test null clears a field while an omitted field is left unchanged
+func TestPatchCatMergeSemantics(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		expected Cat
+	}{
+		{"empty patch", `{}`, Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"}},
+		{"null color", `{"color": null}`, Cat{ID: "id1", Name: "Toto", BirthDate: "2023-04-16"}},
+		{"null birthDate", `{"birthDate": null}`, Cat{ID: "id1", Name: "Toto", Color: "Grey"}},
+		{"set color only", `{"color": "Black"}`, Cat{ID: "id1", Name: "Toto", Color: "Black", BirthDate: "2023-04-16"}},
+		{"rename and clear", `{"name": "Felix", "color": null}`, Cat{ID: "id1", Name: "Felix", BirthDate: "2023-04-16"}},
+		{"set weight", `{"weightGrams": 4200}`, Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16", WeightGrams: 4200}},
+		{"null weight", `{"weightGrams": null}`, Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryRepo(
+				Cat{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16"},
+			)
+
+			req := httptest.NewRequest("PATCH", "/api/cats/id1", strings.NewReader(test.patch))
+			req.SetPathValue("catId", "id1")
+
+			statusCode, response := patchCat(withStore(store, req))
+			if statusCode != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d (%v)", http.StatusOK, statusCode, response)
+			}
+
+			// The update time is checked apart
+			patched := response.(Response).Body.(Cat)
+			if patched.UpdatedAt.IsZero() {
+				t.Error("Expected the update time to be set")
+			}
+			if storedCats(store)["id1"] != patched {
+				t.Errorf("Expected stored cat %+v, got %+v", patched, storedCats(store)["id1"])
+			}
+
+			patched.UpdatedAt = time.Time{}
+			if patched != test.expected {
+				t.Errorf("Expected response %+v, got %+v", test.expected, patched)
+			}
+		})
+	}
+}
+
+// Test invalid patches are rejected without touching the stored cat
+func TestPatchCatErrors(t *testing.T) {
+	stored := Cat{ID: "id1", Name: "Toto", Color: "Grey"}
+	store := NewMemoryRepo(stored)
+
+	tests := []struct {
+		name         string
+		catID        string
+		patch        string
+		expectedCode int
+	}{
+		{"null name", "id1", `{"name": null, "color": "Black"}`, http.StatusBadRequest},
+		{"wrong type", "id1", `{"color": 42}`, http.StatusBadRequest},
+		{"invalid JSON", "id1", `{ invalid json }`, http.StatusBadRequest},
+		{"not an object", "id1", `["color"]`, http.StatusBadRequest},
+		{"unknown cat", "unknown-id", `{"color": "Black"}`, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/api/cats/"+test.catID, strings.NewReader(test.patch))
+			req.SetPathValue("catId", test.catID)
+
+			statusCode, _ := patchCat(withStore(store, req))
+			if statusCode != test.expectedCode {
+				t.Errorf("Expected status code %d, got %d", test.expectedCode, statusCode)
+			}
+
+			if storedCats(store)["id1"] != stored {
+				t.Errorf("Stored cat should be unchanged, got %+v", storedCats(store)["id1"])
+			}
+		})
+	}
+}
+
+// Test the patched cat goes through the same validation
+func TestPatchCatValidationErrors(t *testing.T) {
+	stored := Cat{ID: "id1", Name: "Toto"}
+	store := NewMemoryRepo(stored)
+
+	req := httptest.NewRequest("PATCH", "/api/cats/id1", strings.NewReader(`{"name": "", "birthDate": "1997"}`))
+	req.SetPathValue("catId", "id1")
+
+	statusCode, response := patchCat(withStore(store, req))
+	if statusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, statusCode)
+	}
+	if verr, ok := response.(ValidationError); !ok || len(verr.Errors) != 2 {
+		t.Errorf("Expected 2 field errors, got %v", response)
+	}
+	if storedCats(store)["id1"] != stored {
+		t.Errorf("Stored cat should be unchanged, got %+v", storedCats(store)["id1"])
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Largest page served when --max-page-size is not given
+const defaultMaxPageSize = 500
+
+// Window of a list asked with ?limit=&offset=, the limit already clamped to --max-page-size
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Reads the window of a list, a missing or too large limit gets the maximum page size
+func parsePage(params *QueryParams) Page {
+	maxPageSize := currentConfig().MaxPageSize
+	page := Page{Limit: params.Int("limit", maxPageSize), Offset: params.Int("offset", 0)}
+
+	if page.Limit <= 0 {
+		params.Invalid("limit", "positive")
+		page.Limit = maxPageSize
+	}
+	if page.Offset < 0 {
+		params.Invalid("offset", "positive_or_zero")
+		page.Offset = 0
+	}
+	page.Limit = min(page.Limit, maxPageSize)
+	return page
+}
+
+// Slice of the items in the window, empty past the end
+func paginate[T any](items []T, page Page) []T {
+	start := min(page.Offset, len(items))
+	end := min(start+page.Limit, len(items))
+	return items[start:end]
+}
+
+// Tells the client the total and the effective limit, a bare array has no room for them
+func pageHeader(total int, page Page) http.Header {
+	header := http.Header{}
+	header.Set("X-Total-Count", strconv.Itoa(total))
+	header.Set("X-Limit", strconv.Itoa(page.Limit))
+	header.Set("X-Offset", strconv.Itoa(page.Offset))
+	return header
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// =============================================================================
+// PAGINATION TESTS
+// =============================================================================
+
+// Test an absurd limit is clamped to the maximum page size
+func TestListCatsMaxPageSize(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	cats := []Cat{}
+	for idx := range 12 {
+		cats = append(cats, Cat{ID: fmt.Sprintf("id%02d", idx), Name: "Toto"})
+	}
+	catsStore = NewMemoryRepo(cats...)
+	cfg := currentConfig()
+	cfg.MaxPageSize = 5
+	setConfig(cfg)
+	app := newApp()
+
+	tests := []struct {
+		query    string
+		expected []string
+		limit    string
+	}{
+		{"?limit=1000000", []string{"id00", "id01", "id02", "id03", "id04"}, "5"},
+		{"", []string{"id00", "id01", "id02", "id03", "id04"}, "5"},
+		{"?limit=2&offset=3", []string{"id03", "id04"}, "2"},
+		{"?limit=5&offset=10", []string{"id10", "id11"}, "5"},
+		{"?offset=50", []string{}, "5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats"+test.query, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			var catIDs []string
+			json.NewDecoder(rec.Body).Decode(&catIDs)
+			if !slices.Equal(catIDs, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, catIDs)
+			}
+			if limit := rec.Header().Get("X-Limit"); limit != test.limit {
+				t.Errorf("Expected the effective limit %s, got %s", test.limit, limit)
+			}
+			if total := rec.Header().Get("X-Total-Count"); total != "12" {
+				t.Errorf("Expected 12 cats in total, got %s", total)
+			}
+		})
+	}
+}
+
+// Test the malformed windows are refused
+func TestListCatsInvalidPage(t *testing.T) {
+	app := newApp()
+
+	for _, query := range []string{"?limit=0", "?limit=-3", "?limit=ten", "?offset=-1", "?offset=1.5"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
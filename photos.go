@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+)
+
+// Media type of the creations sending a photo along with the cat
+const multipartContentType = "multipart/form-data"
+
+// Largest photo accepted, within the default --max-body-bytes
+const maxPhotoBytes = 512 << 10
+
+// Media types of the accepted photos, sniffed from their content rather than trusted from the client
+var photoContentTypes = []string{"image/jpeg", "image/png"}
+
+var (
+	errPhotoTooLarge = errors.New("photo too large")
+	errPhotoType     = errors.New("photo neither a JPEG nor a PNG")
+	errMissingCat    = errors.New("missing the 'cat' part")
+)
+
+func isMultipartForm(req *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	return mediaType == multipartContentType
+}
+
+// Reads a creation form into the cat, given as JSON in the "cat" part, and returns the optional image of the "photo" part
+func readCatForm(req *http.Request, cat *Cat) ([]byte, error) {
+	var photo []byte
+	foundCat := false
+
+	reader, err := req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch part.FormName() {
+		case "cat":
+			if err := decodeJSON(part, cat); err != nil {
+				return nil, err
+			}
+			foundCat = true
+		case "photo":
+			if photo, err = io.ReadAll(io.LimitReader(part, maxPhotoBytes+1)); err != nil {
+				return nil, err
+			}
+			if len(photo) > maxPhotoBytes {
+				return nil, errPhotoTooLarge
+			}
+			if !slices.Contains(photoContentTypes, http.DetectContentType(photo)) {
+				return nil, errPhotoType
+			}
+		}
+	}
+
+	if !foundCat {
+		return nil, errMissingCat
+	}
+	return photo, nil
+}
+
+// Answer for a creation form which cannot be read
+func formFailure(req *http.Request, err error) (int, any) {
+	switch {
+	case errors.Is(err, errPhotoTooLarge):
+		return http.StatusRequestEntityTooLarge, localize(req, "photo_too_large")
+	case errors.Is(err, errPhotoType):
+		return http.StatusUnsupportedMediaType, localize(req, "photo_type")
+	case errors.Is(err, errMissingCat):
+		return http.StatusBadRequest, localize(req, "photo_form")
+	}
+	return decodeFailure(req, err)
+}
+
+// Writes the photo of a cat into --photo-dir, the ID cannot escape it
+func savePhoto(catID string, photo []byte) error {
+	dir := currentConfig().PhotoDir
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	return root.WriteFile(catID, photo, 0o644)
+}
+
+// Removes the photo of a cat, a cat without one is not an error
+func deletePhoto(catID string) error {
+	root, err := os.OpenRoot(currentConfig().PhotoDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	if err := root.Remove(catID); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Removes the photos of deleted cats, a failure only leaves the file behind
+func deletePhotos(catIDs []string) {
+	for _, catID := range catIDs {
+		if err := deletePhoto(catID); err != nil {
+			Logger.WithField("cat_id", catID).Warn("Unable to delete the photo of the cat: ", err)
+		}
+	}
+}
+
+// Serves the photo of a cat with its sniffed type, the photos of deleted cats are not served
+func getCatPhoto(res http.ResponseWriter, req *http.Request) {
+	catID := req.PathValue("catId")
+	Logger.Info("Getting the photo of the cat: ", catID)
+
+	if _, err := storeOf(req.Context()).Get(req.Context(), catID); err != nil {
+		code, body := storeFailure(req, err)
+		if err == ErrNotFound {
+			code, body = catNotFound(req, catID)
+		}
+		writeResponse(res, req, code, body)
+		return
+	}
+
+	photo, err := os.OpenInRoot(currentConfig().PhotoDir, catID)
+	if errors.Is(err, fs.ErrNotExist) {
+		writeResponse(res, req, http.StatusNotFound, localize(req, "photo_not_found"))
+		return
+	} else if err != nil {
+		code, body := storeFailure(req, err)
+		writeResponse(res, req, code, body)
+		return
+	}
+	defer photo.Close()
+
+	info, err := photo.Stat()
+	if err != nil || info.IsDir() {
+		writeResponse(res, req, http.StatusNotFound, localize(req, "photo_not_found"))
+		return
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(photo, head)
+	res.Header().Set("Content-Type", http.DetectContentType(head[:n]))
+	http.ServeContent(res, req, "", info.ModTime(), photo)
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Smallest content sniffed as a PNG
+var pngPhoto = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// Creation request sending the cat as JSON and the photo, when given, as a form
+func newCatFormRequest(t *testing.T, catJSON string, photo []byte) *http.Request {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if catJSON != "" {
+		form.WriteField("cat", catJSON)
+	}
+	if photo != nil {
+		part, err := form.CreateFormFile("photo", "photo.png")
+		if err != nil {
+			t.Fatalf("Failed to create the photo part: %v", err)
+		}
+		part.Write(photo)
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/cats", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+// =============================================================================
+// PHOTO TESTS
+// =============================================================================
+
+// Test a cat created with a photo gets it served back, until the cat is deleted
+func TestCreateCatWithPhoto(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.PhotoDir = filepath.Join(t.TempDir(), "photos")
+	setConfig(cfg)
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newCatFormRequest(t, `{"name": "Toto", "color": "Grey"}`, pngPhoto))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created Cat
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.Name != "Toto" || created.Color != "Grey" {
+		t.Errorf("Expected the cat of the form, got %+v", created)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/"+created.ID+"/photo", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected the PNG photo, got %d as %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !bytes.Equal(rec.Body.Bytes(), pngPhoto) {
+		t.Errorf("Expected the photo as sent, got %q", rec.Body.Bytes())
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/"+created.ID, nil))
+	if _, err := os.Stat(filepath.Join(cfg.PhotoDir, created.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the photo to be deleted with the cat, got %v", err)
+	}
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/"+created.ID+"/photo", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a deleted cat, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+// Test the forms with a wrong photo or without cat are refused and store nothing
+func TestCreateCatWithPhotoInvalid(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo()
+	cfg := currentConfig()
+	cfg.PhotoDir = filepath.Join(t.TempDir(), "photos")
+	setConfig(cfg)
+	app := newApp()
+
+	tooLarge := append(bytes.Clone(pngPhoto), make([]byte, maxPhotoBytes)...)
+	tests := []struct {
+		name         string
+		catJSON      string
+		photo        []byte
+		expectedCode int
+		expectedBody string
+	}{
+		{"gif photo", `{"name": "Toto"}`, []byte("GIF89a..."), http.StatusUnsupportedMediaType, "JPEG or a PNG"},
+		{"text photo", `{"name": "Toto"}`, []byte("not an image"), http.StatusUnsupportedMediaType, "JPEG or a PNG"},
+		{"too large", `{"name": "Toto"}`, tooLarge, http.StatusRequestEntityTooLarge, "larger than 512 KiB"},
+		{"missing cat", "", pngPhoto, http.StatusBadRequest, "'cat' part"},
+		{"malformed cat", `{"name": `, pngPhoto, http.StatusBadRequest, "Invalid JSON input"},
+		{"invalid cat", `{"color": "Grey"}`, pngPhoto, http.StatusUnprocessableEntity, "is required"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newCatFormRequest(t, test.catJSON, test.photo))
+		if rec.Code != test.expectedCode || !strings.Contains(rec.Body.String(), test.expectedBody) {
+			t.Errorf("%s: expected %d with %q, got %d: %s", test.name, test.expectedCode, test.expectedBody, rec.Code, rec.Body.String())
+		}
+	}
+
+	if cats, _ := catsStore.List(t.Context()); len(cats) != 0 {
+		t.Errorf("Expected no cat to be stored, got %d", len(cats))
+	}
+	if entries, _ := os.ReadDir(cfg.PhotoDir); len(entries) != 0 {
+		t.Errorf("Expected no photo to be stored, got %d", len(entries))
+	}
+}
+
+// Test a cat created without photo has none
+func TestGetCatPhotoMissing(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	cfg := currentConfig()
+	cfg.PhotoDir = t.TempDir()
+	setConfig(cfg)
+	app := newApp()
+
+	for _, target := range []string{"/api/cats/id1/photo", "/api/cats/unknown/photo"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+// Test the photo of a cat evicted from the full store goes with it
+func TestEvictedCatPhoto(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.PhotoDir = filepath.Join(t.TempDir(), "photos")
+	cfg.MaxCats = 1
+	cfg.EvictionPolicy = evictionOldest
+	setConfig(cfg)
+	app := newAppWithStore(NewMemoryRepo())
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newCatFormRequest(t, `{"name": "Toto"}`, pngPhoto))
+	var created Cat
+	if err := json.NewDecoder(rec.Body).Decode(&created); rec.Code != http.StatusCreated || err != nil {
+		t.Fatalf("Expected the cat created, got %d (%v)", rec.Code, err)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("PUT", "/api/cats/id2", strings.NewReader(`{"name": "Felix"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.PhotoDir, created.ID)); !os.IsNotExist(err) {
+		t.Errorf("Expected the photo to be deleted with the evicted cat, got %v", err)
+	}
+}
+
+// Test the bulk delete and the replacing import remove the photos of the cats they drop
+func TestDroppedCatsPhotos(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.PhotoDir = filepath.Join(t.TempDir(), "photos")
+	setConfig(cfg)
+
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto", Color: "Grey"}, Cat{ID: "id2", Name: "Felix"}, Cat{ID: "id3", Name: "Tom"})
+	app := newAppWithStore(store)
+	for _, catID := range []string{"id1", "id2", "id3"} {
+		if err := savePhoto(catID, pngPhoto); err != nil {
+			t.Fatalf("Failed to save the photo: %v", err)
+		}
+	}
+	hasPhoto := func(catID string) bool {
+		_, err := os.Stat(filepath.Join(cfg.PhotoDir, catID))
+		return err == nil
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats?color=Grey", nil))
+	if rec.Code != http.StatusOK || hasPhoto("id1") || !hasPhoto("id2") {
+		t.Errorf("Expected only the photo of id1 deleted, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/import?mode=replace", strings.NewReader(`[{"id": "id2", "name": "Felix"}]`)))
+	if rec.Code != http.StatusOK || !hasPhoto("id2") || hasPhoto("id3") {
+		t.Errorf("Expected the photo of the dropped id3 deleted, the one of the imported id2 kept, got %d", rec.Code)
+	}
+}
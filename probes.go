@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Set while the store and the spec are loaded, the server already listens to answer the probes
+var initializing atomic.Bool
+
+// Set once a shutdown signal is received, the new requests are turned away while the others drain
+var shuttingDown atomic.Bool
+
+// Answer of the health probes
+type ProbeStatus struct {
+	Status string `json:"status"`
+	// Outcome of each check of a deep probe, "ok" or the failure
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Bound of the store checks of a deep probe, a hung backend is a failed one
+const deepProbeTimeout = 2 * time.Second
+
+// Liveness: the process is up and answering. With `?deep=true` the store is also read and
+// written, a read-only filesystem or a full disk failing the probe with a 503
+func getHealth(req *http.Request) (int, any) {
+	if req.URL.Query().Get("deep") != "true" {
+		return http.StatusOK, ProbeStatus{Status: "ok"}
+	}
+	if initializing.Load() {
+		return http.StatusServiceUnavailable, ProbeStatus{Status: "starting"}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), deepProbeTimeout)
+	defer cancel()
+	store := storeOf(ctx)
+	checks := map[string]string{"read": "ok", "write": "ok"}
+	failed := false
+	if _, err := store.List(ctx); err != nil {
+		checks["read"], failed = err.Error(), true
+	}
+	if err := store.ProbeWrite(ctx); err != nil {
+		checks["write"], failed = err.Error(), true
+	}
+
+	if failed {
+		Logger.Warnf("Deep health probe failing: %v", checks)
+		return http.StatusServiceUnavailable, ProbeStatus{Status: "failing", Checks: checks}
+	}
+	return http.StatusOK, ProbeStatus{Status: "ok", Checks: checks}
+}
+
+// Readiness: the initialization is over and the requests can be routed here
+func getReady(req *http.Request) (int, any) {
+	if initializing.Load() {
+		return http.StatusServiceUnavailable, ProbeStatus{Status: "starting"}
+	}
+	if shuttingDown.Load() {
+		return http.StatusServiceUnavailable, ProbeStatus{Status: "stopping"}
+	}
+	return http.StatusOK, ProbeStatus{Status: "ready"}
+}
+
+// Turns away everything but the probes until the initialization is over
+func awaitInit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if initializing.Load() && r.URL.Path != "/health" && r.URL.Path != "/ready" {
+			w.Header().Set("Retry-After", "1")
+			writeResponse(w, r, http.StatusServiceUnavailable, ErrorBody{Error: localize(r, "server_starting")})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Turns away the new requests but the probes once shutting down, a 503 the clients can retry
+// on another instance. The requests already past this point complete
+func refuseWhenStopping(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown.Load() && r.URL.Path != "/health" && r.URL.Path != "/ready" {
+			w.Header().Set("Retry-After", "1")
+			w.Header().Set("Connection", "close")
+			writeResponse(w, r, http.StatusServiceUnavailable, ErrorBody{Error: localize(r, "server_stopping")})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
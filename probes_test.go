@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// =============================================================================
+// PROBES TESTS
+// =============================================================================
+
+// Test the liveness answers at once while the readiness waits for the initialization
+func TestProbesDuringStartup(t *testing.T) {
+	initializing.Store(true)
+	defer initializing.Store(false)
+
+	app := newApp()
+	tests := []struct {
+		path     string
+		expected int
+		status   string
+	}{
+		{"/health", http.StatusOK, "ok"},
+		{"/ready", http.StatusServiceUnavailable, "starting"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.expected {
+			t.Errorf("%s: expected status code %d, got %d", test.path, test.expected, rec.Code)
+		}
+		var body ProbeStatus
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Status != test.status {
+			t.Errorf("%s: expected status %q, got %v (%v)", test.path, test.status, body, err)
+		}
+	}
+
+	// The API waits as well
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a 503 to retry, got %d", rec.Code)
+	}
+}
+
+// Test everything is served once initialized
+func TestProbesReady(t *testing.T) {
+	app := newApp()
+
+	for _, path := range []string{"/health", "/ready", "/api/cats"} {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status code %d, got %d", path, http.StatusOK, rec.Code)
+		}
+	}
+}
+
+// Test the new requests get a 503 to retry once shutting down, the liveness still answering
+func TestProbesDuringShutdown(t *testing.T) {
+	shuttingDown.Store(true)
+	defer shuttingDown.Store(false)
+
+	app := newApp()
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/health", http.StatusOK},
+		{"/ready", http.StatusServiceUnavailable},
+		{"/api/cats", http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.expected {
+			t.Errorf("%s: expected status code %d, got %d", test.path, test.expected, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	var body ErrorBody
+	json.NewDecoder(rec.Body).Decode(&body)
+	if body.Error != "Server shutting down" || rec.Header().Get("Retry-After") == "" || rec.Header().Get("Connection") != "close" {
+		t.Errorf("Expected a 503 to retry elsewhere, got %+v with %v", body, rec.Header())
+	}
+}
+
+// Store accepting the reads but refusing the writes, like a read-only replica
+type readOnlyStore struct {
+	Store
+}
+
+func (store readOnlyStore) ProbeWrite(ctx context.Context) error {
+	return errors.New("read-only file system")
+}
+
+// Test the deep probe writes to the store and names the failed check, the plain one not
+func TestDeepHealth(t *testing.T) {
+	tests := []struct {
+		store    Store
+		path     string
+		expected int
+		checks   map[string]string
+	}{
+		{readOnlyStore{NewMemoryRepo()}, "/health", http.StatusOK, nil},
+		{NewMemoryRepo(), "/health?deep=true", http.StatusOK, map[string]string{"read": "ok", "write": "ok"}},
+		{readOnlyStore{NewMemoryRepo()}, "/health?deep=true", http.StatusServiceUnavailable, map[string]string{"read": "ok", "write": "read-only file system"}},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		newAppWithStore(test.store).ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		var body ProbeStatus
+		json.NewDecoder(rec.Body).Decode(&body)
+		if rec.Code != test.expected || !reflect.DeepEqual(body.Checks, test.checks) {
+			t.Errorf("%s: expected %d with %v, got %d with %+v", test.path, test.expected, test.checks, rec.Code, body)
+		}
+	}
+}
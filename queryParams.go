@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Typed reader of the query parameters. The invalid ones get their fallback and are collected,
+// so a handler answers a single 400 listing them all
+type QueryParams struct {
+	query    url.Values
+	problems []string
+	// Language of the client, for the rules broken
+	language string
+}
+
+func newQueryParams(req *http.Request) *QueryParams {
+	return &QueryParams{query: req.URL.Query(), language: requestLanguage(req)}
+}
+
+// Records a parameter breaking a rule the typed readers cannot check, like a positive bound. The
+// rule is the code of its message in the catalog
+func (params *QueryParams) Invalid(key string, rule string, args ...any) {
+	message := translate(params.language, rule, args...)
+	params.problems = append(params.problems, fmt.Sprintf("%s '%s' %s", key, params.query.Get(key), message))
+}
+
+func (params *QueryParams) Has(key string) bool {
+	return params.query.Get(key) != ""
+}
+
+func (params *QueryParams) String(key string, fallback string) string {
+	if !params.Has(key) {
+		return fallback
+	}
+	return params.query.Get(key)
+}
+
+func (params *QueryParams) Int(key string, fallback int) int {
+	if value := params.OptionalInt(key); value != nil {
+		return *value
+	}
+	return fallback
+}
+
+// Integer parameter, nil when absent or invalid
+func (params *QueryParams) OptionalInt(key string) *int {
+	if !params.Has(key) {
+		return nil
+	}
+	value, err := strconv.Atoi(params.query.Get(key))
+	if err != nil {
+		params.Invalid(key, "not_integer_param")
+		return nil
+	}
+	return &value
+}
+
+func (params *QueryParams) Bool(key string, fallback bool) bool {
+	if !params.Has(key) {
+		return fallback
+	}
+	value, err := strconv.ParseBool(params.query.Get(key))
+	if err != nil {
+		params.Invalid(key, "not_boolean_param")
+		return fallback
+	}
+	return value
+}
+
+// Invalid input error listing the invalid parameters, nil when all were fine
+func (params *QueryParams) Err() error {
+	if len(params.problems) == 0 {
+		return nil
+	}
+	return invalidInput("invalid_query", strings.Join(params.problems, ", "))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// QUERY PARAMETERS TESTS
+// =============================================================================
+
+// Test the typed values, their fallbacks and the collected problems
+func TestQueryParams(t *testing.T) {
+	params := newQueryParams(httptest.NewRequest("GET", "/?limit=5&offset=abc&pretty=yes&name=Toto&dryRun=1", nil))
+
+	if limit := params.Int("limit", 10); limit != 5 {
+		t.Errorf("Expected the limit 5, got %d", limit)
+	}
+	if offset := params.Int("offset", 0); offset != 0 {
+		t.Errorf("Expected the fallback offset, got %d", offset)
+	}
+	if minWeight := params.OptionalInt("minWeight"); minWeight != nil {
+		t.Errorf("Expected no minWeight, got %d", *minWeight)
+	}
+	if pretty := params.Bool("pretty", false); pretty {
+		t.Error("Expected the fallback for an invalid boolean")
+	}
+	if dryRun := params.Bool("dryRun", false); !dryRun {
+		t.Error("Expected dryRun=1 to be true")
+	}
+	if name, color := params.String("name", ""), params.String("color", "Grey"); name != "Toto" || color != "Grey" {
+		t.Errorf("Expected Toto and the fallback color, got %q and %q", name, color)
+	}
+
+	err := params.Err()
+	expected := "Invalid query parameter: offset 'abc' must be an integer, pretty 'yes' must be true or false"
+	if !errors.Is(err, ErrInvalidInput) || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+
+	if err := newQueryParams(httptest.NewRequest("GET", "/?limit=5", nil)).Err(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+// Test the list endpoints answer the same 400 listing every invalid parameter
+func TestInvalidQueryParams(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	tests := []struct {
+		method   string
+		target   string
+		expected string
+	}{
+		{"GET", "/api/cats?limit=abc&minWeight=heavy", "Invalid query parameter: minWeight 'heavy' must be an integer, limit 'abc' must be an integer"},
+		{"GET", "/api/cats?limit=0", "Invalid query parameter: limit '0' must be a positive integer"},
+		{"GET", "/api/cats?format=ndjson&maxWeight=1.5", "Invalid query parameter: maxWeight '1.5' must be an integer"},
+		{"GET", "/api/cats/count?minWeight=heavy", "Invalid query parameter: minWeight 'heavy' must be an integer"},
+		{"DELETE", "/api/cats?minWeight=heavy", "Invalid query parameter: minWeight 'heavy' must be an integer"},
+		{"POST", "/api/cats?dryRun=maybe", "Invalid query parameter: dryRun 'maybe' must be true or false"},
+	}
+
+	for _, test := range tests {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest(test.method, test.target, strings.NewReader(`{"name": "Felix"}`)))
+		if rec.Code != http.StatusBadRequest || rec.Body.String() != `"`+test.expected+`"`+"\n" {
+			t.Errorf("%s %s: expected a 400 %q, got %d %s", test.method, test.target, test.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
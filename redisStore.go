@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Set of all the stored IDs, listing from it is deterministic unlike SCAN
+const redisIndexKey = "cats"
+
+// Number of hashes fetched in one round trip when iterating
+const redisIterateBatch = 100
+
+// Counter bumped by every write, a transaction watching it is retried when another write lands
+// before its own
+const redisVersionKey = "cats:version"
+
+// Times a transaction is retried after concurrent writes before giving up
+const redisTransactAttempts = 10
+
+var ErrWriteContention = errors.New("too many concurrent writes, the change was not applied")
+
+// Sentinel of the write probe, expiring by itself should the probe stop before deleting it
+const (
+	redisProbeKey = "health:probe"
+	redisProbeTTL = time.Minute
+)
+
+func redisCatKey(catID string) string {
+	return "cat:" + catID
+}
+
+// Shared store for several instances, each cat is a hash under `cat:{id}`
+type RedisRepo struct {
+	client *redis.Client
+}
+
+// Connects to the server, failing if it cannot be reached
+func NewRedisRepo(ctx context.Context, addr string) (*RedisRepo, error) {
+	client := redis.NewClient(&redis.Options{Addr: addr})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("unable to reach redis at %s: %w", addr, err)
+	}
+	return &RedisRepo{client: client}, nil
+}
+
+func catToHash(cat Cat) map[string]any {
+	hash := map[string]any{
+		"name":      cat.Name,
+		"birthDate": cat.BirthDate,
+		"color":     cat.Color,
+		"breed":     cat.Breed,
+	}
+	if cat.WeightGrams != 0 {
+		hash["weightGrams"] = strconv.Itoa(cat.WeightGrams)
+	}
+	if !cat.CreatedAt.IsZero() {
+		hash["createdAt"] = cat.CreatedAt.Format(time.RFC3339Nano)
+	}
+	if !cat.UpdatedAt.IsZero() {
+		hash["updatedAt"] = cat.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return hash
+}
+
+func catFromHash(catID string, hash map[string]string) Cat {
+	cat := Cat{
+		ID:        catID,
+		Name:      hash["name"],
+		BirthDate: hash["birthDate"],
+		Color:     hash["color"],
+		Breed:     hash["breed"],
+	}
+	// Unparsable values are left unknown
+	cat.WeightGrams, _ = strconv.Atoi(hash["weightGrams"])
+	cat.CreatedAt, _ = time.Parse(time.RFC3339Nano, hash["createdAt"])
+	cat.UpdatedAt, _ = time.Parse(time.RFC3339Nano, hash["updatedAt"])
+	return cat
+}
+
+func (repo *RedisRepo) List(ctx context.Context) ([]Cat, error) {
+	return listRedisCats(ctx, repo.client)
+}
+
+// Lists through the client or through a transaction watching the keys
+func listRedisCats(ctx context.Context, client redis.Cmdable) ([]Cat, error) {
+	catIDs, err := client.SMembers(ctx, redisIndexKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetching all the hashes in a single round trip
+	pipe := client.Pipeline()
+	commands := make([]*redis.MapStringStringCmd, len(catIDs))
+	for idx, catID := range catIDs {
+		commands[idx] = pipe.HGetAll(ctx, redisCatKey(catID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]Cat, 0, len(catIDs))
+	for idx, catID := range catIDs {
+		// Skipping an ID whose hash vanished in between
+		if hash := commands[idx].Val(); len(hash) > 0 {
+			results = append(results, catFromHash(catID, hash))
+		}
+	}
+	return results, nil
+}
+
+// Fetches the hashes a batch at a time, so only a batch of cats is held whatever the store size
+func (repo *RedisRepo) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	catIDs, err := repo.client.SMembers(ctx, redisIndexKey).Result()
+	if err != nil {
+		return err
+	}
+
+	for batch := range slices.Chunk(catIDs, redisIterateBatch) {
+		pipe := repo.client.Pipeline()
+		commands := make([]*redis.MapStringStringCmd, len(batch))
+		for idx, catID := range batch {
+			commands[idx] = pipe.HGetAll(ctx, redisCatKey(catID))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for idx, catID := range batch {
+			// Skipping an ID whose hash vanished in between
+			if hash := commands[idx].Val(); len(hash) > 0 && !yield(catFromHash(catID, hash)) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (repo *RedisRepo) Get(ctx context.Context, catID string) (Cat, error) {
+	hash, err := repo.client.HGetAll(ctx, redisCatKey(catID)).Result()
+	if err != nil {
+		return Cat{}, err
+	}
+	if len(hash) == 0 {
+		return Cat{}, ErrNotFound
+	}
+	return catFromHash(catID, hash), nil
+}
+
+// Replaces the whole hash so the cleared fields do not linger
+func (repo *RedisRepo) Save(ctx context.Context, cat Cat) error {
+	_, err := repo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisCatKey(cat.ID))
+		pipe.HSet(ctx, redisCatKey(cat.ID), catToHash(cat))
+		pipe.SAdd(ctx, redisIndexKey, cat.ID)
+		pipe.Incr(ctx, redisVersionKey)
+		return nil
+	})
+	return err
+}
+
+// A single transaction, the replaced IDs are read from the index beforehand
+func (repo *RedisRepo) Import(ctx context.Context, cats []Cat, replace bool) error {
+	var previousIDs []string
+	if replace {
+		var err error
+		if previousIDs, err = repo.client.SMembers(ctx, redisIndexKey).Result(); err != nil {
+			return err
+		}
+	}
+
+	_, err := repo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, catID := range previousIDs {
+			pipe.Del(ctx, redisCatKey(catID))
+		}
+		if replace {
+			pipe.Del(ctx, redisIndexKey)
+		}
+		for _, cat := range cats {
+			pipe.Del(ctx, redisCatKey(cat.ID))
+			pipe.HSet(ctx, redisCatKey(cat.ID), catToHash(cat))
+			pipe.SAdd(ctx, redisIndexKey, cat.ID)
+		}
+		pipe.Incr(ctx, redisVersionKey)
+		return nil
+	})
+	return err
+}
+
+func (repo *RedisRepo) Delete(ctx context.Context, catID string) error {
+	var deleted *redis.IntCmd
+	_, err := repo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, redisCatKey(catID))
+		pipe.SRem(ctx, redisIndexKey, catID)
+		pipe.Incr(ctx, redisVersionKey)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// The matching cats are read first then deleted in a single transaction,
+// those already gone in between are not counted
+func (repo *RedisRepo) DeleteMatching(ctx context.Context, match func(Cat) bool) ([]string, error) {
+	cats, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matchingIDs []string
+	for _, cat := range cats {
+		if match(cat) {
+			matchingIDs = append(matchingIDs, cat.ID)
+		}
+	}
+
+	commands := make([]*redis.IntCmd, len(matchingIDs))
+	_, err = repo.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for idx, catID := range matchingIDs {
+			commands[idx] = pipe.Del(ctx, redisCatKey(catID))
+			pipe.SRem(ctx, redisIndexKey, catID)
+		}
+		pipe.Incr(ctx, redisVersionKey)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deletedIDs := []string{}
+	for idx, catID := range matchingIDs {
+		if commands[idx].Val() > 0 {
+			deletedIDs = append(deletedIDs, catID)
+		}
+	}
+	return deletedIDs, nil
+}
+
+// Reads the cats watching the version, so the transaction fails should any write land before
+// it executes, and then starts over from a fresh read
+func (repo *RedisRepo) Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error {
+	for range redisTransactAttempts {
+		err := repo.client.Watch(ctx, func(tx *redis.Tx) error {
+			stored, err := listRedisCats(ctx, tx)
+			if err != nil {
+				return err
+			}
+			cats := make(map[string]Cat, len(stored))
+			for _, cat := range stored {
+				cats[cat.ID] = cat
+			}
+			change, err := decide(cats)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, catID := range change.Delete {
+					pipe.Del(ctx, redisCatKey(catID))
+					pipe.SRem(ctx, redisIndexKey, catID)
+				}
+				for _, cat := range change.Save {
+					pipe.Del(ctx, redisCatKey(cat.ID))
+					pipe.HSet(ctx, redisCatKey(cat.ID), catToHash(cat))
+					pipe.SAdd(ctx, redisIndexKey, cat.ID)
+				}
+				pipe.Incr(ctx, redisVersionKey)
+				return nil
+			})
+			return err
+		}, redisVersionKey)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return ErrWriteContention
+}
+
+// A read-only replica or a full disk refuses the SET, unlike the PING done at startup
+func (repo *RedisRepo) ProbeWrite(ctx context.Context) error {
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := repo.client.Set(ctx, redisProbeKey, value, redisProbeTTL).Err(); err != nil {
+		return err
+	}
+	readBack, err := repo.client.Get(ctx, redisProbeKey).Result()
+	if err != nil {
+		return err
+	}
+	if readBack != value {
+		return ErrProbeMismatch
+	}
+	return repo.client.Del(ctx, redisProbeKey).Err()
+}
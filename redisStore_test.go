@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// Redis store on an in-process server holding the given cats, closed with the test
+func newMiniRedisRepo(t *testing.T, cats ...Cat) *RedisRepo {
+	t.Helper()
+	server := miniredis.RunT(t)
+	repo, err := NewRedisRepo(context.Background(), server.Addr())
+	if err != nil {
+		t.Fatalf("Failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { repo.client.Close() })
+	if err := repo.Import(context.Background(), cats, false); err != nil {
+		t.Fatalf("Failed to load the cats: %v", err)
+	}
+	return repo
+}
+
+// =============================================================================
+// REDIS STORE TESTS
+// =============================================================================
+
+// Test a saved cat replaces its whole hash, the cleared fields do not linger
+func TestRedisSaveClearsFields(t *testing.T) {
+	ctx := context.Background()
+	repo := newMiniRedisRepo(t, Cat{ID: "id1", Name: "Toto", Color: "Grey", WeightGrams: 4200})
+
+	if err := repo.Save(ctx, Cat{ID: "id1", Name: "Toto"}); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if cat, err := repo.Get(ctx, "id1"); err != nil || cat.Color != "" || cat.WeightGrams != 0 {
+		t.Errorf("Expected the color and the weight cleared, got %+v (%v)", cat, err)
+	}
+}
+
+// Test the index and the hashes stay in step, a cat deleted behind the index is skipped
+func TestRedisIndex(t *testing.T) {
+	ctx := context.Background()
+	repo := newMiniRedisRepo(t, Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})
+
+	if members, err := repo.client.SMembers(ctx, redisIndexKey).Result(); err != nil || len(members) != 2 {
+		t.Fatalf("Expected 2 indexed IDs, got %v (%v)", members, err)
+	}
+	repo.client.Del(ctx, redisCatKey("id2"))
+
+	cats, err := repo.List(ctx)
+	if err != nil || len(cats) != 1 || cats[0].ID != "id1" {
+		t.Errorf("Expected only id1 listed, got %v (%v)", cats, err)
+	}
+	if err := repo.Delete(ctx, "id2"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound for the vanished hash, got %v", err)
+	}
+	if members, _ := repo.client.SMembers(ctx, redisIndexKey).Result(); len(members) != 1 {
+		t.Errorf("Expected the delete to clean the index, got %v", members)
+	}
+}
+
+// Test a transaction decided on cats another write changed meanwhile starts over from a fresh read
+func TestRedisTransactRetries(t *testing.T) {
+	ctx := context.Background()
+	repo := newMiniRedisRepo(t, Cat{ID: "id1", Name: "Toto"})
+
+	attempts := 0
+	err := repo.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+		attempts++
+		if attempts == 1 {
+			repo.Save(ctx, Cat{ID: "id1", Name: "Titi"})
+		}
+		cat := cats["id1"]
+		cat.Color = "Grey"
+		return StoreChange{Save: []Cat{cat}}, nil
+	})
+	if err != nil || attempts != 2 {
+		t.Fatalf("Expected a second attempt, got %d (%v)", attempts, err)
+	}
+	if cat, _ := repo.Get(ctx, "id1"); cat.Name != "Titi" || cat.Color != "Grey" {
+		t.Errorf("Expected the change applied on the concurrent write, got %+v", cat)
+	}
+
+	err = repo.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+		repo.Save(ctx, Cat{ID: "id2", Name: "Felix"})
+		return StoreChange{}, nil
+	})
+	if err != ErrWriteContention {
+		t.Errorf("Expected ErrWriteContention when every attempt is overtaken, got %v", err)
+	}
+}
+
+// Test the cats survive the round trip through a redis hash
+func TestRedisHashRoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 30, 0, 123, time.UTC)
+	cats := []Cat{
+		{ID: "id1", Name: "Toto", Color: "Grey", BirthDate: "2023-04-16", WeightGrams: 4200, CreatedAt: createdAt, UpdatedAt: createdAt.Add(time.Hour)},
+		{ID: "id2", Name: "Felix"},
+	}
+
+	for _, cat := range cats {
+		hash := map[string]string{}
+		for field, value := range catToHash(cat) {
+			hash[field] = value.(string)
+		}
+
+		if decoded := catFromHash(cat.ID, hash); decoded != cat {
+			t.Errorf("Expected %+v, got %+v", cat, decoded)
+		}
+	}
+}
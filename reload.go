@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Keeps a setting applied at startup only, telling whether the reloaded value differed
+func keepSetting[T comparable](reloaded *T, current T) bool {
+	changed := *reloaded != current
+	*reloaded = current
+	return changed
+}
+
+// Restores the settings needing a restart into the reloaded config, returns the flags of the ones changed
+func keepStartupSettings(reloaded *Config, current Config) []string {
+	startupOnly := []struct {
+		flag string
+		kept bool
+	}{
+		{"config", keepSetting(&reloaded.ConfigFile, current.ConfigFile)},
+		{"addr", keepSetting(&reloaded.Addr, current.Addr)},
+		{"tls-cert", keepSetting(&reloaded.TLSCert, current.TLSCert)},
+		{"tls-key", keepSetting(&reloaded.TLSKey, current.TLSKey)},
+		{"base-path", keepSetting(&reloaded.BasePath, current.BasePath)},
+		{"store", keepSetting(&reloaded.Store, current.Store)},
+		{"redis-addr", keepSetting(&reloaded.RedisAddr, current.RedisAddr)},
+		{"seed", keepSetting(&reloaded.Seed, current.Seed)},
+		{"id-strategy", keepSetting(&reloaded.IDStrategy, current.IDStrategy)},
+		{"shutdown-timeout", keepSetting(&reloaded.ShutdownTimeout, current.ShutdownTimeout)},
+		{"validate-requests", keepSetting(&reloaded.ValidateRequests, current.ValidateRequests)},
+		{"max-concurrent", keepSetting(&reloaded.MaxConcurrent, current.MaxConcurrent)},
+		{"deterministic", keepSetting(&reloaded.Deterministic, current.Deterministic)},
+		{"ordered-events", keepSetting(&reloaded.OrderedEvents, current.OrderedEvents)},
+		{"webhook-concurrency", keepSetting(&reloaded.WebhookConcurrency, current.WebhookConcurrency)},
+		{"webhook-breaker-failures", keepSetting(&reloaded.WebhookBreakerFailures, current.WebhookBreakerFailures)},
+		{"webhook-breaker-cooldown", keepSetting(&reloaded.WebhookBreakerCooldown, current.WebhookBreakerCooldown)},
+	}
+
+	var ignored []string
+	for _, setting := range startupOnly {
+		if setting.kept {
+			ignored = append(ignored, setting.flag)
+		}
+	}
+	return ignored
+}
+
+// Reads the settings again from the same sources as at startup and swaps them in at once.
+// On error the settings in effect are left untouched.
+func reloadConfig(args []string, lookupEnv func(string) (string, bool)) error {
+	current := currentConfig()
+
+	reloaded := defaultConfig()
+	flags := flag.NewFlagSet("reload", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	registerFlags(flags, &reloaded)
+	if err := loadConfig(flags, &reloaded, args, lookupEnv); err != nil {
+		return err
+	}
+	for _, name := range keepStartupSettings(&reloaded, current) {
+		Logger.Warnf("--%s only applies at startup, its change is ignored until a restart", name)
+	}
+	if err := reloaded.validate(); err != nil {
+		return err
+	}
+
+	if reloaded.LogBuffer != current.LogBuffer {
+		logRing.Resize(reloaded.LogBuffer)
+	}
+	setConfig(reloaded)
+	Logger.Info("Config reloaded: ", describeConfig(flags))
+	return nil
+}
+
+// Reloads the config on each SIGHUP, the connections are left alone
+func watchReloads(args []string) {
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			Logger.Info("SIGHUP received, reloading the config")
+			if err := reloadConfig(args, os.LookupEnv); err != nil {
+				Logger.Error("Unable to reload the config, keeping the current one: ", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// =============================================================================
+// CONFIG RELOAD TESTS
+// =============================================================================
+
+// Test a reload applies the live settings and keeps the startup ones
+func TestReloadConfig(t *testing.T) {
+	// Save original state
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	path := writeConfigFile(t, `
+max-cats: 3
+log-level: info
+api-key: rotated
+addr: ":9999"
+store: redis
+`)
+	if err := reloadConfig([]string{"--config", path}, envOf(nil)); err != nil {
+		t.Fatalf("Failed to reload the config: %v", err)
+	}
+
+	cfg := currentConfig()
+	if cfg.MaxCats != 3 || cfg.LogLevel != logLevelInfo || cfg.APIKey != "rotated" {
+		t.Errorf("Expected the live settings to be applied, got %+v", cfg)
+	}
+	if cfg.Addr != originalConfig.Addr || cfg.Store != originalConfig.Store {
+		t.Errorf("Expected the startup settings to be kept, got %q and %q", cfg.Addr, cfg.Store)
+	}
+
+	// A broken file leaves the settings in effect
+	if err := os.WriteFile(path, []byte("max-cats: -1\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write the config file: %v", err)
+	}
+	if err := reloadConfig([]string{"--config", path}, envOf(nil)); err == nil {
+		t.Error("Expected an error for an invalid setting")
+	}
+	if currentConfig() != cfg {
+		t.Errorf("Expected the config to be unchanged, got %+v", currentConfig())
+	}
+}
+
+// Test a reloaded log level applies to the lines logged while and after it is reloaded
+func TestReloadLogLevel(t *testing.T) {
+	// Save original state
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	var buffer bytes.Buffer
+	logger := AppLogger{out: &buffer}
+	path := writeConfigFile(t, "log-level: warn\nlog-color: never\n")
+
+	// The requests keep logging while the signal goroutine reloads
+	var logging sync.WaitGroup
+	logging.Add(1)
+	go func() {
+		defer logging.Done()
+		for range 100 {
+			Logger.WithField("cat_id", "id1").Debug("Logging during the reload")
+		}
+	}()
+	if err := reloadConfig([]string{"--config", path}, envOf(nil)); err != nil {
+		t.Fatalf("Failed to reload the config: %v", err)
+	}
+	logging.Wait()
+
+	logger.Info("Hidden")
+	logger.Warnf("Shown %d", 1)
+	if output := buffer.String(); strings.Contains(output, "Hidden") || !strings.Contains(output, " W ") || !strings.Contains(output, "Shown 1") {
+		t.Errorf("Expected only the warning, got %q", output)
+	}
+}
+
+// Test the changed startup settings are reported and restored
+func TestKeepStartupSettings(t *testing.T) {
+	current := defaultConfig()
+	reloaded := defaultConfig()
+	reloaded.Addr = ":9999"
+	reloaded.TLSCert = "cert.pem"
+	reloaded.RequestTimeout = 0
+
+	ignored := keepStartupSettings(&reloaded, current)
+	if !slices.Equal(ignored, []string{"addr", "tls-cert"}) {
+		t.Errorf("Expected addr and tls-cert to be ignored, got %v", ignored)
+	}
+	if reloaded.Addr != current.Addr || reloaded.TLSCert != "" || reloaded.RequestTimeout != 0 {
+		t.Errorf("Expected only the startup settings to be restored, got %+v", reloaded)
+	}
+}
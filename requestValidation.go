@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// Parses the spec and routes it under the configured base path
+func loadSpecRouter() (routers.Router, error) {
+	specYAML, err := readSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(specYAML)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(openapi3.NewLoader().Context); err != nil {
+		return nil, err
+	}
+
+	// The relative servers of the UI do not tell the mount point
+	doc.Servers = openapi3.Servers{{URL: apiPath("")}}
+	for _, pathItem := range doc.Paths.Map() {
+		pathItem.Servers = nil
+	}
+	return legacy.NewRouter(doc)
+}
+
+// Checks the requests described by the spec before they reach the handlers,
+// the others are left to the router
+func validateRequests(specRouter routers.Router, next http.Handler) http.Handler {
+	options := &openapi3filter.Options{
+		// The API key is checked by requireAPIKey
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := specRouter.FindRoute(r)
+		if err != nil || slices.Contains(slices.Collect(maps.Values(pathParams)), "") {
+			// An empty parameter is the router matching a shorter path, /cats for /cats/{catId}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		})
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				code, body := decodeFailure(r, err)
+				writeResponse(w, r, code, body)
+				return
+			}
+			Logger.Info("Request not matching the spec: ", err)
+			writeResponse(w, r, http.StatusBadRequest, ErrorBody{Error: localize(r, "invalid_request", err)})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
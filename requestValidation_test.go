@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// REQUEST VALIDATION TESTS
+// =============================================================================
+
+// Test the embedded spec can be routed
+func TestLoadSpecRouter(t *testing.T) {
+	if _, err := loadSpecRouter(); err != nil {
+		t.Fatalf("Expected the embedded spec to load: %v", err)
+	}
+}
+
+// Test the requests not matching the spec are stopped with a 400
+func TestValidateRequests(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+	cfg := currentConfig()
+	cfg.ValidateRequests = true
+	setConfig(cfg)
+	app := newApp()
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		contentType  string
+		body         string
+		expectedCode int
+	}{
+		{"valid creation", "POST", "/api/cats", "application/json", `{"name": "Felix", "color": "Black"}`, http.StatusCreated},
+		{"extra field", "POST", "/api/cats", "application/json", `{"name": "Felix", "age": 3}`, http.StatusBadRequest},
+		{"wrong type", "POST", "/api/cats", "application/json", `{"name": 42}`, http.StatusBadRequest},
+		{"missing name", "POST", "/api/cats", "application/json", `{"color": "Black"}`, http.StatusBadRequest},
+		{"wrong query type", "POST", "/api/cats?dryRun=maybe", "application/json", `{"name": "Felix"}`, http.StatusBadRequest},
+		{"valid patch", "PATCH", "/api/cats/id1", "application/merge-patch+json", `{"color": null}`, http.StatusOK},
+		{"null name patch", "PATCH", "/api/cats/id1", "application/merge-patch+json", `{"name": null}`, http.StatusBadRequest},
+		{"valid import", "POST", "/api/import", "application/json", `[{"id": "id2", "name": "Tom", "createdAt": "2024-05-01T10:30:00Z"}]`, http.StatusOK},
+		{"invalid import", "POST", "/api/import", "application/json", `[{"id": "id3", "name": "Tom", "owner": "Jerry"}]`, http.StatusBadRequest},
+		{"read route", "GET", "/api/cats/id1", "", "", http.StatusOK},
+		// Outside the spec, the router answers
+		{"unknown route", "GET", "/api/nothing", "", "", http.StatusNotFound},
+		{"wrong method", "PUT", "/api/cats", "", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != test.expectedCode {
+				t.Fatalf("Expected status code %d, got %d: %s", test.expectedCode, rec.Code, rec.Body.String())
+			}
+			if test.expectedCode == http.StatusBadRequest {
+				var body ErrorBody
+				if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" {
+					t.Errorf("Expected the validation detail, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+// Test the validation is off by default
+func TestValidateRequestsDisabled(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	catsStore = NewMemoryRepo()
+
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix", "age": 3}`)))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+}
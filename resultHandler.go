@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Failures of a ResultFunc answered with their own status, ErrNotFound being the third one
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+)
+
+// Service function failing with an error rather than picking a status, a nil error answering 200
+type ResultFunc func(*http.Request) (any, error)
+
+// Error told to the client with its own message, matching its sentinel through errors.Is. The
+// message is the one of the catalog code, in the language of the client once answered
+type clientError struct {
+	sentinel error
+	code     string
+	args     []any
+}
+
+func (err clientError) Error() string {
+	return translate(supportedLanguages[0], err.code, err.args...)
+}
+
+func (err clientError) Unwrap() error {
+	return err.sentinel
+}
+
+func invalidInput(code string, args ...any) error {
+	return clientError{sentinel: ErrInvalidInput, code: code, args: args}
+}
+
+func capitalize(message string) string {
+	first, size := utf8.DecodeRuneInString(message)
+	return string(unicode.ToUpper(first)) + message[size:]
+}
+
+// Status and body of the error of a ResultFunc, the internal failures are not detailed to the client
+func errorResponse(req *http.Request, err error) (int, any) {
+	statuses := map[error]int{
+		ErrNotFound:     http.StatusNotFound,
+		ErrInvalidInput: http.StatusBadRequest,
+		ErrConflict:     http.StatusConflict,
+	}
+	for sentinel, code := range statuses {
+		if errors.Is(err, sentinel) {
+			Logger.Info("Request refused: ", err)
+			return code, capitalize(localizeError(req, err))
+		}
+	}
+	return storeFailure(req, err)
+}
+
+// Same as makeHandlerFunc for the functions returning an error, mapped to its status in a single place
+func makeResultHandlerFunc(resultFunc ResultFunc) http.HandlerFunc {
+	return makeHandlerFunc(func(req *http.Request) (int, any) {
+		body, err := resultFunc(req)
+		if err != nil {
+			return errorResponse(req, err)
+		}
+		return http.StatusOK, body
+	})
+}
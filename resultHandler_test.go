@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// RESULT HANDLER TESTS
+// =============================================================================
+
+// Test the errors are mapped to their status in a single place
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		err          error
+		expectedCode int
+		expectedBody string
+	}{
+		{ErrNotFound, http.StatusNotFound, "Cat not found"},
+		{fmt.Errorf("%w: cat '%s'", ErrNotFound, "id1"), http.StatusNotFound, "Cat not found: cat 'id1'"},
+		{invalidInput("invalid_query", "limit '0' must be a positive integer"), http.StatusBadRequest, "Invalid query parameter: limit '0' must be a positive integer"},
+		{ErrInvalidInput, http.StatusBadRequest, "Invalid input"},
+		{fmt.Errorf("%w: same name", ErrConflict), http.StatusConflict, "Conflict: same name"},
+		{context.DeadlineExceeded, http.StatusServiceUnavailable, "Request interrupted"},
+		{errors.New("disk on fire"), http.StatusInternalServerError, "Internal Server Error"},
+	}
+
+	for _, test := range tests {
+		code, body := errorResponse(httptest.NewRequest("GET", "/", nil), test.err)
+		if code != test.expectedCode || body != test.expectedBody {
+			t.Errorf("%v: expected %d %q, got %d %q", test.err, test.expectedCode, test.expectedBody, code, body)
+		}
+	}
+}
+
+// Test the adapter answers the results and the errors, the old handlers still working alongside
+func TestMakeResultHandlerFunc(t *testing.T) {
+	handler := makeResultHandlerFunc(func(req *http.Request) (any, error) {
+		if req.URL.Query().Get("fail") != "" {
+			return nil, ErrNotFound
+		}
+		return CatCount{Count: 3}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"count":3`) {
+		t.Errorf("Expected the result with a 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/?fail=1", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Cat not found") {
+		t.Errorf("Expected the error with a 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/count?minWeight=heavy", nil))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Invalid query parameter: minWeight") {
+		t.Errorf("Expected the filter error with a 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
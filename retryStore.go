@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// Connection failures worth another try, the server may answer a moment later
+func isTransientStoreError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// Calls the operation again on a transient error, waiting twice as long each time,
+// up to --store-retries more times and as long as the request has time left
+func withRetries[T any](ctx context.Context, operation string, call func() (T, error)) (T, error) {
+	cfg := currentConfig()
+	delay := cfg.StoreBackoff
+
+	result, err := call()
+	for attempt := 1; attempt <= cfg.StoreRetries && isTransientStoreError(err); attempt++ {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+		Logger.Debugf("Store %s failed, retry %d/%d in %v: %v", operation, attempt, cfg.StoreRetries, delay, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		delay *= 2
+		result, err = call()
+	}
+	return result, err
+}
+
+// Store retrying the idempotent calls, a save or an import is not replayed
+// as it may have been applied before the connection dropped
+type retryingStore struct {
+	Store
+}
+
+func (store retryingStore) List(ctx context.Context) ([]Cat, error) {
+	return withRetries(ctx, "List", func() ([]Cat, error) {
+		return store.Store.List(ctx)
+	})
+}
+
+func (store retryingStore) Get(ctx context.Context, catID string) (Cat, error) {
+	return withRetries(ctx, "Get", func() (Cat, error) {
+		return store.Store.Get(ctx, catID)
+	})
+}
+
+// A retry not finding the cat means the failed attempt deleted it before the connection dropped
+func (store retryingStore) Delete(ctx context.Context, catID string) error {
+	attempts := 0
+	_, err := withRetries(ctx, "Delete", func() (struct{}, error) {
+		attempts++
+		err := store.Store.Delete(ctx, catID)
+		if err == ErrNotFound && attempts > 1 {
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// Error of an iteration which already handed out cats, not to be retried
+type interruptedIteration struct {
+	err error
+}
+
+func (interrupted interruptedIteration) Error() string {
+	return interrupted.err.Error()
+}
+
+// Retried only while no cat was yielded, starting over later would yield the first ones twice
+func (store retryingStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	_, err := withRetries(ctx, "Iterate", func() (struct{}, error) {
+		handed := false
+		err := store.Store.Iterate(ctx, func(cat Cat) bool {
+			handed = true
+			return yield(cat)
+		})
+		if err != nil && handed {
+			return struct{}{}, interruptedIteration{err}
+		}
+		return struct{}{}, err
+	})
+	if interrupted, ok := err.(interruptedIteration); ok {
+		return interrupted.err
+	}
+	return err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Store failing its first calls with the given error
+type flakyStore struct {
+	Store
+	failures int
+	err      error
+	calls    *int
+}
+
+func (store flakyStore) fail() error {
+	*store.calls++
+	if *store.calls <= store.failures {
+		return store.err
+	}
+	return nil
+}
+
+func (store flakyStore) Get(ctx context.Context, catID string) (Cat, error) {
+	if err := store.fail(); err != nil {
+		return Cat{}, err
+	}
+	return store.Store.Get(ctx, catID)
+}
+
+func (store flakyStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	if err := store.fail(); err != nil {
+		return err
+	}
+	return store.Store.Iterate(ctx, yield)
+}
+
+func (store flakyStore) Save(ctx context.Context, cat Cat) error {
+	if err := store.fail(); err != nil {
+		return err
+	}
+	return store.Store.Save(ctx, cat)
+}
+
+// Store losing the reply of its first calls, the connection dropping once they were applied.
+// An iteration is lost after its first cat
+type lostReplyStore struct {
+	Store
+	lost  int
+	calls *int
+}
+
+func (store lostReplyStore) Delete(ctx context.Context, catID string) error {
+	*store.calls++
+	err := store.Store.Delete(ctx, catID)
+	if *store.calls <= store.lost {
+		return io.EOF
+	}
+	return err
+}
+
+func (store lostReplyStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	*store.calls++
+	if *store.calls <= store.lost {
+		store.Store.Iterate(ctx, func(cat Cat) bool {
+			yield(cat)
+			return false
+		})
+		return io.EOF
+	}
+	return store.Store.Iterate(ctx, yield)
+}
+
+// =============================================================================
+// STORE RETRIES TESTS
+// =============================================================================
+
+// Test the connection errors only are retried
+func TestIsTransientStoreError(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{nil, false},
+		{ErrNotFound, false},
+		{context.DeadlineExceeded, false},
+		{errors.New("WRONGTYPE Operation against a key"), false},
+		{io.EOF, true},
+		{syscall.ECONNREFUSED, true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNRESET}, true},
+	}
+
+	for _, test := range tests {
+		if transient := isTransientStoreError(test.err); transient != test.transient {
+			t.Errorf("%v: expected transient %t, got %t", test.err, test.transient, transient)
+		}
+	}
+}
+
+// Test a read is retried until it succeeds, and a save is never replayed
+func TestRetryingStore(t *testing.T) {
+	// Save original state
+	originalConfig := currentConfig()
+	defer func() {
+		// Restore original state
+		setConfig(originalConfig)
+	}()
+
+	cfg := currentConfig()
+	cfg.StoreRetries, cfg.StoreBackoff = 2, time.Millisecond
+	setConfig(cfg)
+
+	calls := 0
+	store := retryingStore{flakyStore{Store: NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}), failures: 2, err: io.EOF, calls: &calls}}
+	cat, err := store.Get(context.Background(), "id1")
+	if err != nil || cat.Name != "Toto" || calls != 3 {
+		t.Errorf("Expected the cat after 3 calls, got %v (%v) after %d", cat, err, calls)
+	}
+
+	calls = 0
+	store = retryingStore{flakyStore{Store: NewMemoryRepo(), failures: 3, err: io.EOF, calls: &calls}}
+	if _, err := store.Get(context.Background(), "id1"); err != io.EOF || calls != 3 {
+		t.Errorf("Expected to give up after 2 retries, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	if err := store.Save(context.Background(), Cat{ID: "id2"}); err != io.EOF || calls != 1 {
+		t.Errorf("Expected the save to fail at once, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	store = retryingStore{flakyStore{Store: NewMemoryRepo(), failures: 1, err: ErrNotFound, calls: &calls}}
+	if _, err := store.Get(context.Background(), "id1"); err != ErrNotFound || calls != 1 {
+		t.Errorf("Expected a missing cat not to be retried, got %v after %d calls", err, calls)
+	}
+}
+
+// Test a delete whose reply was lost succeeds on the retry, and an iteration is retried only before its first cat
+func TestRetryingStoreLostReply(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.StoreRetries, cfg.StoreBackoff = 2, time.Millisecond
+	setConfig(cfg)
+	ctx := context.Background()
+
+	calls := 0
+	store := retryingStore{lostReplyStore{Store: NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}), lost: 1, calls: &calls}}
+	if err := store.Delete(ctx, "id1"); err != nil || calls != 2 {
+		t.Errorf("Expected the retry to find the cat deleted, got %v after %d calls", err, calls)
+	}
+	calls = 0
+	store = retryingStore{lostReplyStore{Store: NewMemoryRepo(), calls: &calls}}
+	if err := store.Delete(ctx, "id1"); err != ErrNotFound || calls != 1 {
+		t.Errorf("Expected a first attempt to report the missing cat, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	store = retryingStore{lostReplyStore{Store: NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"}), lost: 1, calls: &calls}}
+	seen := 0
+	if err := store.Iterate(ctx, func(Cat) bool { seen++; return true }); err != io.EOF || calls != 1 || seen != 1 {
+		t.Errorf("Expected no retry once a cat was yielded, got %v after %d calls and %d cats", err, calls, seen)
+	}
+
+	calls = 0
+	store = retryingStore{flakyStore{Store: NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}), failures: 1, err: io.EOF, calls: &calls}}
+	seen = 0
+	if err := store.Iterate(ctx, func(Cat) bool { seen++; return true }); err != nil || calls != 2 || seen != 1 {
+		t.Errorf("Expected the iteration retried before its first cat, got %v after %d calls and %d cats", err, calls, seen)
+	}
+}
+
+// Test the retries stop when the request has no time left for the next one
+func TestRetryingStoreDeadline(t *testing.T) {
+	// Save original state
+	originalConfig := currentConfig()
+	defer func() {
+		// Restore original state
+		setConfig(originalConfig)
+	}()
+
+	cfg := currentConfig()
+	cfg.StoreRetries, cfg.StoreBackoff = 5, time.Second
+	setConfig(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	store := retryingStore{flakyStore{Store: NewMemoryRepo(), failures: 5, err: io.EOF, calls: &calls}}
+	begin := time.Now()
+	if _, err := store.Get(ctx, "id1"); err != io.EOF || calls != 1 {
+		t.Errorf("Expected no retry past the deadline, got %v after %d calls", err, calls)
+	}
+	if elapsed := time.Since(begin); elapsed > 40*time.Millisecond {
+		t.Errorf("Expected to give up right away, took %v", elapsed)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+)
+
+// Route of the app, both registered and listed by GET /api/routes from the same descriptor
+type Route struct {
+	Method      string       `json:"method"`
+	Path        string       `json:"path"`
+	Description string       `json:"description"`
+	Handler     http.Handler `json:"-"`
+}
+
+// Pattern of the route for the ServeMux, "GET /api/cats"
+func (route Route) Pattern() string {
+	return route.Method + " " + route.Path
+}
+
+// All the routes, the API ones under the base path
+func appRoutes() []Route {
+	// The UI is served with its index.html by default and loads the spec from /openapi.json
+	fsys, _ := fs.Sub(content, "swagger-ui")
+
+	var routes []Route
+	routes = []Route{
+		{"GET", "/{$}", "Home page", http.HandlerFunc(getHomeHandler)},
+		{"GET", "/favicon.ico", "Icon of the pages", http.HandlerFunc(getFavicon)},
+		{"GET", "/static/", "Assets of the home page", staticHandler()},
+		{"POST", apiPath("/cats"), "Creates a new cat", requireContentType(makeHandlerFunc(idempotentCreate(createCat)), jsonContentType, multipartContentType)},
+		{"GET", apiPath("/cats"), "Lists all cats", http.HandlerFunc(listCatsHandler)},
+		{"PATCH", apiPath("/cats"), "Patches several cats at once, each on its own", requireContentType(makeHandlerFunc(patchCats), jsonContentType)},
+		{"DELETE", apiPath("/cats"), "Deletes the cats matching the filters of the list, or all of them once confirmed", makeHandlerFunc(deleteCats)},
+		{"GET", apiPath("/cats/count"), "Counts the cats, with the same filters as the list", makeResultHandlerFunc(countCats)},
+		{"GET", apiPath("/cats/stats"), "Aggregates the whole store", makeResultHandlerFunc(catsStats)},
+		{"POST", apiPath("/cats/batch"), "Creates several cats at once, all or none", requireContentType(makeHandlerFunc(createCats), jsonContentType)},
+		{"POST", apiPath("/cats/batchGet"), "Fetches several cats at once", requireContentType(makeHandlerFunc(batchGetCats), jsonContentType)},
+		{"GET", apiPath("/cats/byYear"), "Groups the cats by birth year", makeResultHandlerFunc(catsByYear)},
+		{"GET", apiPath("/cats/random"), "Picks a random cat", makeHandlerFunc(randomCat)},
+		{"GET", apiPath("/cats/schema"), "Describes a cat as a JSON Schema", makeHandlerFunc(getCatSchema)},
+		{"GET", apiPath("/breeds"), "Lists the known breeds", makeHandlerFunc(listBreeds)},
+		{"GET", apiPath("/cats/{catId}"), "Gets a cat details", makeHandlerFunc(getCat)},
+		{"GET", apiPath("/cats/{catId}/photo"), "Gets the photo of a cat", http.HandlerFunc(getCatPhoto)},
+		{"GET", apiPath("/cats/events"), "Streams the cat creations and deletions live", http.HandlerFunc(streamCatEvents)},
+		{"PUT", apiPath("/cats/{catId}"), "Creates or replaces a cat under a client chosen ID", requireContentType(makeHandlerFunc(putCat), jsonContentType)},
+		{"PATCH", apiPath("/cats/{catId}"), "Partially updates a cat", requireContentType(makeHandlerFunc(patchCat), jsonContentType, mergePatchContentType, jsonPatchContentType)},
+		{"DELETE", apiPath("/cats/{catId}"), "Deletes a cat", makeHandlerFunc(deleteCat)},
+		{"GET", apiPath("/export"), "Exports all the cats", http.HandlerFunc(exportCats)},
+		{"POST", apiPath("/import"), "Imports a dataset, nothing changes if any cat is invalid", requireContentType(makeHandlerFunc(importCats), jsonContentType)},
+		{"GET", apiPath("/routes"), "Lists the routes of the server", makeResultHandlerFunc(func(req *http.Request) (any, error) {
+			return routes, nil
+		})},
+
+		{"GET", "/swagger/", "Swagger UI of the API", requireSpec(http.StripPrefix("/swagger", http.FileServer(http.FS(fsys))))},
+		{"GET", "/openapi.json", "OpenAPI spec of the API", http.HandlerFunc(getSpecHandler)},
+		{"POST", apiPath("/admin/spec/reload"), "Reloads the spec served by /openapi.json and the Swagger UI", requireAPIKey(makeHandlerFunc(reloadSpecHandler))},
+		{"POST", apiPath("/admin/backfill"), "Fills the missing birth dates", requireAPIKey(makeResultHandlerFunc(backfillBirthDates))},
+		{"GET", apiPath("/admin/runtime"), "Runtime figures without a metrics stack", requireAPIKey(makeResultHandlerFunc(getRuntimeStats))},
+		{"GET", apiPath("/admin/dump"), "Dumps the store internals with --admin-dump", requireAPIKey(makeResultHandlerFunc(dumpStore))},
+		{"GET", "/logs", "Tails the application logs", requireAPIKey(makeHandlerFunc(getLogs))},
+		{"GET", "/health", "Liveness probe", makeHandlerFunc(getHealth)},
+		{"GET", "/ready", "Readiness probe", makeHandlerFunc(getReady)},
+	}
+	return routes
+}
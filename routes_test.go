@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Routes of the server without operation in the spec
+var undocumentedRoutes = map[string]bool{
+	"GET /{$}":          true,
+	"GET /favicon.ico":  true,
+	"GET /static/":      true,
+	"GET /swagger/":     true,
+	"GET /openapi.json": true,
+}
+
+// =============================================================================
+// ROUTES TESTS
+// =============================================================================
+
+// Test the routes are listed as registered
+func TestListRoutes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/routes", nil))
+
+	var routes []Route
+	if err := json.NewDecoder(rec.Body).Decode(&routes); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected the routes, got %d (%v)", rec.Code, err)
+	}
+	if len(routes) != len(appRoutes()) {
+		t.Errorf("Expected %d routes, got %d", len(appRoutes()), len(routes))
+	}
+	for _, route := range routes {
+		if route.Method == "" || route.Path == "" || route.Description == "" {
+			t.Errorf("Expected a complete route, got %+v", route)
+		}
+	}
+}
+
+// Test every route has its operation in the spec and the other way around
+func TestRoutesMatchSpec(t *testing.T) {
+	yamlSpec, err := specFS.ReadFile("openapi.yml")
+	if err != nil {
+		t.Fatalf("Failed to read the spec: %v", err)
+	}
+	var spec struct {
+		Paths map[string]map[string]any
+	}
+	if err := yaml.Unmarshal(yamlSpec, &spec); err != nil {
+		t.Fatalf("Failed to parse the spec: %v", err)
+	}
+
+	// The spec paths are relative to the base path but for the probes and the logs
+	registered := map[string]bool{}
+	for _, route := range appRoutes() {
+		if undocumentedRoutes[route.Pattern()] {
+			continue
+		}
+		specPath := strings.TrimPrefix(route.Path, "/api")
+		registered[route.Method+" "+specPath] = true
+		if _, documented := spec.Paths[specPath][strings.ToLower(route.Method)]; !documented {
+			t.Errorf("Expected %s in the spec", route.Pattern())
+		}
+	}
+	for specPath, operations := range spec.Paths {
+		for method := range operations {
+			if method != "parameters" && method != "servers" && !registered[strings.ToUpper(method)+" "+specPath] {
+				t.Errorf("Expected a route for %s %s of the spec", strings.ToUpper(method), specPath)
+			}
+		}
+	}
+}
+
+// Test the Swagger UI and the spec it loads are served
+func TestSwaggerUI(t *testing.T) {
+	app := newApp()
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/swagger/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `id="swagger-ui"`) {
+		t.Error("Expected the Swagger UI index.html")
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/swagger/swagger-initializer.js", nil))
+	if !strings.Contains(rec.Body.String(), `"../openapi.json"`) {
+		t.Error("Expected the UI to load the spec from /openapi.json")
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var spec map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&spec); err != nil {
+		t.Fatalf("Expected a JSON spec: %v", err)
+	}
+	if _, found := spec["openapi"]; !found {
+		t.Error("Expected an 'openapi' field in the spec")
+	}
+}
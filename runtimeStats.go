@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var startedAt = time.Now()
+
+// Requests served since the start, counted by the access log middleware
+type RequestCounters struct {
+	total atomic.Int64
+	// Indexed by the first digit of the status, 1xx to 5xx
+	byClass [6]atomic.Int64
+}
+
+func (counters *RequestCounters) Record(code int) {
+	counters.total.Add(1)
+	if class := code / 100; class >= 1 && class <= 5 {
+		counters.byClass[class].Add(1)
+	}
+}
+
+// Count of each status class, "2xx": 42, the classes never answered are left out
+func (counters *RequestCounters) ByClass() map[string]int64 {
+	classes := map[string]int64{}
+	for class := 1; class <= 5; class++ {
+		if count := counters.byClass[class].Load(); count > 0 {
+			classes[strconv.Itoa(class)+"xx"] = count
+		}
+	}
+	return classes
+}
+
+var servedRequests RequestCounters
+
+// Operational figures of the process, without a metrics stack
+type RuntimeStats struct {
+	UptimeSeconds   float64          `json:"uptimeSeconds"`
+	Requests        int64            `json:"requests"`
+	RequestsByClass map[string]int64 `json:"requestsByClass"`
+	Goroutines      int              `json:"goroutines"`
+	HeapAllocBytes  uint64           `json:"heapAllocBytes"`
+}
+
+// The request being served is not counted yet, the access log records it once answered
+func getRuntimeStats(req *http.Request) (any, error) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return RuntimeStats{
+		UptimeSeconds:   time.Since(startedAt).Seconds(),
+		Requests:        servedRequests.total.Load(),
+		RequestsByClass: servedRequests.ByClass(),
+		Goroutines:      runtime.NumGoroutine(),
+		HeapAllocBytes:  memStats.HeapAlloc,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// =============================================================================
+// RUNTIME STATS TESTS
+// =============================================================================
+
+// Test the counters group the statuses by class
+func TestRequestCounters(t *testing.T) {
+	var counters RequestCounters
+	for _, code := range []int{200, 201, 404, 500, 999} {
+		counters.Record(code)
+	}
+
+	classes := counters.ByClass()
+	if counters.total.Load() != 5 || classes["2xx"] != 2 || classes["4xx"] != 1 || classes["5xx"] != 1 || len(classes) != 3 {
+		t.Errorf("Expected 5 requests in 3 classes, got %d in %v", counters.total.Load(), classes)
+	}
+}
+
+// Test the endpoint reports the requests answered before it, behind the API key
+func TestRuntimeStatsEndpoint(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	app := newAppWithStore(NewMemoryRepo())
+	get := func(target string, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var before RuntimeStats
+	json.NewDecoder(get("/api/admin/runtime", "").Body).Decode(&before)
+	get("/api/cats/missing", "")
+
+	var after RuntimeStats
+	rec := get("/api/admin/runtime", "")
+	json.NewDecoder(rec.Body).Decode(&after)
+	if rec.Code != http.StatusOK || after.Requests < before.Requests+2 || after.RequestsByClass["4xx"] < before.RequestsByClass["4xx"]+1 {
+		t.Errorf("Expected the stats and the 404 counted, got %d with %+v after %+v", rec.Code, after, before)
+	}
+	if after.UptimeSeconds <= 0 || after.Goroutines == 0 || after.HeapAllocBytes == 0 {
+		t.Errorf("Expected the process figures, got %+v", after)
+	}
+
+	cfg := currentConfig()
+	cfg.APIKey = "secret"
+	setConfig(cfg)
+	if rec := get("/api/admin/runtime", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 without the API key, got %d", rec.Code)
+	}
+	if rec := get("/api/admin/runtime", "secret"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a 200 with the API key, got %d", rec.Code)
+	}
+}
@@ -131,8 +131,8 @@ echo -e "${GREEN}✅ Unit tests completed${NC}"
 # =============================================================================
 print_section "Integration Tests"
 
-echo "🔗 Running integration tests..."
-go test -v ./test/integration/... -coverprofile=integration-coverage.out 2>/dev/null || echo "No integration tests to run"
+echo "🔗 Running integration tests (the app served in-process)..."
+go test -v -run 'TestNewTestServer|TestServedEndpoints' . -coverprofile=integration-coverage.out
 
 echo -e "${GREEN}✅ Integration tests completed${NC}"
 
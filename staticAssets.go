@@ -0,0 +1,42 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Assets of the web pages, served under /static/
+//
+//go:embed static
+var staticFS embed.FS
+
+// The embedded assets only change with a new binary
+const staticMaxAge = 24 * time.Hour
+
+func setStaticCaching(header http.Header) {
+	header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(staticMaxAge.Seconds())))
+}
+
+// Serves the embedded static tree, its types told by the extensions
+func staticHandler() http.Handler {
+	// Not in the builtin MIME types, and named differently by the systems
+	mime.AddExtensionType(".ico", "image/x-icon")
+
+	assets, _ := fs.Sub(staticFS, "static")
+	files := http.StripPrefix("/static", http.FileServer(http.FS(assets)))
+
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		setStaticCaching(res.Header())
+		files.ServeHTTP(res, req)
+	})
+}
+
+// Where the browsers look for the icon, whatever the page says
+func getFavicon(res http.ResponseWriter, req *http.Request) {
+	setStaticCaching(res.Header())
+	http.ServeFileFS(res, req, staticFS, "static/favicon.ico")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// STATIC ASSETS TESTS
+// =============================================================================
+
+// Test the favicon and the static tree are served typed and cacheable
+func TestStaticAssets(t *testing.T) {
+	app := newApp()
+
+	tests := []struct {
+		target      string
+		contentType string
+	}{
+		{"/favicon.ico", "image/x-icon"},
+		{"/static/favicon.ico", "image/x-icon"},
+		{"/static/home.css", "text/css"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, httptest.NewRequest("GET", test.target, nil))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+			}
+			if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.contentType) {
+				t.Errorf("Expected Content-Type %s, got %s", test.contentType, contentType)
+			}
+			if cacheControl := rec.Header().Get("Cache-Control"); !strings.Contains(cacheControl, "max-age=86400") {
+				t.Errorf("Expected a cacheable asset, got %q", cacheControl)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/static/missing.png", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(rec.Body.String(), `href="/favicon.ico"`) || !strings.Contains(rec.Body.String(), `href="/static/home.css"`) {
+		t.Error("Expected the home page to link the static assets")
+	}
+}
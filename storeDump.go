@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// What the store holds and how it is reached, for troubleshooting a running server
+type StoreDump struct {
+	Backend string `json:"backend"`
+	// Layers around the backend from the outermost, like "traced" or "retrying"
+	Wrappers []string `json:"wrappers"`
+	Count    int      `json:"count"`
+	// Raw contents of the in-memory map, keyed by ID
+	Cats  map[string]Cat `json:"cats,omitempty"`
+	Redis *RedisDump     `json:"redis,omitempty"`
+}
+
+// Connection of the Redis store and the figures of its pool
+type RedisDump struct {
+	Addr       string `json:"addr"`
+	Hits       uint32 `json:"hits"`
+	Misses     uint32 `json:"misses"`
+	Timeouts   uint32 `json:"timeouts"`
+	TotalConns uint32 `json:"totalConns"`
+	IdleConns  uint32 `json:"idleConns"`
+	StaleConns uint32 `json:"staleConns"`
+}
+
+// Backend describing its own internals
+type dumpableStore interface {
+	Dump(ctx context.Context) (StoreDump, error)
+}
+
+func (repo *MemoryRepo) Dump(ctx context.Context) (StoreDump, error) {
+	repo.lock.RLock()
+	defer repo.lock.RUnlock()
+	cats := make(map[string]Cat, len(repo.cats))
+	for catID, cat := range repo.cats {
+		cats[catID] = cat
+	}
+	return StoreDump{Backend: "memory", Count: len(cats), Cats: cats}, nil
+}
+
+func (repo *RedisRepo) Dump(ctx context.Context) (StoreDump, error) {
+	count, err := repo.client.SCard(ctx, redisIndexKey).Result()
+	if err != nil {
+		return StoreDump{}, err
+	}
+	stats := repo.client.PoolStats()
+	return StoreDump{
+		Backend: "redis",
+		Count:   int(count),
+		Redis: &RedisDump{
+			Addr:       repo.client.Options().Addr,
+			Hits:       stats.Hits,
+			Misses:     stats.Misses,
+			Timeouts:   stats.Timeouts,
+			TotalConns: stats.TotalConns,
+			IdleConns:  stats.IdleConns,
+			StaleConns: stats.StaleConns,
+		},
+	}, nil
+}
+
+// Peels the layers the store is served through down to its backend
+func unwrapStore(store Store) (Store, []string) {
+	wrappers := []string{}
+	for {
+		switch layer := store.(type) {
+		case tracedStore:
+			wrappers, store = append(wrappers, "traced"), layer.Store
+		case sortedStore:
+			wrappers, store = append(wrappers, "sorted"), layer.Store
+		case retryingStore:
+			wrappers, store = append(wrappers, "retrying"), layer.Store
+		default:
+			return store, wrappers
+		}
+	}
+}
+
+// Dumps the store with --admin-dump, the endpoint staying a 404 otherwise. A backend which does
+// not describe itself is only named and counted
+func dumpStore(req *http.Request) (any, error) {
+	if !currentConfig().AdminDump {
+		return nil, clientError{sentinel: ErrNotFound, code: "dump_disabled"}
+	}
+
+	backend, wrappers := unwrapStore(storeOf(req.Context()))
+	var dump StoreDump
+	if dumpable, ok := backend.(dumpableStore); ok {
+		var err error
+		if dump, err = dumpable.Dump(req.Context()); err != nil {
+			return nil, err
+		}
+	} else {
+		cats, err := backend.List(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		dump = StoreDump{Backend: fmt.Sprintf("%T", backend), Count: len(cats)}
+	}
+	dump.Wrappers = wrappers
+	Logger.WithFields(Fields{"backend": dump.Backend, "count": dump.Count}).Info("Store dumped")
+	return dump, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// =============================================================================
+// STORE DUMP TESTS
+// =============================================================================
+
+// Test the dump stays a 404 until enabled, then answers the raw contents behind the API key
+func TestDumpStore(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	app := newAppWithStore(tracedStore{sortedStore{NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}, Cat{ID: "id2", Name: "Felix"})}})
+	get := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/admin/dump", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(""); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected status code %d while disabled, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	cfg := currentConfig()
+	cfg.AdminDump = true
+	cfg.APIKey = "secret"
+	setConfig(cfg)
+	if rec := get("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without the key, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec := get("secret")
+	var dump StoreDump
+	if err := json.NewDecoder(rec.Body).Decode(&dump); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the dump, got %d (%v)", rec.Code, err)
+	}
+	if dump.Backend != "memory" || !slices.Equal(dump.Wrappers, []string{"traced", "sorted"}) {
+		t.Errorf("Expected the memory backend under 2 layers, got %s under %v", dump.Backend, dump.Wrappers)
+	}
+	if dump.Count != 2 || dump.Cats["id1"].Name != "Toto" || dump.Cats["id2"].Name != "Felix" {
+		t.Errorf("Expected the 2 cats keyed by ID, got %d: %v", dump.Count, dump.Cats)
+	}
+}
+
+// Test a backend which does not describe itself is still named and counted
+func TestDumpUnknownStore(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+	cfg := currentConfig()
+	cfg.AdminDump = true
+	setConfig(cfg)
+
+	app := newAppWithStore(readOnlyStore{NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})})
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/admin/dump", nil))
+	var dump StoreDump
+	if err := json.NewDecoder(rec.Body).Decode(&dump); rec.Code != http.StatusOK || err != nil {
+		t.Fatalf("Expected the dump, got %d (%v)", rec.Code, err)
+	}
+	if dump.Backend != "main.readOnlyStore" || dump.Count != 1 || dump.Cats != nil {
+		t.Errorf("Expected the store named and counted only, got %+v", dump)
+	}
+}
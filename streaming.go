@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Encodings of a stream of cats
+const (
+	streamArray  = "array"  // A JSON array, with manual brackets
+	streamNDJSON = "ndjson" // One JSON cat per line
+)
+
+// Writes the matching cats as the store iterates them, so neither the whole store nor its encoding
+// is held. The status goes with the first cat: a store failing before it is answered as usual,
+// one failing later cuts the stream short, the truncated body telling the client
+func streamCats(res http.ResponseWriter, req *http.Request, encoding string, match func(Cat) bool) {
+	flusher, _ := res.(http.Flusher)
+	var encoder *json.Encoder
+	start := func() {
+		if encoding == streamNDJSON {
+			res.Header().Set("content-type", ndjsonContentType)
+		} else {
+			res.Header().Set("content-type", jsonResponseType)
+		}
+		res.WriteHeader(http.StatusOK)
+		if encoding == streamArray {
+			res.Write([]byte("["))
+		}
+		encoder = json.NewEncoder(res)
+	}
+
+	streamed := 0
+	err := storeOf(req.Context()).Iterate(req.Context(), func(cat Cat) bool {
+		if match != nil && !match(cat) {
+			return true
+		}
+		if encoder == nil {
+			start()
+		} else if encoding == streamArray {
+			res.Write([]byte(","))
+		}
+		// A failed write is a client gone, there is nobody left to stream to
+		if err := encoder.Encode(outputNaming(cat)); err != nil {
+			return false
+		}
+
+		streamed++
+		if flusher != nil && streamed%exportFlushEvery == 0 {
+			flusher.Flush()
+		}
+		return true
+	})
+	if err != nil {
+		if encoder == nil {
+			code, body := storeFailure(req, err)
+			writeResponse(res, req, code, body)
+		} else {
+			Logger.Errorf("Stream of the cats cut short after %d cats: %v", streamed, err)
+		}
+		return
+	}
+
+	if encoder == nil {
+		start()
+	}
+	if encoding == streamArray {
+		res.Write([]byte("]"))
+		if currentConfig().JSONNewline {
+			res.Write([]byte("\n"))
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
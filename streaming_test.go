@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Store failing its iteration after handing some cats
+type brokenIterationStore struct {
+	Store
+	handed int
+}
+
+func (store brokenIterationStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	for idx := range store.handed {
+		if !yield(Cat{ID: "id" + string(rune('1'+idx)), Name: "Toto"}) {
+			return nil
+		}
+	}
+	return errors.New("connection reset")
+}
+
+// =============================================================================
+// STREAMING TESTS
+// =============================================================================
+
+// Test the iteration stops as soon as the caller has enough
+func TestMemoryIterate(t *testing.T) {
+	repo := NewMemoryRepo(Cat{ID: "id1"}, Cat{ID: "id2"}, Cat{ID: "id3"})
+
+	seen := 0
+	err := repo.Iterate(context.Background(), func(cat Cat) bool {
+		seen++
+		return seen < 2
+	})
+	if err != nil || seen != 2 {
+		t.Errorf("Expected the iteration stopped after 2 cats, got %d (%v)", seen, err)
+	}
+}
+
+// Test the export and the NDJSON list stream what the store iterates
+func TestStreamCats(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey"},
+		Cat{ID: "id2", Name: "Felix", Color: "Black"},
+	))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	var exported []Cat
+	if err := json.Unmarshal(rec.Body.Bytes(), &exported); err != nil || len(exported) != 2 {
+		t.Errorf("Expected the 2 cats exported as an array, got %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats?format=ndjson&color=grey", nil))
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"id1"`) {
+		t.Errorf("Expected the grey cat streamed alone, got %q", rec.Body.String())
+	}
+
+	emptyApp := newAppWithStore(NewMemoryRepo())
+	rec = httptest.NewRecorder()
+	emptyApp.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Errorf("Expected an empty array, got %d with %q", rec.Code, rec.Body.String())
+	}
+}
+
+// Test a store failing before the first cat is answered, one failing later cuts the stream
+func TestStreamCatsStoreFailure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newAppWithStore(brokenIterationStore{Store: NewMemoryRepo()}).ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	newAppWithStore(brokenIterationStore{Store: NewMemoryRepo(), handed: 2}).ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	var exported []Cat
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &exported) == nil {
+		t.Errorf("Expected a truncated array, got %d with %q", rec.Code, rec.Body.String())
+	}
+}
+
+// Test the list streams the whole cats as NDJSON on demand
+func TestListCatsNDJSON(t *testing.T) {
+	store := NewMemoryRepo(
+		Cat{ID: "id1", Name: "Toto", Color: "Grey"},
+		Cat{ID: "id2", Name: "Felix", Color: "Black"},
+		Cat{ID: "id3", Name: "Tom", Color: "grey"},
+	)
+	app := newAppWithStore(store)
+
+	tests := []struct {
+		name     string
+		target   string
+		accept   string
+		expected int
+	}{
+		{"format parameter", "/api/cats?format=ndjson", "", 3},
+		{"accept header", "/api/cats", "application/json;q=0.5, application/x-ndjson", 3},
+		{"filtered", "/api/cats?format=ndjson&color=grey", "", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.target, nil)
+			req.Header.Set("Accept", test.accept)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, rec.Code)
+			}
+			if contentType := rec.Header().Get("Content-Type"); contentType != "application/x-ndjson" {
+				t.Errorf("Expected NDJSON, got %s", contentType)
+			}
+			if !rec.Flushed {
+				t.Error("Expected the stream to be flushed")
+			}
+
+			lines := strings.Split(strings.TrimSuffix(rec.Body.String(), "\n"), "\n")
+			if len(lines) != test.expected {
+				t.Fatalf("Expected %d lines, got %q", test.expected, rec.Body.String())
+			}
+			for _, line := range lines {
+				var cat Cat
+				if err := json.Unmarshal([]byte(line), &cat); err != nil || cat.ID == "" || cat.Name == "" {
+					t.Errorf("Expected a whole cat per line, got %q", line)
+				}
+			}
+		})
+	}
+
+	// The IDs array stays the default
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if contentType := rec.Header().Get("Content-Type"); contentType != jsonResponseType {
+		t.Errorf("Expected the JSON list by default, got %s", contentType)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Field of a request body not known by the target, only refused with --strict-json
+type UnknownFieldError struct {
+	Field string
+}
+
+func (err UnknownFieldError) Error() string {
+	return "Unknown field: " + err.Field
+}
+
+// The decoder only reports the unknown fields in the text of its error
+var unknownFieldMessage = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+
+// Decodes a document, refusing the unknown fields when --strict-json is on
+func decodeStrictly(body io.Reader, target any) error {
+	decoder := json.NewDecoder(body)
+	if currentConfig().StrictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	err := decoder.Decode(target)
+	if err == nil {
+		return nil
+	}
+	if match := unknownFieldMessage.FindStringSubmatch(err.Error()); match != nil {
+		return UnknownFieldError{Field: clientFieldName(match[1])}
+	}
+	return err
+}
+
+// Field name as the client wrote it, birth_date with --field-naming snake
+func clientFieldName(field string) string {
+	if currentConfig().FieldNaming == namingSnake {
+		return camelToSnake(field)
+	}
+	return field
+}
+
+// JSON names of the fields of a struct, from their tags
+func jsonFieldNames(value any) []string {
+	var names []string
+	kind := reflect.TypeOf(value)
+	for idx := 0; idx < kind.NumField(); idx++ {
+		name, _, _ := strings.Cut(kind.Field(idx).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// A merge patch is decoded as a map, so its keys are checked against the cat fields here
+func checkPatchFields(patch map[string]json.RawMessage) error {
+	if !currentConfig().StrictJSON {
+		return nil
+	}
+	known := jsonFieldNames(Cat{})
+	for _, field := range slices.Sorted(maps.Keys(patch)) {
+		if !slices.Contains(known, field) {
+			return UnknownFieldError{Field: clientFieldName(field)}
+		}
+	}
+	return nil
+}
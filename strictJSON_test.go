@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// =============================================================================
+// STRICT JSON TESTS
+// =============================================================================
+
+// Test the unknown fields are ignored by default and refused by name with --strict-json
+func TestStrictJSON(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, newJSONRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := send("POST", "/api/cats", `{"name": "Tata", "colour": "Grey"}`); rec.Code != http.StatusCreated {
+		t.Errorf("Expected the unknown field ignored by default, got %d with %s", rec.Code, rec.Body.String())
+	}
+
+	cfg := currentConfig()
+	cfg.StrictJSON = true
+	setConfig(cfg)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		field  string
+	}{
+		{"POST", "/api/cats", `{"name": "Titi", "colour": "Grey"}`, "colour"},
+		{"PUT", "/api/cats/id1", `{"id": "id1", "name": "Toto", "weight": 4200}`, "weight"},
+		{"PATCH", "/api/cats/id1", `{"nmae": "Tutu"}`, "nmae"},
+		{"POST", "/api/import", `[{"name": "Tete", "age": 3}]`, "age"},
+	}
+	for _, test := range tests {
+		rec := send(test.method, test.target, test.body)
+		if expected := `"Unknown field: ` + test.field + `"`; rec.Code != http.StatusBadRequest || strings.TrimSpace(rec.Body.String()) != expected {
+			t.Errorf("%s %s: expected a 400 naming %s, got %d with %s", test.method, test.target, test.field, rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := send("PATCH", "/api/cats/id1", `{"color": "Black"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected a known field accepted, got %d with %s", rec.Code, rec.Body.String())
+	}
+
+	// The field is named as the client wrote it
+	cfg.FieldNaming = namingSnake
+	setConfig(cfg)
+	if rec := send("POST", "/api/cats", `{"name": "Tyty", "birth_dates": "2020-01-01"}`); !strings.Contains(rec.Body.String(), "birth_dates") {
+		t.Errorf("Expected the snake_case field named, got %d with %s", rec.Code, rec.Body.String())
+	}
+}
@@ -5,6 +5,7 @@ package apitests
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 	"time"
 )
@@ -21,17 +22,22 @@ var baseUrl = "http://localhost:8080/api"
 // Global client with a proper timeout
 var client = &http.Client{Timeout: 10 * time.Second}
 
-// Wrapper to HTTP API calls, does the error handling and JSON decoding
-func call(method, path string, reqBody any, code *int, result any) error {
+// Wrapper to HTTP API calls, does the error handling and JSON decoding.
+// A nil reqBody sends no body at all, the raw response is returned for the non JSON answers
+func call(method, path string, reqBody any, code *int, result any) ([]byte, error) {
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return err
+	var body io.Reader
+	if reqBody != nil {
+		jsonBody, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(jsonBody)
 	}
 
-	req, err := http.NewRequest(method, baseUrl+path, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest(method, baseUrl+path, body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Set appropriate headers
@@ -43,7 +49,7 @@ func call(method, path string, reqBody any, code *int, result any) error {
 	// send the request
 	res, err := client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer res.Body.Close()
 
@@ -51,11 +57,14 @@ func call(method, path string, reqBody any, code *int, result any) error {
 		*code = res.StatusCode
 	}
 
-	if result != nil {
-		err = json.NewDecoder(res.Body).Decode(result)
-		// Don't treat JSON decode errors as fatal for API tests
-		// Sometimes we get plain text responses for error cases
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if result != nil && len(raw) > 0 {
+		err = json.Unmarshal(raw, result)
 	}
 
-	return err
+	return raw, err
 }
\ No newline at end of file
@@ -1,168 +0,0 @@
-package integration
-
-import (
-	"encoding/json"
-	"net/http"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
-	"strings"
-	"testing"
-	"time"
-)
-
-// Get the project root directory
-func getProjectRoot() string {
-	_, filename, _, _ := runtime.Caller(0)
-	return filepath.Join(filepath.Dir(filename), "..", "..")
-}
-
-func TestRealHomeHandler(t *testing.T) {
-	// Start the actual application in background and test it
-	root := getProjectRoot()
-
-	// Build the application
-	cmd := exec.Command("go", "build", "-o", "testapp", ".")
-	cmd.Dir = root
-	err := cmd.Run()
-	if err != nil {
-		t.Fatalf("Failed to build application: %v", err)
-	}
-	defer os.Remove(filepath.Join(root, "testapp"))
-
-	// Start the application in background
-	app := exec.Command("./testapp")
-	app.Dir = root
-	err = app.Start()
-	if err != nil {
-		t.Fatalf("Failed to start application: %v", err)
-	}
-	defer func() {
-		if app.Process != nil {
-			app.Process.Kill()
-		}
-	}()
-
-	// Wait a moment for the server to start
-	time.Sleep(2 * time.Second)
-
-	// Test the home endpoint
-	resp, err := http.Get("http://localhost:8080/")
-	if err != nil {
-		t.Fatalf("Failed to make request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
-	}
-
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") {
-		t.Errorf("Expected Content-Type to contain text/html, got %s", contentType)
-	}
-}
-
-func TestYml2JsonWithRealFile(t *testing.T) {
-	// Test the actual yml2json function with the real openapi.yml file
-	root := getProjectRoot()
-
-	// Change to project directory
-	originalDir, _ := os.Getwd()
-	defer os.Chdir(originalDir)
-
-	err := os.Chdir(root)
-	if err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-
-	// Check if openapi.yml exists
-	if _, err := os.Stat("openapi.yml"); os.IsNotExist(err) {
-		t.Skip("openapi.yml not found, skipping real file test")
-	}
-
-	// Test that we can run the yml2json function by building and running it
-	// Create a simple test program that calls yml2json
-	testProgram := `
-package main
-
-import (
-	"encoding/json"
-	"gopkg.in/yaml.v3"
-	"io/ioutil"
-	"log"
-	"os"
-)
-
-func yml2json() {
-	yfile, err := ioutil.ReadFile("openapi.yml")
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var data any
-	err = yaml.Unmarshal(yfile, &data)
-	if err != nil {
-		log.Fatal(err)
-	}
-	enc := json.NewEncoder(os.Stdout)
-	enc.SetIndent("", "\t")
-	enc.Encode(data)
-}
-
-func main() {
-	yml2json()
-}
-`
-
-	// Write test program
-	err = os.WriteFile("test_yml2json.go", []byte(testProgram), 0644)
-	if err != nil {
-		t.Fatalf("Failed to write test program: %v", err)
-	}
-	defer os.Remove("test_yml2json.go")
-
-	// Run the test program
-	cmd := exec.Command("go", "run", "test_yml2json.go")
-	output, err := cmd.Output()
-	if err != nil {
-		t.Fatalf("Failed to run yml2json test: %v", err)
-	}
-
-	// Verify output is valid JSON
-	outputStr := string(output)
-	if !strings.Contains(outputStr, "openapi") {
-		t.Error("Output should contain openapi specification")
-	}
-
-	// Try to parse as JSON to verify it's valid
-	var jsonData interface{}
-	err = json.Unmarshal(output, &jsonData)
-	if err != nil {
-		t.Errorf("Output is not valid JSON: %v", err)
-	}
-}
-
-func TestAPIEndpointsIntegration(t *testing.T) {
-	// This test requires the app to be running
-	// We'll test the concept with a simple HTTP client
-
-	tests := []struct {
-		endpoint string
-		method   string
-		expected int
-	}{
-		{"/", "GET", http.StatusOK},
-		{"/swagger/", "GET", http.StatusOK}, // Assuming swagger is served
-		{"/logs", "GET", http.StatusOK},     // Assuming logs endpoint exists
-	}
-
-	// This is a conceptual test - in a real scenario you'd start the server
-	for _, test := range tests {
-		t.Run(test.method+"_"+test.endpoint, func(t *testing.T) {
-			// Mock the concept of testing real endpoints
-			t.Logf("Would test %s %s expecting %d", test.method, test.endpoint, test.expected)
-		})
-	}
-}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Real app served in-process over HTTP with a memory store of the given cats, no binary to build
+// nor startup to wait for. Returns the base URL, like http://127.0.0.1:41234, and the cleanup func
+func NewTestServer(cats ...Cat) (string, func()) {
+	server := httptest.NewServer(newAppWithStore(NewMemoryRepo(cats...)))
+	return server.URL, server.Close
+}
+
+// =============================================================================
+// TEST SERVER TESTS
+// =============================================================================
+
+// Test a cat goes through its life cycle over real HTTP requests
+func TestNewTestServer(t *testing.T) {
+	baseURL, cleanup := NewTestServer(Cat{ID: "id1", Name: "Toto"})
+	defer cleanup()
+
+	res, err := http.Post(baseURL+"/api/cats", jsonContentType, strings.NewReader(`{"name": "Tata"}`))
+	if err != nil {
+		t.Fatalf("Failed to create the cat: %v", err)
+	}
+	var created Cat
+	json.NewDecoder(res.Body).Decode(&created)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated || created.ID == "" {
+		t.Fatalf("Expected the cat created, got %d with %+v", res.StatusCode, created)
+	}
+
+	res, err = http.Get(baseURL + "/api/cats")
+	if err != nil {
+		t.Fatalf("Failed to list the cats: %v", err)
+	}
+	var ids []string
+	json.NewDecoder(res.Body).Decode(&ids)
+	res.Body.Close()
+	if len(ids) != 2 {
+		t.Errorf("Expected the seeded and the created cats, got %v", ids)
+	}
+
+	req, _ := http.NewRequest("DELETE", baseURL+"/api/cats/"+created.ID, nil)
+	if res, err = http.DefaultClient.Do(req); err != nil {
+		t.Fatalf("Failed to delete the cat: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected the cat deleted, got %d", res.StatusCode)
+	}
+
+	res, err = http.Get(baseURL + "/api/cats/" + created.ID)
+	if err != nil {
+		t.Fatalf("Failed to get the cat: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the deleted cat gone, got %d", res.StatusCode)
+	}
+}
+
+// Test the pages and the tools are served over real HTTP requests, the home as HTML
+func TestServedEndpoints(t *testing.T) {
+	baseURL, cleanup := NewTestServer()
+	defer cleanup()
+
+	tests := []struct {
+		path        string
+		contentType string
+	}{
+		{"/", "text/html"},
+		{"/swagger/", "text/html"},
+		{"/logs", ""},
+	}
+
+	for _, test := range tests {
+		res, err := http.Get(baseURL + test.path)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", test.path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected status code %d, got %d", test.path, http.StatusOK, res.StatusCode)
+		}
+		if contentType := res.Header.Get("Content-Type"); !strings.Contains(contentType, test.contentType) {
+			t.Errorf("%s: expected Content-Type %s, got %s", test.path, test.contentType, contentType)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// Requests left unbounded, their responses stream for as long as needed
+func isTimeoutExempt(req *http.Request) bool {
+	switch req.URL.Path {
+	case apiPath("/export"), apiPath("/cats/events"):
+		return true
+	case apiPath("/cats"):
+		return req.Method == http.MethodGet && wantsNDJSON(req)
+	}
+	return false
+}
+
+// Holds a response until the handler completes in time
+type bufferedWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	code   int
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+// Bounds each request by --request-timeout: the context given to the handler, and so to the store,
+// is cancelled and the client gets a 503 once the time is over
+func timeoutRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := currentConfig().RequestTimeout
+		if timeout <= 0 || isTimeoutExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		buffered := &bufferedWriter{header: http.Header{}}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					panicked <- err
+				}
+			}()
+			next.ServeHTTP(buffered, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case err := <-panicked:
+			panic(err)
+		case <-done:
+			for key, values := range buffered.header {
+				w.Header()[key] = values
+			}
+			if buffered.code == 0 {
+				buffered.code = http.StatusOK
+			}
+			w.WriteHeader(buffered.code)
+			w.Write(buffered.body.Bytes())
+		case <-ctx.Done():
+			Logger.Warnf("Request '%s %s' stopped after %v", r.Method, r.URL.Path, timeout)
+			writeResponse(w, r, http.StatusServiceUnavailable, ErrorBody{Error: localize(r, "request_timed_out")})
+		}
+	})
+}
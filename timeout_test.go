@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Store answering only once the request context is over
+type stalledStore struct {
+	Store
+	cancelled chan error
+}
+
+func (store stalledStore) List(ctx context.Context) ([]Cat, error) {
+	<-ctx.Done()
+	store.cancelled <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+// =============================================================================
+// REQUEST TIMEOUT TESTS
+// =============================================================================
+
+// Test a slow request gets a JSON 503 and its store call is cancelled
+func TestRequestTimeout(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	store := stalledStore{Store: NewMemoryRepo(), cancelled: make(chan error, 1)}
+	catsStore = store
+	cfg := currentConfig()
+	cfg.RequestTimeout = 20 * time.Millisecond
+	setConfig(cfg)
+
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status code %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	var body ErrorBody
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error != "Request timed out" {
+		t.Errorf("Expected a JSON timeout error, got %v (%v)", body, err)
+	}
+
+	select {
+	case err := <-store.cancelled:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected the store call to hit the deadline, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("The store call was abandoned instead of cancelled")
+	}
+}
+
+// Test the export and the fast requests are not affected
+func TestRequestTimeoutPassThrough(t *testing.T) {
+	// Save original state
+	originalConfig := currentConfig()
+	defer func() {
+		// Restore original state
+		setConfig(originalConfig)
+	}()
+
+	cfg := currentConfig()
+	cfg.RequestTimeout = 20 * time.Millisecond
+	setConfig(cfg)
+	slow := timeoutRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("X-Slow", "done")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	rec := httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest("GET", "/api/export", nil))
+	if rec.Code != http.StatusAccepted || rec.Header().Get("X-Slow") != "done" {
+		t.Errorf("Expected the export to run unbounded, got %d", rec.Code)
+	}
+
+	cfg.RequestTimeout = time.Second
+	setConfig(cfg)
+	rec = httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusAccepted || rec.Header().Get("X-Slow") != "done" {
+		t.Errorf("Expected the response of a request in time, got %d", rec.Code)
+	}
+
+	cfg.RequestTimeout = 0
+	setConfig(cfg)
+	rec = httptest.NewRecorder()
+	slow.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected no bound with a zero timeout, got %d", rec.Code)
+	}
+}
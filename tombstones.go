@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Number of deleted IDs remembered with --track-deletes, the oldest are forgotten first
+const maxTombstones = 1000
+
+// Bounded set of the deleted cat IDs, telling a gone cat apart from an unknown one
+type Tombstones struct {
+	lock  sync.Mutex
+	size  int
+	order []string
+	ids   map[string]bool
+}
+
+func NewTombstones(size int) *Tombstones {
+	return &Tombstones{size: size, ids: map[string]bool{}}
+}
+
+func (tombs *Tombstones) Add(catID string) {
+	tombs.lock.Lock()
+	defer tombs.lock.Unlock()
+
+	if tombs.size <= 0 || tombs.ids[catID] {
+		return
+	}
+	if len(tombs.order) >= tombs.size {
+		delete(tombs.ids, tombs.order[0])
+		tombs.order = tombs.order[1:]
+	}
+	tombs.order = append(tombs.order, catID)
+	tombs.ids[catID] = true
+}
+
+func (tombs *Tombstones) Has(catID string) bool {
+	tombs.lock.Lock()
+	defer tombs.lock.Unlock()
+	return tombs.ids[catID]
+}
+
+// Kept in memory, each instance only knows its own deletions
+var deletedCats = NewTombstones(maxTombstones)
+
+// Answer for a missing cat, 410 when it was deleted and the deletions are tracked
+func catNotFound(req *http.Request, catID string) (int, any) {
+	if currentConfig().TrackDeletes && deletedCats.Has(catID) {
+		Logger.Infof("Cat '%s' was deleted", catID)
+		return http.StatusGone, localize(req, "cat_deleted")
+	}
+	Logger.Infof("Cat '%s' not found", catID)
+	return http.StatusNotFound, localize(req, "cat_not_found")
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// =============================================================================
+// TOMBSTONES TESTS
+// =============================================================================
+
+// Test the set forgets the oldest deletions once full
+func TestTombstonesBounded(t *testing.T) {
+	tombs := NewTombstones(2)
+	tombs.Add("id1")
+	tombs.Add("id2")
+	tombs.Add("id2")
+	tombs.Add("id3")
+
+	if tombs.Has("id1") {
+		t.Error("Expected the oldest deletion to be forgotten")
+	}
+	if !tombs.Has("id2") || !tombs.Has("id3") {
+		t.Error("Expected the recent deletions to be kept")
+	}
+}
+
+// Test a deleted cat is gone only when the deletions are tracked
+func TestTrackDeletes(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig, originalTombstones := catsStore, currentConfig(), deletedCats
+	defer func() {
+		// Restore original state
+		catsStore, deletedCats = originalStore, originalTombstones
+		setConfig(originalConfig)
+	}()
+
+	tests := []struct {
+		trackDeletes bool
+		expectedCode int
+	}{
+		{false, http.StatusNotFound},
+		{true, http.StatusGone},
+	}
+
+	for _, test := range tests {
+		catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+		deletedCats = NewTombstones(maxTombstones)
+		cfg := currentConfig()
+		cfg.TrackDeletes = test.trackDeletes
+		setConfig(cfg)
+		app := newApp()
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/id1", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("Expected status code %d, got %d", http.StatusNoContent, rec.Code)
+		}
+
+		for _, method := range []string{"GET", "PATCH", "DELETE"} {
+			rec = httptest.NewRecorder()
+			app.ServeHTTP(rec, newJSONRequest(method, "/api/cats/id1", nil))
+			if rec.Code != test.expectedCode {
+				t.Errorf("Tracking %v, %s: expected status code %d, got %d", test.trackDeletes, method, test.expectedCode, rec.Code)
+			}
+		}
+
+		// Never existed
+		rec = httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("GET", "/api/cats/unknown", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Tracking %v: expected status code %d, got %d", test.trackDeletes, http.StatusNotFound, rec.Code)
+		}
+	}
+}
+
+// Test only the attempt deleting the cat gets it back, a retry after it tells the cat is gone
+func TestDeleteReturningCat(t *testing.T) {
+	originalStore, originalConfig, originalTombstones := catsStore, currentConfig(), deletedCats
+	defer func() {
+		catsStore, deletedCats = originalStore, originalTombstones
+		setConfig(originalConfig)
+	}()
+
+	for _, trackDeletes := range []bool{false, true} {
+		catsStore = NewMemoryRepo(Cat{ID: "id1", Name: "Toto", Color: "Grey"})
+		deletedCats = NewTombstones(maxTombstones)
+		cfg := currentConfig()
+		cfg.TrackDeletes = trackDeletes
+		setConfig(cfg)
+		app := newApp()
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/id1?return=true", nil))
+		var deleted Cat
+		json.NewDecoder(rec.Body).Decode(&deleted)
+		if rec.Code != http.StatusOK || deleted.ID != "id1" || deleted.Color != "Grey" {
+			t.Fatalf("Tracking %v: expected the deleted cat, got %d with %+v", trackDeletes, rec.Code, deleted)
+		}
+
+		// The retry of a client which lost the first answer
+		expectedCode := http.StatusNotFound
+		if trackDeletes {
+			expectedCode = http.StatusGone
+		}
+		rec = httptest.NewRecorder()
+		app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/id1?return=true", nil))
+		if rec.Code != expectedCode {
+			t.Errorf("Tracking %v: expected the retry answered %d, got %d", trackDeletes, expectedCode, rec.Code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	newAppWithStore(NewMemoryRepo()).ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/id1?return=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid flag, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+// Test the cat answered is the one the delete removed, a concurrent update included
+func TestDeleteReturningOvertakenCat(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto", Color: "Grey"})
+	app := newAppWithStore(overtakenStore{store, &sync.Once{}, func() {
+		store.Save(context.Background(), Cat{ID: "id1", Name: "Toto", Color: "Black"})
+	}})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest("DELETE", "/api/cats/id1?return=true", nil))
+	var deleted Cat
+	json.NewDecoder(rec.Body).Decode(&deleted)
+	if rec.Code != http.StatusOK || deleted.Color != "Black" {
+		t.Errorf("Expected the updated cat answered, got %d with %+v", rec.Code, deleted)
+	}
+	if cats, _ := store.List(t.Context()); len(cats) != 0 {
+		t.Errorf("Expected the cat deleted, got %v", cats)
+	}
+}
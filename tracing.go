@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Service name of the spans, OTEL_SERVICE_NAME overrides it
+const serviceName = "cats-api"
+
+// Does nothing until initTracing installs an exporter
+var tracer = otel.Tracer("backend")
+
+// Tracing is on once an OTLP endpoint is given through the standard environment variables
+func tracingConfigured(lookupEnv func(string) (string, bool)) bool {
+	if disabled, _ := lookupEnv("OTEL_SDK_DISABLED"); strings.EqualFold(disabled, "true") {
+		return false
+	}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"} {
+		if endpoint, _ := lookupEnv(name); endpoint != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Exports the spans over OTLP/HTTP, configured by the OTEL_* environment variables.
+// The returned function flushes the spans left, on shutdown
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	if !tracingConfigured(os.LookupEnv) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName), attribute.String("service.version", version)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	Logger.Info("Exporting the traces over OTLP")
+	return provider.Shutdown, nil
+}
+
+// Starts a server span per request, named after the route template of the router
+// and joining the trace of the caller
+func traceRequests(router *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		// The patterns are "METHOD /path"
+		_, pattern := router.Handler(r)
+		_, route, _ := strings.Cut(pattern, " ")
+		name := r.Method
+		if route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		if recorder.code == 0 {
+			recorder.code = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.code))
+		if recorder.code >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.code))
+		}
+	})
+}
+
+// Store traced with a child span per call
+type tracedStore struct {
+	Store
+}
+
+func startStoreSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "store."+operation, trace.WithSpanKind(trace.SpanKindClient))
+}
+
+// A missing cat is an answer, not a failure
+func endStoreSpan(span trace.Span, err error) {
+	if err != nil && err != ErrNotFound {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (store tracedStore) List(ctx context.Context) ([]Cat, error) {
+	ctx, span := startStoreSpan(ctx, "List")
+	cats, err := store.Store.List(ctx)
+	span.SetAttributes(attribute.Int("cats.count", len(cats)))
+	endStoreSpan(span, err)
+	return cats, err
+}
+
+func (store tracedStore) Iterate(ctx context.Context, yield func(Cat) bool) error {
+	ctx, span := startStoreSpan(ctx, "Iterate")
+	count := 0
+	err := store.Store.Iterate(ctx, func(cat Cat) bool {
+		count++
+		return yield(cat)
+	})
+	span.SetAttributes(attribute.Int("cats.count", count))
+	endStoreSpan(span, err)
+	return err
+}
+
+func (store tracedStore) Get(ctx context.Context, catID string) (Cat, error) {
+	ctx, span := startStoreSpan(ctx, "Get")
+	span.SetAttributes(attribute.String("cat.id", catID))
+	cat, err := store.Store.Get(ctx, catID)
+	endStoreSpan(span, err)
+	return cat, err
+}
+
+func (store tracedStore) Save(ctx context.Context, cat Cat) error {
+	ctx, span := startStoreSpan(ctx, "Save")
+	span.SetAttributes(attribute.String("cat.id", cat.ID))
+	err := store.Store.Save(ctx, cat)
+	endStoreSpan(span, err)
+	return err
+}
+
+func (store tracedStore) Delete(ctx context.Context, catID string) error {
+	ctx, span := startStoreSpan(ctx, "Delete")
+	span.SetAttributes(attribute.String("cat.id", catID))
+	err := store.Store.Delete(ctx, catID)
+	endStoreSpan(span, err)
+	return err
+}
+
+func (store tracedStore) DeleteMatching(ctx context.Context, match func(Cat) bool) ([]string, error) {
+	ctx, span := startStoreSpan(ctx, "DeleteMatching")
+	deletedIDs, err := store.Store.DeleteMatching(ctx, match)
+	span.SetAttributes(attribute.Int("cats.count", len(deletedIDs)))
+	endStoreSpan(span, err)
+	return deletedIDs, err
+}
+
+func (store tracedStore) Import(ctx context.Context, cats []Cat, replace bool) error {
+	ctx, span := startStoreSpan(ctx, "Import")
+	span.SetAttributes(attribute.Int("cats.count", len(cats)), attribute.Bool("import.replace", replace))
+	err := store.Store.Import(ctx, cats, replace)
+	endStoreSpan(span, err)
+	return err
+}
+
+func (store tracedStore) Transact(ctx context.Context, decide func(cats map[string]Cat) (StoreChange, error)) error {
+	ctx, span := startStoreSpan(ctx, "Transact")
+	attempts := 0
+	var refusal error
+	err := store.Store.Transact(ctx, func(cats map[string]Cat) (StoreChange, error) {
+		attempts++
+		change, err := decide(cats)
+		refusal = err
+		return change, err
+	})
+	span.SetAttributes(attribute.Int("transact.attempts", attempts))
+	// A change refused by decide is an answer, not a failure
+	if err != nil && err == refusal {
+		endStoreSpan(span, nil)
+	} else {
+		endStoreSpan(span, err)
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Records the spans of the test in memory
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	originalTracer := tracer
+	t.Cleanup(func() { tracer = originalTracer })
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// =============================================================================
+// TRACING TESTS
+// =============================================================================
+
+// Test tracing only starts with an OTLP endpoint
+func TestTracingConfigured(t *testing.T) {
+	tests := []struct {
+		env      map[string]string
+		expected bool
+	}{
+		{map[string]string{}, false},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"}, true},
+		{map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://localhost:4318/v1/traces"}, true},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318", "OTEL_SDK_DISABLED": "true"}, false},
+	}
+
+	for _, test := range tests {
+		if configured := tracingConfigured(envOf(test.env)); configured != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.env, test.expected, configured)
+		}
+	}
+}
+
+// Test a request span is named after its route with the store calls as children
+func TestTraceRequests(t *testing.T) {
+	// Save original database state
+	originalStore := catsStore
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+	}()
+
+	spans := recordSpans(t)
+	catsStore = tracedStore{NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})}
+	app := newApp()
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/cats/unknown", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("Expected a request and a store span, got %d", len(ended))
+	}
+	storeSpan, requestSpan := ended[0], ended[1]
+
+	if requestSpan.Name() != "GET /api/cats/{catId}" {
+		t.Errorf("Expected the route template as name, got %q", requestSpan.Name())
+	}
+	if code := spanAttribute(requestSpan, "http.response.status_code").AsInt64(); code != http.StatusNotFound {
+		t.Errorf("Expected the status recorded, got %d", code)
+	}
+	if requestSpan.Status().Code == codes.Error {
+		t.Error("A client error should not fail the span")
+	}
+
+	if storeSpan.Name() != "store.Get" || storeSpan.Parent().SpanID() != requestSpan.SpanContext().SpanID() {
+		t.Errorf("Expected the store call as a child, got %q", storeSpan.Name())
+	}
+	if storeSpan.Status().Code == codes.Error {
+		t.Error("A missing cat should not fail the store span")
+	}
+}
+
+// Test the requests outside the routes still get a span
+func TestTraceUnmatchedRequest(t *testing.T) {
+	spans := recordSpans(t)
+
+	newApp().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nothing", nil))
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != "GET" {
+		t.Fatalf("Expected a span named after the method, got %v", ended)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Layout of the birth dates
+const dateLayout = "2006-01-02"
+
+// Heaviest weight accepted, well above any real cat
+const maxWeightGrams = 30000
+
+// Problem found on a single field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// All the problems found on a request body, answered with a 422
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (verr *ValidationError) Add(field, message string) {
+	verr.Errors = append(verr.Errors, FieldError{Field: field, Message: message})
+}
+
+func (verr ValidationError) HasErrors() bool {
+	return len(verr.Errors) > 0
+}
+
+func (verr ValidationError) Error() string {
+	messages := []string{}
+	for _, fieldErr := range verr.Errors {
+		messages = append(messages, fieldErr.Field+" "+fieldErr.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Cleans a cat sent by a client before it is validated and stored, a blank name becoming a missing one
+func applyInputPolicies(cat *Cat) {
+	cat.Name = strings.TrimSpace(cat.Name)
+	applyColorPolicy(cat)
+}
+
+// Checks the semantic of the cats sent by the clients, shared by creation and updates
+type CatValidator struct {
+	// Clock deciding which birth dates are in the future, time.Now when nil
+	now func() time.Time
+	// Language of the messages, English when empty
+	language string
+}
+
+// Validator answering in the language of the client
+func requestValidator(req *http.Request) CatValidator {
+	return CatValidator{language: requestLanguage(req)}
+}
+
+// Message of the catalog in the language of the validator
+func (validator CatValidator) message(code string, args ...any) string {
+	return translate(validator.language, code, args...)
+}
+
+func (validator CatValidator) today() string {
+	now := time.Now
+	if validator.now != nil {
+		now = validator.now
+	}
+	return now().Format(dateLayout)
+}
+
+// Validates all the fields at once so the client can fix everything in one pass
+func (validator CatValidator) Validate(cat Cat) ValidationError {
+	var verr ValidationError
+
+	maxNameLength := currentConfig().MaxNameLength
+	if strings.TrimSpace(cat.Name) == "" {
+		verr.Add("name", validator.message("required"))
+	} else if strings.ContainsFunc(cat.Name, unicode.IsControl) {
+		verr.Add("name", validator.message("control_chars"))
+	} else if utf8.RuneCountInString(cat.Name) > maxNameLength {
+		verr.Add("name", validator.message("too_long", maxNameLength))
+	}
+
+	if cat.BirthDate != "" && cat.BirthDate != unknownBirthDate {
+		if _, err := time.Parse(dateLayout, cat.BirthDate); err != nil {
+			verr.Add("birthDate", validator.message("date_layout"))
+		} else if cat.BirthDate > validator.today() {
+			// Same layout, the dates compare as strings
+			verr.Add("birthDate", validator.message("future_date"))
+		}
+	}
+
+	if cat.Breed != "" && !isKnownBreed(cat.Breed) {
+		verr.Add("breed", validator.message("one_of", strings.Join(catBreeds, ", ")))
+	}
+
+	if cat.WeightGrams < 0 {
+		verr.Add("weightGrams", validator.message("negative"))
+	} else if cat.WeightGrams > maxWeightGrams {
+		verr.Add("weightGrams", validator.message("at_most", maxWeightGrams))
+	}
+
+	return verr
+}
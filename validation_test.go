@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// =============================================================================
+// VALIDATION TESTS
+// =============================================================================
+
+// Test all the invalid fields are reported at once
+func TestCatValidator(t *testing.T) {
+	tests := []struct {
+		name     string
+		cat      Cat
+		expected []string
+	}{
+		{"valid", Cat{Name: "Toto", BirthDate: "2023-04-16"}, nil},
+		{"no birth date", Cat{Name: "Toto"}, nil},
+		{"missing name", Cat{BirthDate: "2023-04-16"}, []string{"name"}},
+		{"bad date", Cat{Name: "Toto", BirthDate: "16/04/2023"}, []string{"birthDate"}},
+		{"impossible date", Cat{Name: "Toto", BirthDate: "2023-02-30"}, []string{"birthDate"}},
+		{"everything wrong", Cat{BirthDate: "1997"}, []string{"name", "birthDate"}},
+		{"born today", Cat{Name: "Toto", BirthDate: "2024-05-01"}, nil},
+		{"born tomorrow", Cat{Name: "Toto", BirthDate: "2024-05-02"}, []string{"birthDate"}},
+		{"weighed", Cat{Name: "Toto", WeightGrams: 4200}, nil},
+		{"heaviest", Cat{Name: "Toto", WeightGrams: maxWeightGrams}, nil},
+		{"negative weight", Cat{Name: "Toto", WeightGrams: -1}, []string{"weightGrams"}},
+		{"too heavy", Cat{Name: "Toto", WeightGrams: maxWeightGrams + 1}, []string{"weightGrams"}},
+		{"blank name", Cat{Name: "   "}, []string{"name"}},
+		{"unicode name", Cat{Name: "Félix le Chat 猫"}, nil},
+		{"longest name", Cat{Name: strings.Repeat("é", 64)}, nil},
+		{"too long name", Cat{Name: strings.Repeat("é", 65)}, []string{"name"}},
+		{"control character", Cat{Name: "To\tto"}, []string{"name"}},
+	}
+
+	// Late in the day so a UTC conversion would already be tomorrow
+	validator := CatValidator{now: func() time.Time {
+		return time.Date(2024, 5, 1, 23, 30, 0, 0, time.FixedZone("UTC-5", -5*3600))
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verr := validator.Validate(test.cat)
+
+			if len(verr.Errors) != len(test.expected) {
+				t.Fatalf("Expected errors on %v, got %v", test.expected, verr.Errors)
+			}
+			for idx, field := range test.expected {
+				if verr.Errors[idx].Field != field || verr.Errors[idx].Message == "" {
+					t.Errorf("Expected an error on %s, got %+v", field, verr.Errors[idx])
+				}
+			}
+		})
+	}
+}
+
+// Test the creation answers a 422 with the field errors
+func TestCreateCatValidationErrors(t *testing.T) {
+	store := NewMemoryRepo(Cat{ID: "id1", Name: "Toto"})
+
+	rec := httptest.NewRecorder()
+	newAppWithStore(store).ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"color": "Grey", "birthDate": "yesterday"}`)))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	var result struct {
+		Errors []FieldError `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("Expected a JSON errors object: %v", err)
+	}
+	if len(result.Errors) != 2 || result.Errors[0].Field != "name" || result.Errors[1].Field != "birthDate" {
+		t.Errorf("Expected errors on name and birthDate, got %+v", result.Errors)
+	}
+
+	// A cat cannot be born after today
+	future := time.Now().AddDate(0, 0, 2).Format(dateLayout)
+	statusCode, response := createCat(withStore(store, httptest.NewRequest("POST", "/api/cats", strings.NewReader(`{"name": "Felix", "birthDate": "`+future+`"}`))))
+	if verr, ok := response.(ValidationError); statusCode != http.StatusUnprocessableEntity || !ok || verr.Error() != "birthDate cannot be in the future" {
+		t.Errorf("Expected a future birth date error, got %d (%v)", statusCode, response)
+	}
+
+	if len(storedCats(store)) != 1 {
+		t.Error("An invalid cat should not be stored")
+	}
+}
+
+// Test the name is stored trimmed, a blank one being missing
+func TestCreateCatTrimsName(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo())
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": "  Toto  "}`)))
+	var cat Cat
+	if json.NewDecoder(rec.Body).Decode(&cat); rec.Code != http.StatusCreated || cat.Name != "Toto" {
+		t.Errorf("Expected the name trimmed, got %d with %q", rec.Code, cat.Name)
+	}
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name": " \u00a0 "}`)))
+	var verr ValidationError
+	if json.NewDecoder(rec.Body).Decode(&verr); rec.Code != http.StatusUnprocessableEntity || verr.Error() != "name is required" {
+		t.Errorf("Expected a blank name missing, got %d with %+v", rec.Code, verr)
+	}
+}
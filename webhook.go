@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// Events waiting for delivery, the next ones are dropped while it is full
+	webhookQueueSize = 100
+	webhookAttempts  = 3
+)
+
+// Wait after a failed delivery, doubled for each next attempt
+var webhookRetryDelay = time.Second
+
+// Client keeping a connection per worker at most, a hung webhook cannot pile them up.
+// Each delivery is bounded by --webhook-timeout rather than by the client
+func newWebhookClient(workers int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = workers
+	transport.MaxIdleConnsPerHost = workers
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Transport: transport}
+}
+
+var webhookClient = newWebhookClient(defaultConfig().WebhookConcurrency)
+
+// Rebuilt from the settings when the workers start
+var webhookBreaker = newWebhookBreaker(defaultConfig())
+
+func newWebhookBreaker(cfg Config) *CircuitBreaker {
+	return NewCircuitBreaker("webhook", cfg.WebhookBreakerFailures, cfg.WebhookBreakerCooldown)
+}
+
+var webhookEvents = make(chan CatEvent, webhookQueueSize)
+
+// Queues the event for --webhook-url without waiting, the request is never slowed by the webhook
+func notifyWebhook(event CatEvent) {
+	if currentConfig().WebhookURL == "" {
+		return
+	}
+	select {
+	case webhookEvents <- event:
+	default:
+		Logger.Warnf("Webhook queue full, event '%s' of the cat '%s' dropped", event.Event, event.ID)
+	}
+}
+
+// POSTs the event once, any status but a 2xx is a failure
+func postEvent(url string, payload []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), currentConfig().WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("content-type", jsonResponseType)
+
+	res, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
+
+// Tries a few times before giving up on the event
+func deliverEvent(url string, event CatEvent) error {
+	payload, err := json.Marshal(outputNaming(event))
+	if err != nil {
+		return err
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		err = postEvent(url, payload)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		Logger.Debugf("Webhook delivery %d/%d of the cat '%s' failed, retry in %v: %v", attempt, webhookAttempts, event.ID, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// Sends the queued events to the webhook configured at the time of delivery.
+// While the breaker is open the events are dropped at once rather than waiting on a down webhook
+func runWebhookWorker(events <-chan CatEvent) {
+	for event := range events {
+		url := currentConfig().WebhookURL
+		if url == "" {
+			continue
+		}
+		if !webhookBreaker.Allow() {
+			Logger.Debugf("Webhook breaker %s, event '%s' of the cat '%s' dropped", webhookBreaker.State(), event.Event, event.ID)
+			continue
+		}
+		err := deliverEvent(url, event)
+		webhookBreaker.Record(err)
+		if err != nil {
+			Logger.Warnf("Webhook event '%s' of the cat '%s' dropped: %v", event.Event, event.ID, err)
+		}
+	}
+}
+
+// --webhook-concurrency workers, a single one with --ordered-events so an event waits for the
+// delivery of the previous one
+func startWebhookWorkers() {
+	cfg := currentConfig()
+	workers := cfg.WebhookConcurrency
+	if cfg.OrderedEvents {
+		workers = 1
+	}
+	webhookClient = newWebhookClient(workers)
+	webhookBreaker = newWebhookBreaker(cfg)
+	for range workers {
+		go runWebhookWorker(webhookEvents)
+	}
+}
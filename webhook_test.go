@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Webhook answering 503 to its first calls, handing over the bodies it accepts
+func newFlakyWebhook(failures int32) (*httptest.Server, *atomic.Int32, chan []byte) {
+	var calls atomic.Int32
+	received := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	return server, &calls, received
+}
+
+// =============================================================================
+// WEBHOOK TESTS
+// =============================================================================
+
+// Test a failed delivery is retried, then given up
+func TestDeliverEvent(t *testing.T) {
+	originalDelay := webhookRetryDelay
+	defer func() { webhookRetryDelay = originalDelay }()
+	webhookRetryDelay = time.Millisecond
+
+	event := CatEvent{Event: eventDeleted, ID: "id1", Time: time.Now()}
+
+	server, calls, received := newFlakyWebhook(webhookAttempts - 1)
+	defer server.Close()
+	if err := deliverEvent(server.URL, event); err != nil || calls.Load() != webhookAttempts {
+		t.Errorf("Expected a delivery at the last attempt, got %v after %d calls", err, calls.Load())
+	}
+	if body := <-received; !strings.Contains(string(body), `"event":"deleted"`) || strings.Contains(string(body), `"cat"`) {
+		t.Errorf("Expected a deleted event without cat, got %s", body)
+	}
+
+	failing, calls, _ := newFlakyWebhook(webhookAttempts)
+	defer failing.Close()
+	if err := deliverEvent(failing.URL, event); err == nil || calls.Load() != webhookAttempts {
+		t.Errorf("Expected to give up after %d calls, got %v after %d", webhookAttempts, err, calls.Load())
+	}
+}
+
+// Test a created cat is queued, and the events are dropped rather than blocking once the queue is full
+func TestNotifyWebhook(t *testing.T) {
+	// Save original state
+	originalStore, originalConfig := catsStore, currentConfig()
+	defer func() {
+		// Restore original state
+		catsStore = originalStore
+		setConfig(originalConfig)
+	}()
+
+	catsStore = NewMemoryRepo()
+	notifyWebhook(CatEvent{Event: eventDeleted, ID: "id1"})
+	if len(webhookEvents) != 0 {
+		t.Fatal("Expected no event without a webhook")
+	}
+
+	cfg := currentConfig()
+	cfg.WebhookURL = "http://localhost/hook"
+	setConfig(cfg)
+
+	rec := httptest.NewRecorder()
+	newApp().ServeHTTP(rec, newJSONRequest("POST", "/api/cats", strings.NewReader(`{"name":"Toto","birthDate":"2020-01-01","color":"Black"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d", http.StatusCreated, rec.Code)
+	}
+	var created Cat
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	select {
+	case event := <-webhookEvents:
+		if event.Event != eventCreated || event.ID != created.ID || event.Cat == nil || event.Cat.Name != "Toto" || event.Time.IsZero() {
+			t.Errorf("Expected the created event of %s, got %+v", created.ID, event)
+		}
+	default:
+		t.Fatal("Expected the creation to be queued")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for range webhookQueueSize + 1 {
+			notifyWebhook(CatEvent{Event: eventDeleted, ID: "id1"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("A full queue blocked the notification")
+	}
+	if len(webhookEvents) != webhookQueueSize {
+		t.Errorf("Expected %d queued events, got %d", webhookQueueSize, len(webhookEvents))
+	}
+	for len(webhookEvents) > 0 {
+		<-webhookEvents
+	}
+}
+
+// Test a down webhook is no longer called once the breaker opens, the events being dropped
+func TestWebhookBreaker(t *testing.T) {
+	originalConfig, originalBreaker, originalDelay := currentConfig(), webhookBreaker, webhookRetryDelay
+	defer func() {
+		setConfig(originalConfig)
+		webhookBreaker, webhookRetryDelay = originalBreaker, originalDelay
+	}()
+	webhookRetryDelay = time.Millisecond
+
+	down, calls, _ := newFlakyWebhook(1000)
+	defer down.Close()
+	cfg := currentConfig()
+	cfg.WebhookURL = down.URL
+	cfg.WebhookBreakerFailures = 2
+	cfg.WebhookBreakerCooldown = time.Hour
+	setConfig(cfg)
+	webhookBreaker = newWebhookBreaker(cfg)
+
+	events := make(chan CatEvent, 5)
+	for range 5 {
+		events <- CatEvent{Event: eventDeleted, ID: "id1"}
+	}
+	close(events)
+	runWebhookWorker(events)
+
+	if expected := int32(2 * webhookAttempts); calls.Load() != expected {
+		t.Errorf("Expected %d calls before the breaker opens, got %d", expected, calls.Load())
+	}
+	if state := webhookBreaker.State(); state != breakerOpen {
+		t.Errorf("Expected the breaker open, got %s", state)
+	}
+}
+
+// Test a delivery attempt is bounded by --webhook-timeout
+func TestWebhookTimeout(t *testing.T) {
+	originalConfig := currentConfig()
+	defer setConfig(originalConfig)
+
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	cfg := currentConfig()
+	cfg.WebhookTimeout = 50 * time.Millisecond
+	setConfig(cfg)
+
+	start := time.Now()
+	if err := postEvent(hung.URL, []byte(`{}`)); err == nil || time.Since(start) > time.Second {
+		t.Errorf("Expected the attempt to time out, got %v after %v", err, time.Since(start))
+	}
+}
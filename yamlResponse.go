@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+)
+
+const yamlResponseType = "application/yaml"
+
+// Asked with `?format=yaml` or an Accept header listing a YAML media type, JSON otherwise
+func wantsYAML(req *http.Request) bool {
+	if req.URL.Query().Get("format") == "yaml" {
+		return true
+	}
+	return acceptsMediaType(req, yamlResponseType) || acceptsMediaType(req, "application/x-yaml") || acceptsMediaType(req, "text/yaml")
+}
+
+// Drops the JSON flow style so the document reads as block YAML
+func useBlockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		useBlockStyle(child)
+	}
+}
+
+// Writes the body as YAML, going through JSON so the fields are named and ordered the same.
+// JSON being YAML, the numbers and strings are kept as written
+func encodeYAML(out io.Writer, body any) error {
+	document, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(document, &node); err != nil {
+		return err
+	}
+	useBlockStyle(&node)
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// =============================================================================
+// YAML RESPONSE TESTS
+// =============================================================================
+
+// Test the YAML keeps the JSON names, order and types
+func TestEncodeYAML(t *testing.T) {
+	var buffer bytes.Buffer
+	err := encodeYAML(&buffer, map[string]any{
+		"cat":    Cat{ID: "id1", Name: "true", BirthDate: "2020-01-01", WeightGrams: 4200},
+		"ids":    []string{},
+		"ratio":  0.5,
+		"nested": map[string][]int{"a": {1, 2}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+
+	expected := `cat:
+  name: "true"
+  id: id1
+  birthDate: "2020-01-01"
+  weightGrams: 4200
+ids: []
+nested:
+  a:
+    - 1
+    - 2
+ratio: 0.5
+`
+	if buffer.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buffer.String())
+	}
+}
+
+// Test YAML is answered when asked by the query or the Accept header only
+func TestYAMLResponses(t *testing.T) {
+	app := newAppWithStore(NewMemoryRepo(Cat{ID: "id1", Name: "Toto"}))
+
+	tests := []struct {
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"/api/cats/id1", "", jsonResponseType, `{"name":"Toto","id":"id1"}` + "\n"},
+		{"/api/cats/id1?format=yaml", "", yamlResponseType, "name: Toto\nid: id1\n"},
+		{"/api/cats", "application/yaml", yamlResponseType, "- id1\n"},
+		{"/api/cats", "text/html, application/x-yaml;q=0.9", yamlResponseType, "- id1\n"},
+		{"/api/cats/id2?format=yaml", "", yamlResponseType, "Cat not found\n"},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", test.target, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		if contentType := rec.Header().Get("Content-Type"); contentType != test.contentType {
+			t.Errorf("%s: expected Content-Type %q, got %q", test.target, test.contentType, contentType)
+		}
+		if rec.Body.String() != test.body {
+			t.Errorf("%s: expected %q, got %q", test.target, test.body, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/api/cats/id1", nil)
+	req.Header.Set("Accept", "application/yaml")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Length") != "19" {
+		t.Errorf("Expected the exact YAML length, got %d with %q", rec.Code, rec.Header().Get("Content-Length"))
+	}
+}